@@ -9,13 +9,21 @@ package 注册表类_test
 
 import (
 	"bytes"
+	"context"
 	"crypto/rand"
 	注册表类 "e.coding.net/gogit/go/gosdk/core/win_registry_cn"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"os"
+	"slices"
+	"strings"
 	"syscall"
 	"testing"
 	"time"
 	"unsafe"
+
+	"golang.org/x/sys/windows"
 )
 
 func randKeyName(prefix string) string {
@@ -674,3 +682,1186 @@ func GetDynamicTimeZoneInformation(dtzi *DynamicTimezoneinformation) (rc uint32,
 	}
 	return
 }
+
+func TestKeySecurity(t *testing.T) {
+	softwareK, err := 注册表类.I打开表项(注册表类.CURRENT_USER, "Software", 注册表类.QUERY_VALUE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer softwareK.I关闭()
+
+	testKName := randKeyName("TestKeySecurity_")
+	k, _, err := 注册表类.I创建表项(softwareK, testKName, 注册表类.READ|注册表类.WRITE_DAC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer k.I关闭()
+	defer 注册表类.I删除表项(softwareK, testKName)
+
+	sd, err := k.I取安全描述符()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sd.Owner == "" || sd.DACL == nil {
+		t.Fatalf("unexpected security descriptor %s", sd)
+	}
+
+	want := "D:P(A;;KA;;;SY)(A;;KR;;;WD)"
+	dacl, err := 注册表类.I解析SDDL(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := k.I设置安全描述符(dacl); err != nil {
+		t.Fatal(err)
+	}
+	got, err := k.I取安全描述符(注册表类.DACL_SECURITY_INFORMATION)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.I转SDDL() != want {
+		t.Errorf("got DACL %q, want %q", got.I转SDDL(), want)
+	}
+}
+
+func TestOpenKeyFallback(t *testing.T) {
+	softwareK, err := 注册表类.I打开表项(注册表类.CURRENT_USER, "Software", 注册表类.QUERY_VALUE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer softwareK.I关闭()
+
+	testKName := randKeyName("TestOpenKeyFallback_")
+	k, _, granted, err := 注册表类.I创建表项Ex(softwareK, testKName, 注册表类.I打开选项{View: 注册表类.I视图_本机})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer k.I关闭()
+	if granted != 注册表类.ALL_ACCESS {
+		t.Fatalf("got access %#x for new key, want ALL_ACCESS", granted)
+	}
+	defer 注册表类.I删除表项(softwareK, testKName)
+	old, err := k.I取安全描述符(注册表类.DACL_SECURITY_INFORMATION)
+	if err != nil {
+		t.Fatal(err)
+	}
+	readOnly, _ := 注册表类.I解析SDDL("D:P(A;;KR;;;WD)")
+	if err := k.I设置安全描述符(readOnly); err != nil {
+		t.Fatal(err)
+	}
+	defer k.I设置安全描述符(old)
+
+	if _, err := 注册表类.I打开表项(softwareK, testKName, 注册表类.ALL_ACCESS); err != syscall.ERROR_ACCESS_DENIED {
+		t.Fatalf("opening read-only key with ALL_ACCESS: got %v, want access denied", err)
+	}
+	readK, granted, err := 注册表类.I打开表项Ex(softwareK, testKName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	readK.I关闭()
+	if granted != 注册表类.READ {
+		t.Errorf("got access %#x, want READ", granted)
+	}
+	readK, err = 注册表类.I打开表项(softwareK, testKName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	readK.I关闭()
+	_, _, err = 注册表类.I打开表项Ex(softwareK, testKName, 注册表类.I打开选项{Access: 注册表类.WRITE})
+	if err != syscall.ERROR_ACCESS_DENIED {
+		t.Errorf("opening with WRITE and no fallback: got %v, want access denied", err)
+	}
+}
+
+func TestReadBothViews(t *testing.T) {
+	name := randKeyName("TestReadBothViews_")
+	path := `Software\Classes\CLSID\` + name
+	for _, view := range []注册表类.I视图{注册表类.I视图_32位, 注册表类.I视图_64位} {
+		options := 注册表类.I打开选项{Access: 注册表类.ALL_ACCESS, View: view}
+		parent, _, _, err := 注册表类.I创建表项Ex(注册表类.CURRENT_USER, `Software\Classes\CLSID`, options)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer parent.I关闭()
+		k, _, err := 注册表类.I创建表项(parent, name, 注册表类.ALL_ACCESS)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer 注册表类.I删除表项(parent, name)
+		err = k.I设置文本值("View", view.String())
+		k.I关闭()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := 注册表类.I读取双视图(注册表类.CURRENT_USER, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Values) != 2 {
+		t.Fatalf("got %d values, want one per view", len(got.Values))
+	}
+	for _, v := range got.Values {
+		if len(v.Views) != 1 {
+			t.Errorf("value %q is tagged with %v, want a single view", v.Name, v.Views)
+		}
+	}
+	if _, err := 注册表类.I读取双视图(注册表类.CURRENT_USER, path+`\missing`); err != 注册表类.ErrNotExist {
+		t.Errorf("got %v for missing key, want ErrNotExist", err)
+	}
+}
+
+func TestUserProfiles(t *testing.T) {
+	user, err := windows.GetCurrentProcessToken().GetTokenUser()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sid := user.User.Sid.String()
+
+	loaded, err := 注册表类.I取已加载用户SID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, s := range loaded {
+		found = found || s == sid
+	}
+	if !found {
+		t.Fatalf("current user %s is not in loaded users %q", sid, loaded)
+	}
+
+	profiles, err := 注册表类.I取用户配置列表()
+	if err != nil {
+		t.Fatal(err)
+	}
+	found = false
+	for _, p := range profiles {
+		if p.SID == sid {
+			found = true
+			if !p.Loaded || p.ProfilePath == "" || p.Account == "" {
+				t.Errorf("incomplete profile for current user: %+v", p)
+			}
+		}
+	}
+	if !found {
+		t.Errorf("current user %s has no profile", sid)
+	}
+
+	k, err := 注册表类.I打开用户表项(sid, 注册表类.READ)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer k.I关闭()
+	software, err := 注册表类.I打开表项(k, "Software", 注册表类.READ)
+	if err != nil {
+		t.Fatalf("opening Software under USERS\\%s: %v", sid, err)
+	}
+	software.I关闭()
+	if _, err := 注册表类.I打开用户表项(".DEFAULT"); err == nil {
+		t.Error(".DEFAULT is not a user SID")
+	}
+}
+
+func TestEnablePrivilegeNotHeld(t *testing.T) {
+	// 普通用户和管理员的令牌中都没有SeTcbPrivilege, AdjustTokenPrivileges 成功返回但没有分配。
+	if err := 注册表类.I启用特权("SeTcbPrivilege"); err != windows.ERROR_NOT_ALL_ASSIGNED {
+		t.Skipf("SeTcbPrivilege: got %v, the token may hold it", err)
+	}
+	if err := 注册表类.I启用特权("NoSuchPrivilege"); err == nil {
+		t.Error("enabling an unknown privilege should fail")
+	}
+}
+
+func TestLoadHive(t *testing.T) {
+	if err := 注册表类.I启用特权(注册表类.SE_BACKUP_NAME, 注册表类.SE_RESTORE_NAME); err != nil {
+		t.Skipf("backup and restore privileges are not available: %v", err)
+	}
+	root := &注册表类.I离线表项{Name: "ROOT", SubKeys: []*注册表类.I离线表项{
+		{Name: "Settings", Values: []*注册表类.I离线值{注册表类.I新建文本值("Name", "offline")}},
+	}}
+	data, err := 注册表类.I生成配置单元(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := t.TempDir() + `\test.hiv`
+	if err := os.WriteFile(file, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	name := randKeyName("TestLoadHive_")
+	if err := 注册表类.I加载配置单元(注册表类.USERS, name, file); err != nil {
+		t.Fatal(err)
+	}
+	k, err := 注册表类.I打开表项(注册表类.USERS, name+`\Settings`, 注册表类.READ)
+	if err != nil {
+		注册表类.I卸载配置单元(注册表类.USERS, name)
+		t.Fatal(err)
+	}
+	s, _, err := k.I取文本值("Name")
+	k.I关闭()
+	if err != nil || s != "offline" {
+		t.Errorf("got %q, %v, want %q", s, err, "offline")
+	}
+	if err := 注册表类.I卸载配置单元(注册表类.USERS, name); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestKeyInterceptor(t *testing.T) {
+	path := `Software\` + randKeyName("TestKeyInterceptor_")
+	root, _, err := 注册表类.I创建表项(注册表类.CURRENT_USER, path, 注册表类.ALL_ACCESS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer 注册表类.I删除表项树(注册表类.CURRENT_USER, path)
+	defer root.I关闭()
+
+	var calls []string
+	logger := 注册表类.I拦截函数(func(c *注册表类.I调用记录, next func() error) error {
+		err := next()
+		calls = append(calls, fmt.Sprintf("%s %s %s %#x %d", c.Op, c.Path, c.Name, c.Access, c.Type))
+		return err
+	})
+	readOnly := 注册表类.I拦截函数(func(c *注册表类.I调用记录, next func() error) error {
+		if c.Op == 注册表类.I操作_设置值 && c.Name == "Locked" {
+			return 注册表类.ErrSessionAccessDenied
+		}
+		err := next()
+		if c.Op == 注册表类.I操作_取值 && c.Name == "Port" && c.Result != nil {
+			c.Result = &注册表类.I离线值{Name: "Port", Type: 注册表类.DWORD, Data: []byte{1, 0, 0, 0}}
+		}
+		return err
+	})
+	k, err := 注册表类.I启用拦截(root, logger, readOnly)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer k.I关闭()
+	if _, err := 注册表类.I启用拦截(k); err == nil {
+		t.Error("enabling interception twice should fail")
+	}
+
+	sub, _, err := 注册表类.I创建表项(k, `App\Settings`, 注册表类.ALL_ACCESS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := 注册表类.I设置(sub, "Port", uint32(8080)); err != nil {
+		t.Fatal(err)
+	}
+	if err := sub.I设置文本值("Locked", "x"); !errors.Is(err, 注册表类.ErrSessionAccessDenied) {
+		t.Errorf("write blocked by the interceptor: got %v", err)
+	}
+	if port, err := 注册表类.I取[uint32](sub, "Port"); err != nil || port != 1 {
+		t.Errorf("typed getter sees the interceptor's result: got %d, %v", port, err)
+	}
+	if n, typ, err := sub.I取整数值64("Port"); err != nil || n != 1 || typ != 注册表类.DWORD {
+		t.Errorf("I取整数值64: got %d, %d, %v", n, typ, err)
+	}
+	if _, _, err := sub.I取文本值("Port"); err != 注册表类.ErrUnexpectedType {
+		t.Errorf("I取文本值 of a DWORD: got %v", err)
+	}
+	sub.I关闭()
+	for _, p := range []string{`App\Settings`, "App"} {
+		if err := 注册表类.I删除表项(k, p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 拦截器放行的删除实际作用在注册表上。
+	if names, err := root.I取所有子项名称(-1); err != nil || len(names) != 0 {
+		t.Errorf("subkeys after deleting the keys: %q, %v", names, err)
+	}
+
+	want := []string{
+		`CreateKey App\Settings  0xf003f 0`,
+		`SetValue App\Settings Port 0x0 4`,
+		`SetValue App\Settings Locked 0x0 1`,
+		`GetValue App\Settings Port 0x0 4`,
+		`GetValue App\Settings Port 0x0 4`,
+		`GetValue App\Settings Port 0x0 4`,
+		`DeleteKey App\Settings  0x0 0`,
+		`DeleteKey App  0x0 0`,
+	}
+	if !slices.Equal(calls, want) {
+		t.Errorf("calls:\n%s\nwant:\n%s", strings.Join(calls, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestSymbolicLink(t *testing.T) {
+	user, err := windows.GetCurrentProcessToken().GetTokenUser()
+	if err != nil {
+		t.Fatal(err)
+	}
+	softwareK, err := 注册表类.I打开表项(注册表类.CURRENT_USER, "Software", 注册表类.QUERY_VALUE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer softwareK.I关闭()
+
+	targetName := randKeyName("TestSymbolicLinkTarget_")
+	target, _, err := 注册表类.I创建表项(softwareK, targetName, 注册表类.ALL_ACCESS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer 注册表类.I删除表项(softwareK, targetName)
+	err = target.I设置文本值("Name", "target")
+	target.I关闭()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	linkName := randKeyName("TestSymbolicLink_")
+	link, err := 注册表类.I创建链接表项(softwareK, linkName, `HKU\`+user.User.Sid.String()+`\Software\`+targetName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	link.I关闭()
+	defer 注册表类.I删除链接表项(softwareK, linkName)
+
+	k, err := 注册表类.I打开表项(softwareK, linkName, 注册表类.QUERY_VALUE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, _, err := k.I取文本值("Name")
+	k.I关闭()
+	if err != nil || s != "target" {
+		t.Errorf("reading through link: got %q, %v", s, err)
+	}
+
+	link, err = 注册表类.I打开链接表项(softwareK, linkName, 注册表类.QUERY_VALUE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := link.I取链接目标()
+	link.I关闭()
+	want := `\REGISTRY\USER\` + user.User.Sid.String() + `\Software\` + targetName
+	if err != nil || got != want {
+		t.Errorf("link target: got %q, %v, want %q", got, err, want)
+	}
+
+	if err := 注册表类.I删除链接表项(softwareK, linkName); err != nil {
+		t.Fatal(err)
+	}
+	target, err = 注册表类.I打开表项(softwareK, targetName, 注册表类.QUERY_VALUE)
+	if err != nil {
+		t.Fatalf("deleting the link removed its target: %v", err)
+	}
+	target.I关闭()
+}
+
+func TestDeleteTreeKeepsLinkTargets(t *testing.T) {
+	user, err := windows.GetCurrentProcessToken().GetTokenUser()
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := `Software\` + randKeyName("TestDeleteTreeLink_")
+	keep, _, err := 注册表类.I创建表项(注册表类.CURRENT_USER, path+`\Outside\Keep`, 注册表类.ALL_ACCESS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keep.I关闭()
+	defer 注册表类.I删除表项树(注册表类.CURRENT_USER, path)
+	root, err := 注册表类.I打开表项(注册表类.CURRENT_USER, path, 注册表类.ALL_ACCESS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer root.I关闭()
+	session, err := 注册表类.I新建表项会话(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		name   string
+		delete func() error
+	}{
+		{"key", func() error { return 注册表类.I删除表项树(root, `Tree`) }},
+		{"session", func() error {
+			return 注册表类.I应用到会话(session, []注册表类.I离线差异{{Kind: 注册表类.I差异_删除表项, Path: `Tree`}})
+		}},
+	} {
+		tree, _, err := 注册表类.I创建表项(root, `Tree`, 注册表类.ALL_ACCESS)
+		if err != nil {
+			t.Fatal(err)
+		}
+		link, err := 注册表类.I创建链接表项(tree, "Link", `HKU\`+user.User.Sid.String()+`\`+path+`\Outside`)
+		tree.I关闭()
+		if err != nil {
+			t.Fatal(err)
+		}
+		link.I关闭()
+		if st, err := session.I取信息(`Tree\Link`); err != nil || !st.Link {
+			t.Errorf("%s: stat of the link: got %+v, %v", test.name, st, err)
+		}
+
+		if err := test.delete(); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if _, err := 注册表类.I打开表项(root, `Tree`, 注册表类.QUERY_VALUE); err != 注册表类.ErrNotExist {
+			t.Errorf("%s: deleted tree: got %v", test.name, err)
+		}
+		k, err := 注册表类.I打开表项(root, `Outside\Keep`, 注册表类.QUERY_VALUE)
+		if err != nil {
+			t.Fatalf("%s: deleting the tree followed the link: %v", test.name, err)
+		}
+		k.I关闭()
+	}
+}
+
+func TestVolatileKeyAndClass(t *testing.T) {
+	softwareK, err := 注册表类.I打开表项(注册表类.CURRENT_USER, "Software", 注册表类.QUERY_VALUE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer softwareK.I关闭()
+
+	testKName := randKeyName("TestVolatileKey_")
+	k, existed, _, err := 注册表类.I创建表项Ex(softwareK, testKName, 注册表类.I打开选项{
+		Access:  注册表类.ALL_ACCESS,
+		Options: 注册表类.OPTION_VOLATILE,
+		Class:   "TestClass",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer 注册表类.I删除表项(softwareK, testKName)
+	defer k.I关闭()
+	if existed {
+		t.Fatalf("key %q already exists", testKName)
+	}
+
+	info, err := k.I取对象信息()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Class != "TestClass" {
+		t.Errorf("got class %q, want %q", info.Class, "TestClass")
+	}
+
+	_, _, _, err = 注册表类.I创建表项Ex(k, "Stable", 注册表类.I打开选项{Access: 注册表类.ALL_ACCESS, View: 注册表类.I视图_本机})
+	if err != syscall.Errno(1021) { // ERROR_CHILD_MUST_BE_VOLATILE
+		t.Errorf("creating a stable key under a volatile key: got %v", err)
+	}
+}
+
+func TestSnapshotRestore(t *testing.T) {
+	softwareK, err := 注册表类.I打开表项(注册表类.CURRENT_USER, "Software", 注册表类.QUERY_VALUE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer softwareK.I关闭()
+
+	old := time.Date(2001, 2, 3, 4, 5, 6, 700, time.UTC)
+	tree := &注册表类.I离线表项{Name: "Snapshot", Class: "RootClass", ModTime: old,
+		Values: []*注册表类.I离线值{注册表类.I新建文本值("Name", "value")},
+		SubKeys: []*注册表类.I离线表项{
+			{Name: "Child", Class: "ChildClass", ModTime: old.Add(time.Hour)},
+		},
+	}
+	testKName := randKeyName("TestSnapshotRestore_")
+	if err := 注册表类.I写入离线表项(softwareK, testKName, tree); err != nil {
+		t.Fatal(err)
+	}
+	defer 注册表类.I删除表项(softwareK, testKName)
+	defer 注册表类.I删除表项(softwareK, testKName+`\Child`)
+
+	got, err := 注册表类.I读取离线表项(softwareK, testKName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got.Name = tree.Name
+	if diffs := 注册表类.I比较离线表项(tree, got); len(diffs) != 0 {
+		t.Errorf("restored tree differs: %v", diffs)
+	}
+
+	k, err := 注册表类.I打开表项(softwareK, testKName, 注册表类.SET_VALUE|注册表类.QUERY_VALUE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer k.I关闭()
+	if err := k.I设置写入时间(old); err != nil {
+		t.Fatal(err)
+	}
+	info, err := k.I取对象信息()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.I取写入时间().Equal(old) {
+		t.Errorf("got write time %v, want %v", info.I取写入时间(), old)
+	}
+}
+
+func TestSnapshotLinks(t *testing.T) {
+	user, err := windows.GetCurrentProcessToken().GetTokenUser()
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := `Software\` + randKeyName("TestSnapshotLinks_")
+	keep, _, err := 注册表类.I创建表项(注册表类.CURRENT_USER, path+`\Outside\Keep`, 注册表类.ALL_ACCESS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keep.I关闭()
+	defer 注册表类.I删除表项树(注册表类.CURRENT_USER, path)
+	root, err := 注册表类.I打开表项(注册表类.CURRENT_USER, path, 注册表类.ALL_ACCESS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer root.I关闭()
+	target := `HKU\` + user.User.Sid.String() + `\` + path + `\Outside`
+	link, err := 注册表类.I创建链接表项(root, `Tree\Link`, target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	link.I关闭()
+
+	tree, err := 注册表类.I读取离线表项(root, `Tree`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	snap := tree.I取子项("Link")
+	if !snap.I是链接() || len(snap.SubKeys) != 0 {
+		t.Fatalf("snapshot of the link: got %+v", snap)
+	}
+	want, _ := 注册表类.I转内核路径(target)
+	if got, err := snap.I取链接目标(); err != nil || got != want {
+		t.Errorf("snapshot link target: got %q, %v", got, err)
+	}
+
+	if err := 注册表类.I写入离线表项(root, `Copy`, tree); err != nil {
+		t.Fatal(err)
+	}
+	copied, err := 注册表类.I打开链接表项(root, `Copy\Link`, 注册表类.QUERY_VALUE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer copied.I关闭()
+	if got, err := copied.I取链接目标(); err != nil || got != want {
+		t.Errorf("restored link target: got %q, %v", got, err)
+	}
+	k, err := 注册表类.I打开表项(root, `Copy\Link\Keep`, 注册表类.QUERY_VALUE)
+	if err != nil {
+		t.Fatalf("restored link does not resolve to the target: %v", err)
+	}
+	k.I关闭()
+	// 再次写入时更新已有的链接。
+	if err := 注册表类.I写入离线表项(root, `Copy`, tree); err != nil {
+		t.Errorf("writing over the restored link: %v", err)
+	}
+}
+
+func TestIterators(t *testing.T) {
+	softwareK, err := 注册表类.I打开表项(注册表类.CURRENT_USER, "Software", 注册表类.QUERY_VALUE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer softwareK.I关闭()
+
+	testKName := randKeyName("TestIterators_")
+	k, _, err := 注册表类.I创建表项(softwareK, testKName, 注册表类.ALL_ACCESS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer 注册表类.I删除表项(softwareK, testKName)
+	defer k.I关闭()
+	for _, name := range []string{"a", "b", "c"} {
+		sub, _, err := 注册表类.I创建表项(k, name, 注册表类.ALL_ACCESS)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer 注册表类.I删除表项(k, name)
+		sub.I设置整数值32("V", 1)
+		sub.I关闭()
+		if err := k.I设置文本值(name, strings.Repeat(name, 1000)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var names []string
+	for e, err := range k.I遍历子项(context.Background()) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if e.Info == nil || e.Info.ValueCount != 1 {
+			t.Errorf("subkey %q: unexpected info %+v", e.Name, e.Info)
+		}
+		names = append(names, e.Name)
+	}
+	if len(names) != 3 {
+		t.Errorf("got subkeys %q, want 3", names)
+	}
+
+	count := 0
+	for v, err := range k.I遍历值(context.Background()) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v.Type != 注册表类.SZ || len(v.Data) != 2*1001 {
+			t.Errorf("value %q: type %d, %d bytes", v.Name, v.Type, len(v.Data))
+		}
+		count++
+		if count == 2 {
+			break
+		}
+	}
+	if count != 2 {
+		t.Errorf("early break: got %d values", count)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for _, err := range k.I遍历值(ctx) {
+		if err != context.Canceled {
+			t.Errorf("got %v, want context.Canceled", err)
+		}
+	}
+}
+
+func TestGenericValues(t *testing.T) {
+	path := `Software\` + randKeyName("TestGenericValues_")
+	k, _, err := 注册表类.I创建表项(注册表类.CURRENT_USER, path, 注册表类.ALL_ACCESS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer 注册表类.I删除表项(注册表类.CURRENT_USER, path)
+	defer k.I关闭()
+
+	if err := 注册表类.I设置(k, "Port", uint16(8080)); err != nil {
+		t.Fatal(err)
+	}
+	if err := 注册表类.I设置(k, "Timeout", 3*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if err := 注册表类.I设置(k, "Hosts", []string{"a", "b"}); err != nil {
+		t.Fatal(err)
+	}
+	if port, err := 注册表类.I取[uint16](k, "Port"); err != nil || port != 8080 {
+		t.Errorf("Port: got %d, %v", port, err)
+	}
+	if _, err := 注册表类.I取[uint8](k, "Port"); !errors.Is(err, 注册表类.ErrOverflow) {
+		t.Errorf("Port as uint8: got %v, want ErrOverflow", err)
+	}
+	if d, err := 注册表类.I取[time.Duration](k, "Timeout"); err != nil || d != 3*time.Second {
+		t.Errorf("Timeout: got %v, %v", d, err)
+	}
+	if hosts, err := 注册表类.I取[[]string](k, "Hosts"); err != nil || len(hosts) != 2 {
+		t.Errorf("Hosts: got %q, %v", hosts, err)
+	}
+	if n, err := 注册表类.I取或默认(k, "Missing", 7); err != nil || n != 7 {
+		t.Errorf("Missing: got %d, %v", n, err)
+	}
+	k.I设置文本值("Enabled", "1")
+	if _, err := 注册表类.I取或默认(k, "Enabled", false); err != 注册表类.ErrUnexpectedType {
+		t.Errorf("Enabled without coercion: got %v", err)
+	}
+	if b, err := 注册表类.I取或默认(k, "Enabled", false, 注册表类.COERCE_TEXT); err != nil || !b {
+		t.Errorf("Enabled: got %v, %v", b, err)
+	}
+}
+
+func TestLayeredConfigWatch(t *testing.T) {
+	path := `Software\` + randKeyName("TestLayeredConfig_")
+	for _, sub := range []string{"Policy", "User"} {
+		k, _, err := 注册表类.I创建表项(注册表类.CURRENT_USER, path+`\`+sub, 注册表类.ALL_ACCESS)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer 注册表类.I删除表项(注册表类.CURRENT_USER, path+`\`+sub)
+		k.I关闭()
+	}
+	defer 注册表类.I删除表项(注册表类.CURRENT_USER, path)
+
+	var layers []注册表类.I配置层
+	for _, l := range []struct {
+		sub      string
+		readOnly bool
+	}{{"Policy", true}, {"User", false}, {`Missing\Deep`, false}} {
+		layer, err := 注册表类.I打开配置层(注册表类.CURRENT_USER, path+`\`+l.sub, l.sub, l.readOnly)
+		if err != nil {
+			t.Fatal(err)
+		}
+		layers = append(layers, layer)
+	}
+	if layers[2].Source != nil {
+		t.Error("missing key: want nil source")
+	}
+	c := 注册表类.I新建分层配置(layers...)
+	defer c.I关闭配置层()
+
+	if err := 注册表类.I设置配置(c, "User", "Level", uint32(1)); err != nil {
+		t.Fatal(err)
+	}
+	if err := 注册表类.I设置配置(c, "Policy", "Level", uint32(2)); !errors.Is(err, 注册表类.ErrLayerReadOnly) {
+		t.Errorf("write to policy: got %v, want ErrLayerReadOnly", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	updates := make(chan []*注册表类.I配置项, 10)
+	done := make(chan error, 1)
+	go func() { done <- c.I监视(ctx, func(m []*注册表类.I配置项) { updates <- m }) }()
+
+	next := func() []*注册表类.I配置项 {
+		select {
+		case m := <-updates:
+			return m
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for config update")
+			return nil
+		}
+	}
+	if m := next(); len(m) != 1 || m[0].Layer != "User" {
+		t.Fatalf("initial config: got %+v", m)
+	}
+	policy, err := 注册表类.I打开表项(注册表类.CURRENT_USER, path+`\Policy`, 注册表类.SET_VALUE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = 注册表类.I设置(policy, "Level", uint32(2))
+	policy.I关闭()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m := next(); len(m) != 1 || m[0].Layer != "Policy" {
+		t.Errorf("after policy change: got %+v", m)
+	}
+
+	// 缺失的层先出现中间的子项, 再出现层本身, 之后它的值进入合并结果。
+	for _, sub := range []string{`Missing`, `Missing\Deep`} {
+		k, _, err := 注册表类.I创建表项(注册表类.CURRENT_USER, path+`\`+sub, 注册表类.ALL_ACCESS)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer 注册表类.I删除表项(注册表类.CURRENT_USER, path+`\`+sub)
+		if sub != `Missing` {
+			err = 注册表类.I设置(k, "Extra", uint32(3))
+		}
+		k.I关闭()
+		if err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	if m := next(); len(m) != 2 || m[0].Layer != `Missing\Deep` {
+		t.Errorf("after the missing layer appeared: got %+v", m)
+	}
+	if c.I取层(`Missing\Deep`).Source == nil {
+		t.Error("the appeared layer was not opened")
+	}
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("watch returned %v, want context.Canceled", err)
+	}
+}
+
+func TestBindKey(t *testing.T) {
+	path := `Software\` + randKeyName("TestBindKey_")
+	k, _, err := 注册表类.I创建表项(注册表类.CURRENT_USER, path, 注册表类.ALL_ACCESS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer 注册表类.I删除表项(注册表类.CURRENT_USER, path)
+	defer k.I关闭()
+	k.I设置文本值("Name", "first")
+
+	type config struct {
+		Name  string
+		Level uint32
+	}
+	changes := make(chan *config, 10)
+	b, err := 注册表类.I绑定表项(注册表类.CURRENT_USER, path, 注册表类.I绑定选项[config]{
+		Debounce: 50 * time.Millisecond,
+		OnChange: func(_, new *config) { changes <- new },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.I关闭()
+	if b.I取().Name != "first" {
+		t.Fatalf("initial: got %+v", b.I取())
+	}
+	k.I设置文本值("Name", "second")
+	注册表类.I设置(k, "Level", uint32(3))
+	select {
+	case c := <-changes:
+		if c.Name != "second" || c.Level != 3 {
+			t.Errorf("reloaded: got %+v", c)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for reload")
+	}
+	if err := b.I关闭(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestDesiredState(t *testing.T) {
+	path := `Software\` + randKeyName("TestDesiredState_")
+	root, _, err := 注册表类.I创建表项(注册表类.CURRENT_USER, path, 注册表类.ALL_ACCESS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer 注册表类.I删除表项树(注册表类.CURRENT_USER, path)
+	defer root.I关闭()
+	old, _, err := 注册表类.I创建表项(root, `Old\Child`, 注册表类.ALL_ACCESS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	old.I关闭()
+
+	desired, err := 注册表类.I解析期望状态([]byte(`{"keys": [
+		{"path": "App\\Settings", "values": [
+			{"name": "Level", "type": "dword", "data": 3},
+			{"name": "Hosts", "type": "multi_sz", "data": ["a", "b"]}]},
+		{"path": "Old", "absent": true}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan, err := 注册表类.I计划表项(root, desired)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan) != 4 {
+		t.Fatalf("plan:\n%s", 注册表类.I格式化计划(plan))
+	}
+	if err := 注册表类.I应用计划(root, plan); err != nil {
+		t.Fatal(err)
+	}
+	// Applying the same plan again is harmless.
+	if err := 注册表类.I应用计划(root, plan); err != nil {
+		t.Fatal(err)
+	}
+	plan, err = 注册表类.I计划表项(root, desired)
+	if code := 注册表类.I漂移退出码(plan, err); code != 注册表类.I退出码_无漂移 {
+		t.Errorf("exit code after apply: got %d\n%s", code, 注册表类.I格式化计划(plan))
+	}
+
+	k, err := 注册表类.I打开表项(root, `App\Settings`, 注册表类.ALL_ACCESS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer k.I关闭()
+	k.I设置整数值32("Level", 4)
+	plan, err = 注册表类.I计划表项(root, desired)
+	if code := 注册表类.I漂移退出码(plan, err); code != 注册表类.I退出码_有漂移 || len(plan) != 1 {
+		t.Errorf("exit code after drift: got %d\n%s", code, 注册表类.I格式化计划(plan))
+	}
+}
+
+func TestExportImportSubtree(t *testing.T) {
+	path := `Software\` + randKeyName("TestExport_")
+	k, _, err := 注册表类.I创建表项(注册表类.CURRENT_USER, path+`\Child`, 注册表类.ALL_ACCESS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer 注册表类.I删除表项树(注册表类.CURRENT_USER, path)
+	k.I设置文本值("", "")
+	k.I设置文本值_数组("Multi", []string{"a", "b"})
+	k.I设置字节集值("Blob", []byte{1, 2, 3})
+	k.I关闭()
+
+	for _, format := range []注册表类.I导出格式{注册表类.I格式_JSON, 注册表类.I格式_YAML} {
+		data, err := 注册表类.I导出子树(注册表类.CURRENT_USER, path, format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		copyPath := path + `\Copy`
+		if err := 注册表类.I导入子树(注册表类.CURRENT_USER, copyPath, data, format); err != nil {
+			t.Fatal(err)
+		}
+		orig, _ := 注册表类.I读取离线表项(注册表类.CURRENT_USER, path+`\Child`)
+		copied, err := 注册表类.I读取离线表项(注册表类.CURRENT_USER, copyPath+`\Child`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		copied.Name = orig.Name
+		if d := 注册表类.I比较离线表项(orig, copied, 注册表类.I比较选项{IgnoreModTime: true}); len(d) != 0 {
+			t.Errorf("format %d: differences after import: %v", format, d)
+		}
+		注册表类.I删除表项树(注册表类.CURRENT_USER, copyPath)
+	}
+}
+
+func TestRecordReplay(t *testing.T) {
+	path := `Software\` + randKeyName("TestRecord_")
+	k, _, err := 注册表类.I创建表项(注册表类.CURRENT_USER, path, 注册表类.ALL_ACCESS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer 注册表类.I删除表项树(注册表类.CURRENT_USER, path)
+	defer k.I关闭()
+
+	run := func(s 注册表类.I注册表会话) {
+		if _, err := s.I创建表项(`Child`); err != nil {
+			t.Fatal(err)
+		}
+		v, _ := 注册表类.I编码值("Level", uint32(3))
+		if err := s.I设置值(`Child`, v); err != nil {
+			t.Fatal(err)
+		}
+		if got, err := s.I取值(`Child`, "Level"); err != nil || got.Type != 注册表类.DWORD {
+			t.Errorf("Level: got %v, %v", got, err)
+		}
+		if _, err := s.I取值(`Child`, "Missing"); !errors.Is(err, 注册表类.ErrNotExist) {
+			t.Errorf("Missing: got %v, want ErrNotExist", err)
+		}
+		if names, err := s.I列出子项(``); err != nil || len(names) != 1 {
+			t.Errorf("EnumKeys: got %q, %v", names, err)
+		}
+		if st, err := s.I取信息(`Child`); err != nil || st.ValueCount != 1 {
+			t.Errorf("Stat: got %+v, %v", st, err)
+		}
+		if err := s.I删除表项(`Child`); err != nil {
+			t.Error(err)
+		}
+	}
+	rec, err := 注册表类.I新建录制器(k, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	run(rec)
+
+	name := t.TempDir() + `\cassette.json`
+	if err := rec.I取录像带().I保存(name); err != nil {
+		t.Fatal(err)
+	}
+	c, err := 注册表类.I读取录像带(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Calls) != 7 || c.Calls[3].Err == nil || c.Calls[3].Err.Code != 2 {
+		t.Fatalf("cassette: got %+v", c.Calls)
+	}
+	run(注册表类.I新建回放器(t, c))
+
+	// Key结构 calls made through the recorder are recorded with the caller's access mask.
+	rec, err = 注册表类.I新建录制器(k, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rk, err := rec.I录制表项()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sub, _, err := 注册表类.I创建表项(rk, `Other`, 注册表类.QUERY_VALUE|注册表类.SET_VALUE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sub.I关闭()
+	calls := rec.I取录像带().Calls
+	if len(calls) != 1 || calls[0].Op != 注册表类.I操作_创建表项 || calls[0].Path != `Other` ||
+		calls[0].Access != 注册表类.QUERY_VALUE|注册表类.SET_VALUE {
+		t.Errorf("Key结构 calls: got %+v", calls)
+	}
+}
+
+func TestKeyFaultInjection(t *testing.T) {
+	path := `Software\` + randKeyName("TestFault_")
+	k, _, err := 注册表类.I创建表项(注册表类.CURRENT_USER, path, 注册表类.ALL_ACCESS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer 注册表类.I删除表项树(注册表类.CURRENT_USER, path)
+	defer k.I关闭()
+	if err := k.I设置文本值("Theme", "dark"); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := 注册表类.I创建表项(k, `Child`, 注册表类.ALL_ACCESS); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := 注册表类.I新建故障拦截器(1,
+		// The value grows between the size query and the read, once.
+		注册表类.I故障规则{Op: 注册表类.I操作_取值, Nth: 1, Err: 注册表类.ErrShortBuffer},
+		// The first, most privileged open attempt is denied.
+		注册表类.I故障规则{Op: 注册表类.I操作_打开表项, Nth: 1, Err: syscall.ERROR_ACCESS_DENIED},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fk, err := 注册表类.I启用拦截(k, f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if theme, err := 注册表类.I取[string](fk, "Theme"); err != nil || theme != "dark" {
+		t.Errorf("I取 after ErrShortBuffer: got %q, %v", theme, err)
+	}
+	if theme, _, err := fk.I取文本值("Theme"); err != nil || theme != "dark" {
+		t.Errorf("I取文本值: got %q, %v", theme, err)
+	}
+	child, err := 注册表类.I打开表项(fk, `Child`)
+	if err != nil {
+		t.Fatalf("open with fallback: %v", err)
+	}
+	child.I关闭()
+	if n := f.I注入次数(); n != 2 {
+		t.Errorf("injections: got %d, want 2", n)
+	}
+
+	// The injected ErrSessionNotExist is a *I录制的错误, not ErrNotExist itself.
+	f, _ = 注册表类.I新建故障拦截器(1, 注册表类.I故障规则{Op: 注册表类.I操作_取值, Err: 注册表类.ErrSessionNotExist})
+	fk, _ = 注册表类.I启用拦截(k, f)
+	if theme, err := 注册表类.I取或默认(fk, "Theme", "light"); err != nil || theme != "light" {
+		t.Errorf("I取或默认 of a missing value: got %q, %v", theme, err)
+	}
+
+	// An error that is not ERROR_ACCESS_DENIED stops the fallback.
+	f, _ = 注册表类.I新建故障拦截器(1, 注册表类.I故障规则{Op: 注册表类.I操作_打开表项, Err: 注册表类.ErrNotExist})
+	fk, _ = 注册表类.I启用拦截(k, f)
+	if _, err := 注册表类.I打开表项(fk, `Child`); err != 注册表类.ErrNotExist || f.I注入次数() != 1 {
+		t.Errorf("open: got %v after %d attempts", err, f.I注入次数())
+	}
+}
+
+func TestAuditKey(t *testing.T) {
+	path := `Software\` + randKeyName("TestAudit_")
+	root, _, err := 注册表类.I创建表项(注册表类.CURRENT_USER, path, 注册表类.ALL_ACCESS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer 注册表类.I删除表项树(注册表类.CURRENT_USER, path)
+	defer root.I关闭()
+	if err := root.I设置整数值32("Level", 1); err != nil {
+		t.Fatal(err)
+	}
+
+	name := t.TempDir() + `\audit.jsonl`
+	l, err := 注册表类.I打开审计日志(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ak, err := l.I审计表项(root, "alice", "CHG-2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, _ := 注册表类.I编码值("Level", uint32(3))
+	plan := []注册表类.I离线差异{{Kind: 注册表类.I差异_修改值, Value: "Level", New: v}}
+	if err := 注册表类.I应用计划(ak, plan); err != nil {
+		t.Fatal(err)
+	}
+	snap := &注册表类.I离线表项{Values: []*注册表类.I离线值{v}}
+	if err := 注册表类.I写入离线表项(ak, `Snap`, snap); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := ak.I取文本值("Missing"); err == nil { // reads are not recorded
+		t.Error("Missing: no error")
+	}
+	l.I关闭()
+
+	data, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var r 注册表类.I审计记录
+		if err := json.Unmarshal([]byte(line), &r); err != nil {
+			t.Fatal(err)
+		}
+		if r.Actor != "alice" || r.Outcome != 注册表类.I审计_成功 || r.Path == "" && r.Name == "Level" && r.Old == nil {
+			t.Errorf("record %d: %+v", r.Seq, r)
+		}
+		got = append(got, r.Op+" "+r.Path+" "+r.Name)
+	}
+	want := []string{"CreateKey  ", "SetValue  Level", "CreateKey Snap ", "SetValue Snap Level"}
+	if !slices.Equal(got, want) {
+		t.Errorf("audit records:\n got %q\nwant %q", got, want)
+	}
+	if level, err := 注册表类.I取[uint32](root, "Level"); err != nil || level != 3 {
+		t.Errorf("Level: got %d, %v", level, err)
+	}
+}
+
+func TestDryRunLive(t *testing.T) {
+	path := `Software\` + randKeyName("TestDryRun_")
+	k, _, err := 注册表类.I创建表项(注册表类.CURRENT_USER, path, 注册表类.ALL_ACCESS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer 注册表类.I删除表项树(注册表类.CURRENT_USER, path)
+	defer k.I关闭()
+	k.I设置整数值32("Port", 8080)
+
+	live, err := 注册表类.I新建表项会话(k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := 注册表类.I新建试运行(live, `HKEY_CURRENT_USER\`+path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, _ := 注册表类.I编码值("Port", uint32(9090))
+	if err := d.I设置值(``, v); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.I创建表项(`Child`); err != nil {
+		t.Fatal(err)
+	}
+	if port, err := 注册表类.I会话取[uint32](d, ``, "Port"); err != nil || port != 9090 {
+		t.Errorf("dry-run Port: got %d, %v", port, err)
+	}
+	if port, _, err := k.I取整数值64("Port"); err != nil || port != 8080 {
+		t.Errorf("registry was written: Port = %d, %v", port, err)
+	}
+	if _, err := 注册表类.I打开表项(k, `Child`, 注册表类.QUERY_VALUE); !errors.Is(err, 注册表类.ErrNotExist) {
+		t.Errorf("Child was created: %v", err)
+	}
+	plan, err := d.I差异()
+	if err != nil || len(plan) != 2 {
+		t.Errorf("plan: got %v, %v", plan, err)
+	}
+}
+
+func TestOverlayLocalMachine(t *testing.T) {
+	hklm, err := 注册表类.I新建表项会话(注册表类.LOCAL_MACHINE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	o, err := 注册表类.I新建覆盖会话(hklm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	name := randKeyName("TestOverlay_")
+	if _, err := o.I创建表项(`SOFTWARE\` + name); err != nil {
+		t.Fatal(err)
+	}
+	v, _ := 注册表类.I编码值("Port", uint32(9090))
+	if err := o.I设置值(`SOFTWARE\`+name, v); err != nil {
+		t.Fatal(err)
+	}
+	sw, err := 注册表类.I打开会话表项(o, `SOFTWARE`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names, err := sw.I取所有子项名称(-1)
+	if err != nil || !slices.Contains(names, name) || !slices.Contains(names, "Microsoft") {
+		t.Errorf("merged SOFTWARE subkeys do not contain %s and Microsoft: %v", name, err)
+	}
+	if _, err := 注册表类.I打开表项(注册表类.LOCAL_MACHINE, `SOFTWARE\`+name, 注册表类.QUERY_VALUE); !errors.Is(err, 注册表类.ErrNotExist) {
+		t.Errorf("LOCAL_MACHINE was written: %v", err)
+	}
+
+	// 把只读基础上的变更提交到另一个可写的表项。
+	path := `Software\` + randKeyName("TestOverlayTarget_")
+	k, _, err := 注册表类.I创建表项(注册表类.CURRENT_USER, path, 注册表类.ALL_ACCESS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer 注册表类.I删除表项树(注册表类.CURRENT_USER, path)
+	defer k.I关闭()
+	if _, _, err := 注册表类.I创建表项(k, `SOFTWARE`, 注册表类.ALL_ACCESS); err != nil {
+		t.Fatal(err)
+	}
+	target, err := 注册表类.I新建表项会话(k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := o.I提交(target); err != nil {
+		t.Fatal(err)
+	}
+	if port, err := 注册表类.I会话取[uint32](target, `SOFTWARE\`+name, "Port"); err != nil || port != 9090 {
+		t.Errorf("committed Port: got %d, %v", port, err)
+	}
+	if plan, _ := o.I差异(); len(plan) != 0 {
+		t.Errorf("overlay not cleared: %v", plan)
+	}
+}
@@ -0,0 +1,88 @@
+package 注册表类
+
+// I试运行 是不修改注册表的会话: 读取经过真实的基础会话, 写入只进入覆盖层,
+// 所以同一次运行中之后的读取能看到之前的写入。运行结束后用 I生成REG报告 或
+// I生成JSON报告 查看这次运行本来会做的全部修改。
+// 它不提供 I覆盖会话.I提交, 试运行的修改不会被写回基础会话。
+type I试运行 struct {
+	覆盖  *I覆盖会话
+	根路径 string
+}
+
+// I新建试运行 返回叠加在基础会话上的试运行会话。根路径是基础会话的根项的完整路径,
+// 例如 `HKEY_LOCAL_MACHINE\SOFTWARE\Contoso`, 只用于报告。
+func I新建试运行(基础 I注册表会话, 根路径 string) (*I试运行, error) {
+	覆盖, err := I新建覆盖会话(基础)
+	if err != nil {
+		return nil, err
+	}
+	return &I试运行{覆盖: 覆盖, 根路径: 根路径}, nil
+}
+
+// I打开表项 实现 I注册表会话。
+func (d *I试运行) I打开表项(路径 string, 访问权限 uint32) error {
+	return d.覆盖.I打开表项(路径, 访问权限)
+}
+
+// I取值 实现 I注册表会话。
+func (d *I试运行) I取值(路径, 名称 string) (*I离线值, error) {
+	return d.覆盖.I取值(路径, 名称)
+}
+
+// I列出值 实现 I注册表会话。
+func (d *I试运行) I列出值(路径 string) ([]*I离线值, error) {
+	return d.覆盖.I列出值(路径)
+}
+
+// I列出子项 实现 I注册表会话。
+func (d *I试运行) I列出子项(路径 string) ([]string, error) {
+	return d.覆盖.I列出子项(路径)
+}
+
+// I设置值 实现 I注册表会话, 值只写入覆盖层。
+func (d *I试运行) I设置值(路径 string, 值 *I离线值) error {
+	return d.覆盖.I设置值(路径, 值)
+}
+
+// I删除值 实现 I注册表会话, 只在覆盖层中记录删除。
+func (d *I试运行) I删除值(路径, 名称 string) error {
+	return d.覆盖.I删除值(路径, 名称)
+}
+
+// I创建表项 实现 I注册表会话, 表项只在覆盖层中创建。
+func (d *I试运行) I创建表项(路径 string, 访问权限 ...uint32) (bool, error) {
+	return d.覆盖.I创建表项(路径, 访问权限...)
+}
+
+// I删除表项 实现 I注册表会话, 只在覆盖层中记录删除。
+func (d *I试运行) I删除表项(路径 string) error {
+	return d.覆盖.I删除表项(路径)
+}
+
+// I取信息 实现 I注册表会话。
+func (d *I试运行) I取信息(路径 string) (*I表项统计, error) {
+	return d.覆盖.I取信息(路径)
+}
+
+// I差异 返回这次运行本来会做的修改, 见 I覆盖会话.I差异。
+func (d *I试运行) I差异() ([]I离线差异, error) {
+	return d.覆盖.I差异()
+}
+
+// I生成REG报告 以.reg文件的形式返回本来会做的修改, 见 I导出REG。
+func (d *I试运行) I生成REG报告() ([]byte, error) {
+	变更, err := d.I差异()
+	if err != nil {
+		return nil, err
+	}
+	return I导出REG(d.根路径, 变更), nil
+}
+
+// I生成JSON报告 以JSON的形式返回本来会做的修改, 见 I导出变更JSON。
+func (d *I试运行) I生成JSON报告() ([]byte, error) {
+	变更, err := d.I差异()
+	if err != nil {
+		return nil, err
+	}
+	return I导出变更JSON(d.根路径, 变更)
+}
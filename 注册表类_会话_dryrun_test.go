@@ -0,0 +1,196 @@
+package 注册表类_test
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"unicode/utf16"
+
+	注册表类 "e.coding.net/gogit/go/gosdk/core/win_registry_cn"
+)
+
+func decodeREG(t *testing.T, b []byte) string {
+	t.Helper()
+	if len(b) < 2 || b[0] != 0xff || b[1] != 0xfe || len(b)%2 != 0 {
+		t.Fatalf("not UTF-16LE with BOM: % x", b[:min(len(b), 8)])
+	}
+	u := make([]uint16, 0, len(b)/2-1)
+	for i := 2; i < len(b); i += 2 {
+		u = append(u, uint16(b[i])|uint16(b[i+1])<<8)
+	}
+	return string(utf16.Decode(u))
+}
+
+func TestDryRunMergedView(t *testing.T) {
+	base := testSession(t)
+	s, err := 注册表类.I新建试运行(base, `HKEY_LOCAL_MACHINE\`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const p = `Software\Contoso`
+	if err := s.I设置值(p, dword("Port", 9090)); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.I设置值(p, sz("New", "x")); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.I删除值(p, "theme"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.I删除值(p, "Theme"); !errors.Is(err, 注册表类.ErrSessionNotExist) {
+		t.Errorf("deleting a deleted value: got %v", err)
+	}
+	if err := s.I删除表项(p + `\B`); err != nil {
+		t.Fatal(err)
+	}
+	if existed, err := s.I创建表项(p + `\Z\Deep`); err != nil || existed {
+		t.Fatalf("create: got %v, %v", existed, err)
+	}
+	if err := s.I删除表项(p + `\Z`); !errors.Is(err, 注册表类.ErrSessionAccessDenied) {
+		t.Errorf("deleting a key with subkeys: got %v", err)
+	}
+	if err := s.I设置值(p+`\Missing`, sz("a", "b")); !errors.Is(err, 注册表类.ErrSessionNotExist) {
+		t.Errorf("setting a value under a missing key: got %v", err)
+	}
+
+	if port, _ := 注册表类.I会话取[uint32](s, p, "Port"); port != 9090 {
+		t.Errorf("overlay Port: got %d", port)
+	}
+	if port, _ := 注册表类.I会话取[uint32](base, p, "Port"); port != 8080 {
+		t.Errorf("base was written: Port = %d", port)
+	}
+	var names []string
+	values, _ := s.I列出值(p)
+	for _, v := range values {
+		names = append(names, v.Name)
+	}
+	if got := strings.Join(names, ","); got != "Port,New" {
+		t.Errorf("values: got %s", got)
+	}
+	if keys, _ := s.I列出子项(p); strings.Join(keys, ",") != "A,C,Z" {
+		t.Errorf("subkeys: got %q", keys)
+	}
+	if keys, _ := base.I列出子项(p); len(keys) != 3 {
+		t.Errorf("base subkeys changed: %q", keys)
+	}
+	if st, err := s.I取信息(p); err != nil || st.ValueCount != 2 || st.SubKeyCount != 3 {
+		t.Errorf("stat: got %+v, %v", st, err)
+	}
+	if _, err := s.I取信息(p + `\B`); !errors.Is(err, 注册表类.ErrSessionNotExist) {
+		t.Errorf("deleted key: got %v", err)
+	}
+
+	// 删除后重新创建的表项不再显示基础中的内容。
+	if err := s.I删除表项(p + `\A`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.I创建表项(p + `\A`); err != nil {
+		t.Fatal(err)
+	}
+	if st, _ := s.I取信息(p + `\A`); st.ValueCount != 0 {
+		t.Errorf("recreated key shows base values: %+v", st)
+	}
+}
+
+func TestDryRunReport(t *testing.T) {
+	d, err := 注册表类.I新建试运行(testSession(t), `HKEY_LOCAL_MACHINE\`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const p = `Software\Contoso`
+	d.I设置值(p, dword("Port", 9090))
+	d.I设置值(p, dword("Port", 8080)) // 改回原值后不再是变更
+	d.I设置值(p, sz("Theme", `C:\"light"`))
+	d.I删除值(p, "Theme")
+	d.I设置值(p, sz("", "default"))
+	d.I删除表项(p + `\B`)
+	d.I创建表项(p + `\New`)
+	d.I设置值(p+`\New`, &注册表类.I离线值{Name: "Blob", Type: 注册表类.BINARY, Data: make([]byte, 30)})
+	d.I设置值(p+`\New`, &注册表类.I离线值{Name: "Multi", Type: 注册表类.MULTI_SZ, Data: []byte("a\x00\x00\x00")})
+
+	plan, err := d.I差异()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `- value deleted Software\Contoso ["Theme"]
++ value added Software\Contoso [""] = REG_SZ "default"
+- key deleted Software\Contoso\B
++ key added Software\Contoso\New
++ value added Software\Contoso\New ["Blob"] = REG_BINARY 000000000000000000000000000000000000000000000000000000000000
++ value added Software\Contoso\New ["Multi"] = REG_MULTI_SZ ["a"]
+Plan: 4 to add, 0 to change, 2 to destroy.
+`
+	if got := 注册表类.I格式化计划(plan); got != want {
+		t.Errorf("plan:\n%s\nwant:\n%s", got, want)
+	}
+
+	b, err := d.I生成REG报告()
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantREG := "Windows Registry Editor Version 5.00\r\n" +
+		"\r\n[HKEY_LOCAL_MACHINE\\Software\\Contoso]\r\n" +
+		"\"Theme\"=-\r\n" +
+		"@=\"default\"\r\n" +
+		"\r\n[-HKEY_LOCAL_MACHINE\\Software\\Contoso\\B]\r\n" +
+		"\r\n[HKEY_LOCAL_MACHINE\\Software\\Contoso\\New]\r\n" +
+		"\"Blob\"=hex:00,00,00,00,00,00,00,00,00,00,00,00,00,00,00,00,00,00,00,00,00,00,\\\r\n" +
+		"  00,00,00,00,00,00,00,00\r\n" +
+		"\"Multi\"=hex(7):61,00,00,00\r\n" +
+		"\r\n"
+	if got := decodeREG(t, b); got != wantREG {
+		t.Errorf(".reg report:\n%q\nwant:\n%q", got, wantREG)
+	}
+
+	j, err := d.I生成JSON报告()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var report []map[string]any
+	if err := json.Unmarshal(j, &report); err != nil {
+		t.Fatal(err)
+	}
+	if len(report) != len(plan) || report[0]["kind"] != "value deleted" ||
+		report[0]["path"] != `HKEY_LOCAL_MACHINE\Software\Contoso` || report[0]["name"] != "Theme" ||
+		report[1]["name"] != "" || report[2]["name"] != nil {
+		t.Errorf("JSON report: %s", j)
+	}
+
+	// 把变更应用到基础的副本后应该得到与试运行相同的视图。
+	copyRoot := &注册表类.I离线表项{}
+	base, _ := 注册表类.I新建离线会话(copyRoot)
+	base.I创建表项(p)
+	for _, sub := range []string{"A", "B", "C"} {
+		base.I创建表项(p + `\` + sub)
+	}
+	base.I设置值(p, dword("Port", 8080))
+	base.I设置值(p, sz("Theme", "dark"))
+	if err := 注册表类.I应用到离线表项(copyRoot, plan); err != nil {
+		t.Fatal(err)
+	}
+	if keys, _ := base.I列出子项(p); strings.Join(keys, ",") != "A,C,New" {
+		t.Errorf("applied subkeys: %q", keys)
+	}
+	if st, _ := base.I取信息(p); st.ValueCount != 2 {
+		t.Errorf("applied values: %+v", st)
+	}
+}
+
+func TestREGValueFormats(t *testing.T) {
+	plan := []注册表类.I离线差异{
+		{Kind: 注册表类.I差异_新增值, Path: `K`, Value: "s", New: &注册表类.I离线值{Name: "s", Type: 注册表类.SZ, Data: []byte("a\x00")}},
+		{Kind: 注册表类.I差异_新增值, Path: `K`, Value: "q", New: &注册表类.I离线值{Name: "q", Type: 注册表类.QWORD, Data: []byte{1, 0, 0, 0, 0, 0, 0, 0}}},
+		{Kind: 注册表类.I差异_新增值, Path: `K`, Value: "d", New: dword("d", 0xdeadbeef)},
+	}
+	got := decodeREG(t, 注册表类.I导出REG(`HKEY_CURRENT_USER`, plan))
+	for _, want := range []string{
+		`"s"=hex(1):61,00` + "\r\n", // 没有结尾NUL的字符串不能用引号形式
+		`"q"=hex(b):01,00,00,00,00,00,00,00` + "\r\n",
+		`"d"=dword:deadbeef` + "\r\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf(".reg output %q does not contain %q", got, want)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package 注册表类
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// I故障规则 描述 I故障注入 在哪些调用上注入什么故障。
+type I故障规则 struct {
+	// Op 是 I操作_取值 等操作名称, 为空时匹配所有操作。
+	Op string
+	// Path 是表项路径的模式, 语法与 path.Match 相同但以反斜杠分隔, 不区分大小写。
+	// 为空时匹配所有路径。
+	Path string
+	// Nth 大于0时只在第Nth次匹配的调用上触发, 否则每次匹配都可能触发。
+	Nth int
+	// Probability 大于0时触发的概率, 由 I新建故障注入 的种子决定, 所以结果可以重现。
+	Probability float64
+	// Err 是触发时返回的错误, 例如 ErrSessionAccessDenied。调用不会传给内部会话,
+	// 除非设置了Partial。
+	Err error
+	// Latency 是触发时在调用前等待的时间。
+	Latency time.Duration
+	// Partial 大于0时, 列出值和列出子项的调用照常执行, 但只返回前Partial个结果和Err,
+	// 模拟枚举中途失败或表项被同时删除。
+	Partial int
+}
+
+// I故障拦截器 是按规则在调用上注入错误、延迟或不完整的枚举结果的拦截器。规则按顺序检查,
+// 第一个触发的规则生效; 没有规则触发时调用原样继续。它可以用于 I新建拦截会话,
+// 也可以用 I启用拦截 装到 Key结构 上, 这样 I取值、I取 等读取值的函数中重试
+// ErrShortBuffer 的路径, 以及 I打开表项 遇到ERROR_ACCESS_DENIED后降级的路径都可以测试。
+type I故障拦截器 struct {
+	规则   []I故障规则
+	计数   []int
+	随机   *rand.Rand
+	注入次数 int
+	锁    sync.Mutex
+}
+
+// I新建故障拦截器 返回按规则注入故障的拦截器, 相同的种子和调用序列总是注入相同的故障。
+func I新建故障拦截器(种子 int64, 规则 ...I故障规则) (*I故障拦截器, error) {
+	for i, r := range 规则 {
+		if _, err := path.Match(规则模式(r.Path), ""); err != nil {
+			return nil, fmt.Errorf("规则 %d 的路径模式 %q: %w", i, r.Path, err)
+		}
+		if r.Probability < 0 || r.Probability > 1 {
+			return nil, fmt.Errorf("规则 %d 的概率 %v 不在0到1之间", i, r.Probability)
+		}
+	}
+	return &I故障拦截器{
+		规则: 规则,
+		计数: make([]int, len(规则)),
+		随机: rand.New(rand.NewSource(种子)),
+	}, nil
+}
+
+// I故障注入 包装另一个会话, 用 I故障拦截器 在调用上注入故障, 实现 I注册表会话,
+// 用于测试调用方的错误处理。
+type I故障注入 struct {
+	*I故障拦截器
+	*I拦截会话
+}
+
+// I新建故障注入 返回包装内部会话的故障注入会话, 相同的种子和调用序列总是注入相同的故障。
+func I新建故障注入(内部 I注册表会话, 种子 int64, 规则 ...I故障规则) (*I故障注入, error) {
+	if 内部 == nil {
+		return nil, errors.New("注册表类对象为nil")
+	}
+	故障, err := I新建故障拦截器(种子, 规则...)
+	if err != nil {
+		return nil, err
+	}
+	会话, err := I新建拦截会话(内部, 故障)
+	if err != nil {
+		return nil, err
+	}
+	return &I故障注入{I故障拦截器: 故障, I拦截会话: 会话}, nil
+}
+
+// 规则模式 把反斜杠分隔的路径或模式转换为 path.Match 使用的形式。
+func 规则模式(s string) string {
+	return strings.ToUpper(strings.ReplaceAll(规范路径(s), `\`, "/"))
+}
+
+// I注入次数 返回已经触发的规则次数。
+func (f *I故障拦截器) I注入次数() int {
+	f.锁.Lock()
+	defer f.锁.Unlock()
+	return f.注入次数
+}
+
+// 触发 返回对调用生效的规则, 没有时返回nil。
+func (f *I故障拦截器) 触发(操作, 路径 string) *I故障规则 {
+	f.锁.Lock()
+	defer f.锁.Unlock()
+	p := 规则模式(路径)
+	for i := range f.规则 {
+		r := &f.规则[i]
+		if r.Op != "" && r.Op != 操作 {
+			continue
+		}
+		if r.Path != "" {
+			if ok, _ := path.Match(规则模式(r.Path), p); !ok {
+				continue
+			}
+		}
+		f.计数[i]++
+		if r.Nth > 0 && f.计数[i] != r.Nth {
+			continue
+		}
+		if r.Probability > 0 && f.随机.Float64() >= r.Probability {
+			continue
+		}
+		f.注入次数++
+		return r
+	}
+	return nil
+}
+
+// I拦截 实现 I拦截器。
+func (f *I故障拦截器) I拦截(调用 *I调用记录, 继续 func() error) error {
+	r := f.触发(调用.Op, 调用.Path)
+	if r == nil {
+		return 继续()
+	}
+	if r.Latency > 0 {
+		time.Sleep(r.Latency)
+	}
+	if r.Partial > 0 && (调用.Op == I操作_列出值 || 调用.Op == I操作_列出子项) {
+		if err := 继续(); err != nil {
+			return err
+		}
+		调用.Values = 截断(调用.Values, r.Partial)
+		调用.Names = 截断(调用.Names, r.Partial)
+		return r.Err
+	}
+	if r.Err != nil {
+		return r.Err
+	}
+	return 继续()
+}
+
+func 截断[E any](结果 []E, n int) []E {
+	if len(结果) > n {
+		return 结果[:n]
+	}
+	return 结果
+}
@@ -0,0 +1,174 @@
+package 注册表类_test
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+	"time"
+
+	注册表类 "e.coding.net/gogit/go/gosdk/core/win_registry_cn"
+)
+
+func testSession(t *testing.T) *注册表类.I离线会话 {
+	t.Helper()
+	root := &注册表类.I离线表项{SubKeys: []*注册表类.I离线表项{
+		{Name: "Software", SubKeys: []*注册表类.I离线表项{
+			{Name: "Contoso", Values: []*注册表类.I离线值{dword("Port", 8080), sz("Theme", "dark")},
+				SubKeys: []*注册表类.I离线表项{{Name: "A"}, {Name: "B"}, {Name: "C"}}},
+		}},
+	}}
+	s, err := 注册表类.I新建离线会话(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestOfflineSession(t *testing.T) {
+	s := testSession(t)
+	if port, err := 注册表类.I会话取[uint16](s, `software\contoso`, "port"); err != nil || port != 8080 {
+		t.Errorf("Port: got %d, %v", port, err)
+	}
+	if _, err := s.I取值(`Software\Contoso`, "Missing"); !errors.Is(err, fs.ErrNotExist) || !errors.Is(err, 注册表类.ErrSessionNotExist) {
+		t.Errorf("Missing: got %v", err)
+	}
+	if _, err := s.I列出值(`Software\Nope`); !errors.Is(err, 注册表类.ErrSessionNotExist) {
+		t.Errorf("missing key: got %v", err)
+	}
+	if existed, err := s.I创建表项(`Software\Contoso\A\New`); err != nil || existed {
+		t.Errorf("create: got %v, %v", existed, err)
+	}
+	if existed, err := s.I创建表项(`Software\Contoso\A\New`); err != nil || !existed {
+		t.Errorf("create again: got %v, %v", existed, err)
+	}
+	if err := s.I删除表项(`Software\Contoso\A`); !errors.Is(err, fs.ErrPermission) {
+		t.Errorf("delete key with subkeys: got %v", err)
+	}
+	if err := s.I删除表项(`Software\Contoso\A\New`); err != nil {
+		t.Error(err)
+	}
+	if err := s.I删除值(`Software\Contoso`, "Theme"); err != nil {
+		t.Error(err)
+	}
+	if err := s.I删除值(`Software\Contoso`, "Theme"); !errors.Is(err, 注册表类.ErrSessionNotExist) {
+		t.Errorf("delete missing value: got %v", err)
+	}
+}
+
+func TestOfflineSessionToken(t *testing.T) {
+	sd, _ := 注册表类.I解析SDDL("O:BAG:SYD:P(A;CI;KA;;;BA)(A;CI;KR;;;WD)")
+	root := &注册表类.I离线表项{SubKeys: []*注册表类.I离线表项{{Name: "Locked", Values: []*注册表类.I离线值{dword("Port", 1)}}}}
+	if err := root.SubKeys[0].I设置安全描述符(sd); err != nil {
+		t.Fatal(err)
+	}
+	s, err := 注册表类.I新建离线会话(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.I设置访问令牌(&注册表类.I访问令牌{User: "S-1-5-21-1-2-3-1001", Groups: []string{"WD", "BU"}})
+
+	if _, err := s.I取值("Locked", "Port"); err != nil {
+		t.Errorf("read: got %v", err)
+	}
+	if err := s.I设置值("Locked", dword("Port", 2)); !errors.Is(err, 注册表类.ErrSessionAccessDenied) {
+		t.Errorf("write to read-only key: got %v", err)
+	}
+	if err := s.I删除值("Locked", "Port"); !errors.Is(err, 注册表类.ErrSessionAccessDenied) {
+		t.Errorf("delete value of read-only key: got %v", err)
+	}
+	if _, err := s.I创建表项(`Locked\New`); !errors.Is(err, 注册表类.ErrSessionAccessDenied) {
+		t.Errorf("create under read-only key: got %v", err)
+	}
+	if err := s.I打开表项("Locked", 0x20006); !errors.Is(err, 注册表类.ErrSessionAccessDenied) { // KEY_WRITE
+		t.Errorf("open for write: got %v", err)
+	}
+
+	s.I设置访问令牌(&注册表类.I访问令牌{User: "S-1-5-21-1-2-3-500", Groups: []string{"WD", "BA"}})
+	if err := s.I设置值("Locked", dword("Port", 2)); err != nil {
+		t.Errorf("admin write: got %v", err)
+	}
+}
+
+func TestFaultInjectionRules(t *testing.T) {
+	f, err := 注册表类.I新建故障注入(testSession(t), 1,
+		注册表类.I故障规则{Op: 注册表类.I操作_取值, Path: `SOFTWARE\*`, Nth: 2, Err: 注册表类.ErrSessionAccessDenied},
+		注册表类.I故障规则{Op: 注册表类.I操作_列出子项, Partial: 2, Err: 注册表类.ErrSessionKeyDeleted},
+		注册表类.I故障规则{Op: 注册表类.I操作_设置值, Err: 注册表类.ErrSessionSharingViolation},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, wantErr := range []error{nil, 注册表类.ErrSessionAccessDenied, nil} {
+		_, err := f.I取值(`Software\Contoso`, "Port")
+		if !errors.Is(err, wantErr) || (wantErr == nil) != (err == nil) {
+			t.Errorf("call %d: got %v, want %v", i+1, err, wantErr)
+		}
+	}
+	if _, err := f.I取值(`Software`, "Port"); !errors.Is(err, 注册表类.ErrSessionNotExist) {
+		t.Errorf("unmatched path: got %v", err)
+	}
+	names, err := f.I列出子项(`Software\Contoso`)
+	if len(names) != 2 || !errors.Is(err, 注册表类.ErrSessionKeyDeleted) {
+		t.Errorf("partial enumeration: got %q, %v", names, err)
+	}
+	if err := f.I设置值(`Software\Contoso`, dword("Port", 1)); !errors.Is(err, 注册表类.ErrSessionSharingViolation) {
+		t.Errorf("set: got %v", err)
+	}
+	if port, _ := 注册表类.I会话取[uint32](f, `Software\Contoso`, "Port"); port != 8080 {
+		t.Errorf("failed set changed the value: got %d", port)
+	}
+	if n := f.I注入次数(); n != 3 {
+		t.Errorf("injections: got %d, want 3", n)
+	}
+
+	if _, err := 注册表类.I新建故障注入(testSession(t), 1, 注册表类.I故障规则{Path: `[`}); err == nil {
+		t.Error("bad pattern accepted")
+	}
+}
+
+func TestFaultInjectionDeterministic(t *testing.T) {
+	run := func(seed int64) []bool {
+		f, err := 注册表类.I新建故障注入(testSession(t), seed,
+			注册表类.I故障规则{Probability: 0.5, Err: 注册表类.ErrSessionMoreData})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var failed []bool
+		for range 32 {
+			_, err := 注册表类.I会话取[string](f, `Software\Contoso`, "Theme")
+			if err != nil && !errors.Is(err, 注册表类.ErrSessionMoreData) {
+				t.Fatal(err)
+			}
+			failed = append(failed, err != nil)
+		}
+		return failed
+	}
+	a, b := run(42), run(42)
+	n := 0
+	for i := range a {
+		if a[i] != b[i] {
+			t.Fatalf("call %d differs between runs with the same seed", i)
+		}
+		if a[i] {
+			n++
+		}
+	}
+	if n == 0 || n == len(a) {
+		t.Errorf("probability 0.5 injected %d of %d faults", n, len(a))
+	}
+}
+
+func TestFaultInjectionLatency(t *testing.T) {
+	f, err := 注册表类.I新建故障注入(testSession(t), 0,
+		注册表类.I故障规则{Op: 注册表类.I操作_列出值, Latency: 20 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	if values, err := f.I列出值(`Software\Contoso`); err != nil || len(values) != 2 {
+		t.Errorf("got %d values, %v", len(values), err)
+	}
+	if d := time.Since(start); d < 20*time.Millisecond {
+		t.Errorf("latency not injected: call took %v", d)
+	}
+}
@@ -0,0 +1,162 @@
+package 注册表类
+
+import (
+	"errors"
+)
+
+// I会话表项 是会话中一个表项的句柄, 提供与 Key结构 同名的常用方法, 并实现 I配置来源,
+// 方便把为 Key结构 编写的代码改为使用会话(例如 I覆盖会话 的合并视图)。
+// 句柄记录打开时的'访问权限', 与Windows一样, 权限不足的操作返回 ErrSessionAccessDenied:
+// 读取值和取信息需要QUERY_VALUE, 枚举子项需要ENUMERATE_SUB_KEYS, 写入和删除值需要SET_VALUE,
+// 创建子项需要CREATE_SUB_KEY。句柄只保存路径和权限, 不需要关闭。
+type I会话表项 struct {
+	会话 I注册表会话
+	路径 string
+	权限 uint32
+}
+
+// 会话降级权限 是打开会话表项时没有指定'访问权限'依次尝试的权限, 与 I默认降级权限 的默认值相同。
+var 会话降级权限 = []uint32{key全部权限, key写入权限, key读取权限}
+
+// 权限链 返回按顺序尝试的权限: 给出的'访问权限', 或者没有给出时的 会话降级权限。
+func 权限链(访问权限 []uint32) []uint32 {
+	if 权限 := 可选权限(访问权限); 权限 != 0 {
+		return []uint32{权限}
+	}
+	return 会话降级权限
+}
+
+func 新建会话表项(s I注册表会话, 路径 string, 权限 uint32) *I会话表项 {
+	return &I会话表项{会话: s, 路径: 路径, 权限: I映射通用权限(权限 &^ key视图标志)}
+}
+
+// I打开会话表项 以'访问权限'打开会话中路径指向的表项并返回句柄, 表项不存在时返回 ErrSessionNotExist。
+// 没有指定'访问权限'时与 I打开表项 一样依次尝试ALL_ACCESS、WRITE和READ, 只有拒绝访问会触发降级。
+func I打开会话表项(s I注册表会话, 路径 string, 访问权限 ...uint32) (*I会话表项, error) {
+	if s == nil {
+		return nil, errors.New("注册表类对象为nil")
+	}
+	路径 = 规范路径(路径)
+	var err error
+	for _, 权限 := range 权限链(访问权限) {
+		if err = s.I打开表项(路径, 权限); err == nil {
+			return 新建会话表项(s, 路径, 权限), nil
+		}
+		if !errors.Is(err, ErrSessionAccessDenied) {
+			break
+		}
+	}
+	return nil, err
+}
+
+// I取路径 返回表项相对会话根项的路径。
+func (k *I会话表项) I取路径() string {
+	return k.路径
+}
+
+// I取权限 返回打开句柄时获得的'访问权限', 通用权限已经映射为具体权限。
+func (k *I会话表项) I取权限() uint32 {
+	return k.权限
+}
+
+// 检查权限 在句柄没有'需要'的全部权限时返回 ErrSessionAccessDenied。
+func (k *I会话表项) 检查权限(需要 uint32) error {
+	if k.权限&需要 != 需要 {
+		return ErrSessionAccessDenied
+	}
+	return nil
+}
+
+// I打开子项 以'访问权限'打开路径相对k的子项并返回句柄, 参数的含义与 I打开会话表项 相同。
+func (k *I会话表项) I打开子项(路径 string, 访问权限 ...uint32) (*I会话表项, error) {
+	return I打开会话表项(k.会话, hive连接路径(k.路径, 规范路径(路径)), 访问权限...)
+}
+
+// I创建子项 创建或打开路径相对k的子项, 返回句柄和子项是否已存在。
+// k需要CREATE_SUB_KEY权限; '访问权限'的含义与 I打开会话表项 相同。
+func (k *I会话表项) I创建子项(路径 string, 访问权限 ...uint32) (*I会话表项, bool, error) {
+	if err := k.检查权限(key创建子项权限); err != nil {
+		return nil, false, err
+	}
+	路径 = hive连接路径(k.路径, 规范路径(路径))
+	var err error
+	for _, 权限 := range 权限链(访问权限) {
+		var 是否已存在 bool
+		if 是否已存在, err = k.会话.I创建表项(路径, 权限); err == nil {
+			return 新建会话表项(k.会话, 路径, 权限), 是否已存在, nil
+		}
+		if !errors.Is(err, ErrSessionAccessDenied) {
+			break
+		}
+	}
+	return nil, false, err
+}
+
+// I取所有子项名称 返回子项的名称。参数n的含义与 Key结构.I取所有子项名称 相同。
+func (k *I会话表项) I取所有子项名称(n int) ([]string, error) {
+	if err := k.检查权限(key枚举子项权限); err != nil {
+		return nil, err
+	}
+	名称, err := k.会话.I列出子项(k.路径)
+	if err != nil {
+		return nil, err
+	}
+	return 截取名称(名称, n), nil
+}
+
+// I取所有子项值 返回值的名称。参数的含义与 Key结构.I取所有子项值 相同。
+func (k *I会话表项) I取所有子项值(返回数量 int) ([]string, error) {
+	值, err := k.I列出原始值()
+	if err != nil {
+		return nil, err
+	}
+	名称 := make([]string, len(值))
+	for i, v := range 值 {
+		名称[i] = v.Name
+	}
+	return 截取名称(名称, 返回数量), nil
+}
+
+// I取信息 返回表项的统计信息。
+func (k *I会话表项) I取信息() (*I表项统计, error) {
+	if err := k.检查权限(key查询值权限); err != nil {
+		return nil, err
+	}
+	return k.会话.I取信息(k.路径)
+}
+
+// I删除值 删除名称为'名称'的值。
+func (k *I会话表项) I删除值(名称 string) error {
+	if err := k.检查权限(key设置值权限); err != nil {
+		return err
+	}
+	return k.会话.I删除值(k.路径, 名称)
+}
+
+// I读取原始值 实现 I配置来源, 值不存在时返回nil和nil。
+func (k *I会话表项) I读取原始值(名称 string) (*I离线值, error) {
+	if err := k.检查权限(key查询值权限); err != nil {
+		return nil, err
+	}
+	v, err := k.会话.I取值(k.路径, 名称)
+	if errors.Is(err, ErrSessionNotExist) {
+		return nil, nil
+	}
+	return v, err
+}
+
+// I列出原始值 实现 I配置来源。
+func (k *I会话表项) I列出原始值() ([]*I离线值, error) {
+	if err := k.检查权限(key查询值权限); err != nil {
+		return nil, err
+	}
+	return k.会话.I列出值(k.路径)
+}
+
+// I写入原始值 实现 I配置来源。
+func (k *I会话表项) I写入原始值(值 *I离线值) error {
+	if err := k.检查权限(key设置值权限); err != nil {
+		return err
+	}
+	return k.会话.I设置值(k.路径, 值)
+}
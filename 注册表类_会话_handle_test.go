@@ -0,0 +1,213 @@
+package 注册表类_test
+
+import (
+	"errors"
+	"io/fs"
+	"strings"
+	"testing"
+
+	注册表类 "e.coding.net/gogit/go/gosdk/core/win_registry_cn"
+)
+
+func TestOverlayHandleEnumeration(t *testing.T) {
+	o, err := 注册表类.I新建覆盖会话(testSession(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	k, err := 注册表类.I打开会话表项(o, `Software\Contoso`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	k.I写入原始值(sz("Added", "1"))
+	k.I删除值("Port")
+	o.I删除表项(`Software\Contoso\A`)
+	o.I创建表项(`Software\Contoso\D`)
+
+	if names, err := k.I取所有子项名称(-1); err != nil || strings.Join(names, ",") != "B,C,D" {
+		t.Errorf("subkeys: got %q, %v", names, err)
+	}
+	if names, err := k.I取所有子项名称(2); err != nil || len(names) != 2 {
+		t.Errorf("subkeys(2): got %q, %v", names, err)
+	}
+	if names, err := k.I取所有子项值(0); err != nil || strings.Join(names, ",") != "Theme,Added" {
+		t.Errorf("values: got %q, %v", names, err)
+	}
+	if v, err := k.I读取原始值("Port"); v != nil || err != nil {
+		t.Errorf("deleted value: got %v, %v", v, err)
+	}
+	if _, err := k.I打开子项(`A`); !errors.Is(err, 注册表类.ErrSessionNotExist) {
+		t.Errorf("deleted subkey: got %v", err)
+	}
+	d, err := k.I打开子项(`D`)
+	if err != nil || d.I取路径() != `Software\Contoso\D` {
+		t.Fatalf("created subkey: got %v, %v", d, err)
+	}
+
+	// 句柄可以作为分层配置的一层。
+	cfg := 注册表类.I新建分层配置(注册表类.I配置层{Name: "overlay", Source: k})
+	if theme, _, err := 注册表类.I取配置[string](cfg, "Theme"); err != nil || theme != "dark" {
+		t.Errorf("Theme from overlay layer: got %q, %v", theme, err)
+	}
+}
+
+func TestOverlayCommit(t *testing.T) {
+	base := testSession(t)
+	o, err := 注册表类.I新建覆盖会话(base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const p = `Software\Contoso`
+	o.I设置值(p, dword("Port", 9090))
+	o.I删除值(p, "Theme")
+	o.I删除表项(p + `\B`)
+	o.I删除表项(p + `\A`)
+	o.I创建表项(p + `\A\Sub`)
+	o.I设置值(p+`\A\Sub`, sz("x", "y"))
+
+	before, err := o.I差异()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 提交到中途失败的目标: 覆盖层保持不变, 差异只剩基础中还没有的变更, 重新提交完成它们。
+	flaky, err := 注册表类.I新建故障注入(base, 0,
+		注册表类.I故障规则{Op: 注册表类.I操作_设置值, Path: p + `\A\Sub`, Nth: 1, Err: 注册表类.ErrSessionSharingViolation})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := o.I提交(flaky); !errors.Is(err, 注册表类.ErrSessionSharingViolation) {
+		t.Fatalf("commit: got %v, want sharing violation", err)
+	}
+	if plan, _ := o.I差异(); len(plan) == 0 || len(plan) >= len(before) {
+		t.Errorf("changes after a failed commit: %v, before: %v", plan, before)
+	}
+	if err := o.I提交(); err != nil {
+		t.Fatal(err)
+	}
+	if plan, _ := o.I差异(); len(plan) != 0 {
+		t.Errorf("overlay not cleared after commit: %v", plan)
+	}
+
+	if port, _ := 注册表类.I会话取[uint32](base, p, "Port"); port != 9090 {
+		t.Errorf("base Port: got %d", port)
+	}
+	if _, err := base.I取值(p, "Theme"); !errors.Is(err, 注册表类.ErrSessionNotExist) {
+		t.Errorf("base Theme: got %v", err)
+	}
+	if names, _ := base.I列出子项(p); strings.Join(names, ",") != "C,A" {
+		t.Errorf("base subkeys: got %q", names)
+	}
+	if v, err := 注册表类.I会话取[string](base, p+`\A\Sub`, "x"); err != nil || v != "y" {
+		t.Errorf("base A\\Sub: got %q, %v", v, err)
+	}
+
+	o.I设置值(p, dword("Port", 1))
+	o.I丢弃()
+	if port, _ := 注册表类.I会话取[uint32](o, p, "Port"); port != 9090 {
+		t.Errorf("after discard: Port = %d", port)
+	}
+}
+
+// Access rights used by the session handle tests; the exported constants are Windows-only.
+const (
+	keyQueryValue   = 0x00001
+	keySetValue     = 0x00002
+	keyCreateSubKey = 0x00004
+	keyRead         = 0x20019
+	keyWrite        = 0x20006
+	keyAllAccess    = 0xf003f
+)
+
+func TestSessionHandleAccess(t *testing.T) {
+	s := testSession(t)
+	const p = `Software\Contoso`
+
+	r, err := 注册表类.I打开会话表项(s, p, keyRead)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.I读取原始值("Port"); err != nil {
+		t.Errorf("reading through a READ handle: %v", err)
+	}
+	if names, err := r.I取所有子项名称(-1); err != nil || len(names) != 3 {
+		t.Errorf("enumerating through a READ handle: %q, %v", names, err)
+	}
+	if err := r.I写入原始值(sz("Theme", "light")); !errors.Is(err, fs.ErrPermission) || !errors.Is(err, 注册表类.ErrSessionAccessDenied) {
+		t.Errorf("writing through a READ handle: got %v, want access denied", err)
+	}
+	if err := r.I删除值("Theme"); !errors.Is(err, 注册表类.ErrSessionAccessDenied) {
+		t.Errorf("deleting through a READ handle: got %v", err)
+	}
+	if _, _, err := r.I创建子项("New"); !errors.Is(err, 注册表类.ErrSessionAccessDenied) {
+		t.Errorf("creating through a READ handle: got %v", err)
+	}
+
+	q, err := 注册表类.I打开会话表项(s, p, keyQueryValue)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := q.I取所有子项名称(-1); !errors.Is(err, 注册表类.ErrSessionAccessDenied) {
+		t.Errorf("enumerating subkeys without ENUMERATE_SUB_KEYS: got %v", err)
+	}
+	if _, err := q.I取所有子项值(-1); err != nil {
+		t.Errorf("enumerating values with QUERY_VALUE: %v", err)
+	}
+
+	w, err := 注册表类.I打开会话表项(s, p, keySetValue|keyCreateSubKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.I读取原始值("Port"); !errors.Is(err, 注册表类.ErrSessionAccessDenied) {
+		t.Errorf("reading without QUERY_VALUE: got %v", err)
+	}
+	if err := w.I写入原始值(sz("Theme", "light")); err != nil {
+		t.Error(err)
+	}
+	sub, existed, err := w.I创建子项("New", keyQueryValue)
+	if err != nil || existed || sub.I取权限() != keyQueryValue {
+		t.Fatalf("create: got %v, %v, %v", sub, existed, err)
+	}
+	if err := sub.I写入原始值(sz("x", "y")); !errors.Is(err, 注册表类.ErrSessionAccessDenied) {
+		t.Errorf("writing through the new QUERY_VALUE handle: got %v", err)
+	}
+}
+
+func TestSessionHandleDACL(t *testing.T) {
+	sd, err := 注册表类.I解析SDDL("O:BAD:P(A;;KA;;;BA)(A;;KR;;;WD)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	contoso := &注册表类.I离线表项{Name: "Contoso", Values: []*注册表类.I离线值{sz("Theme", "dark")}}
+	if err := contoso.I设置安全描述符(sd); err != nil {
+		t.Fatal(err)
+	}
+	s, err := 注册表类.I新建离线会话(&注册表类.I离线表项{SubKeys: []*注册表类.I离线表项{contoso}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.I设置访问令牌(&注册表类.I访问令牌{User: "S-1-5-21-1-2-3-1001", Groups: []string{"WD"}})
+	if _, err := 注册表类.I打开会话表项(s, "Contoso", keyWrite); !errors.Is(err, 注册表类.ErrSessionAccessDenied) {
+		t.Errorf("opening for WRITE as a user: got %v", err)
+	}
+	h, err := 注册表类.I打开会话表项(s, "Contoso")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.I取权限() != keyRead {
+		t.Errorf("default open granted %#x, want READ after falling back", h.I取权限())
+	}
+	if err := h.I写入原始值(sz("Theme", "light")); !errors.Is(err, 注册表类.ErrSessionAccessDenied) {
+		t.Errorf("writing through the fallback handle: got %v", err)
+	}
+	if _, err := s.I创建表项(`Contoso\New`, keyRead); !errors.Is(err, 注册表类.ErrSessionAccessDenied) {
+		t.Errorf("creating a subkey without CREATE_SUB_KEY: got %v", err)
+	}
+
+	s.I设置访问令牌(&注册表类.I访问令牌{User: "S-1-5-21-1-2-3-500", Groups: []string{"BA", "WD"}})
+	if h, err := 注册表类.I打开会话表项(s, "Contoso"); err != nil || h.I取权限() != keyAllAccess {
+		t.Errorf("administrator open: got %v, %v", h, err)
+	}
+	if _, err := s.I创建表项(`Contoso\New`, keyRead); err != nil {
+		t.Errorf("administrator create: %v", err)
+	}
+}
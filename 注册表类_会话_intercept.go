@@ -0,0 +1,188 @@
+package 注册表类
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// I拦截器 在会话调用或 I启用拦截 的注册表对象上的调用前后执行, 用于日志、统计、审计、路径限制等横切的功能。
+// 调用中的Op、Path、Name和Value是调用的参数(设置值时Name是值的名称), 拦截器可以修改它们;
+// 调用 继续 执行链中后面的拦截器和内部会话, 结果写入调用的Result、Values、Names、
+// Existed和Stat, 拦截器可以在返回前修改。不调用 继续 而直接填写结果并返回则跳过内部会话。
+type I拦截器 interface {
+	I拦截(调用 *I调用记录, 继续 func() error) error
+}
+
+// I拦截函数 把普通函数转换为 I拦截器。
+type I拦截函数 func(调用 *I调用记录, 继续 func() error) error
+
+// I拦截 实现 I拦截器。
+func (f I拦截函数) I拦截(调用 *I调用记录, 继续 func() error) error {
+	return f(调用, 继续)
+}
+
+// 拦截链 把调用依次交给拦截器, 最后执行内部的操作。第一个拦截器在最外层。
+type 拦截链 []I拦截器
+
+func (c 拦截链) 执行(调用 *I调用记录, 内部 func() error) error {
+	var 执行 func(i int) error
+	执行 = func(i int) error {
+		if i == len(c) {
+			return 内部()
+		}
+		return c[i].I拦截(调用, func() error { return 执行(i + 1) })
+	}
+	return 执行(0)
+}
+
+// 检查拦截器 在有拦截器为nil时返回错误。
+func 检查拦截器(拦截器 []I拦截器) error {
+	for _, x := range 拦截器 {
+		if x == nil {
+			return errors.New("拦截器为nil")
+		}
+	}
+	return nil
+}
+
+// I拦截会话 把每次调用依次交给拦截器, 最后交给内部会话, 实现 I注册表会话。
+// 第一个拦截器在最外层。
+type I拦截会话 struct {
+	内部  I注册表会话
+	拦截器 拦截链
+}
+
+// I新建拦截会话 返回用拦截器包装内部会话的会话。
+func I新建拦截会话(内部 I注册表会话, 拦截器 ...I拦截器) (*I拦截会话, error) {
+	if 内部 == nil {
+		return nil, errors.New("注册表类对象为nil")
+	}
+	if err := 检查拦截器(拦截器); err != nil {
+		return nil, err
+	}
+	return &I拦截会话{内部: 内部, 拦截器: 拦截器}, nil
+}
+
+func (s *I拦截会话) 调用(调用 *I调用记录) error {
+	return s.拦截器.执行(调用, func() error { return s.执行内部(调用) })
+}
+
+func (s *I拦截会话) 执行内部(调用 *I调用记录) (err error) {
+	switch 调用.Op {
+	case I操作_打开表项:
+		err = s.内部.I打开表项(调用.Path, 调用.Access)
+	case I操作_取值:
+		if 调用.Result, err = s.内部.I取值(调用.Path, 调用.Name); 调用.Result != nil {
+			调用.Type = 调用.Result.Type
+		}
+	case I操作_列出值:
+		调用.Values, err = s.内部.I列出值(调用.Path)
+	case I操作_列出子项:
+		调用.Names, err = s.内部.I列出子项(调用.Path)
+	case I操作_设置值:
+		err = s.内部.I设置值(调用.Path, 调用.Value)
+	case I操作_删除值:
+		err = s.内部.I删除值(调用.Path, 调用.Name)
+	case I操作_创建表项:
+		调用.Existed, err = s.内部.I创建表项(调用.Path, 调用.Access)
+	case I操作_删除表项:
+		err = s.内部.I删除表项(调用.Path)
+	case I操作_取信息:
+		调用.Stat, err = s.内部.I取信息(调用.Path)
+	default:
+		err = fmt.Errorf("未知的会话操作 %q", 调用.Op)
+	}
+	return err
+}
+
+// I打开表项 实现 I注册表会话。
+func (s *I拦截会话) I打开表项(路径 string, 访问权限 uint32) error {
+	return s.调用(&I调用记录{Op: I操作_打开表项, Path: 路径, Access: 访问权限})
+}
+
+// I取值 实现 I注册表会话。
+func (s *I拦截会话) I取值(路径, 名称 string) (*I离线值, error) {
+	调用 := &I调用记录{Op: I操作_取值, Path: 路径, Name: 名称}
+	err := s.调用(调用)
+	return 调用.Result, err
+}
+
+// I列出值 实现 I注册表会话。
+func (s *I拦截会话) I列出值(路径 string) ([]*I离线值, error) {
+	调用 := &I调用记录{Op: I操作_列出值, Path: 路径}
+	err := s.调用(调用)
+	return 调用.Values, err
+}
+
+// I列出子项 实现 I注册表会话。
+func (s *I拦截会话) I列出子项(路径 string) ([]string, error) {
+	调用 := &I调用记录{Op: I操作_列出子项, Path: 路径}
+	err := s.调用(调用)
+	return 调用.Names, err
+}
+
+// I设置值 实现 I注册表会话。
+func (s *I拦截会话) I设置值(路径 string, 值 *I离线值) error {
+	if 值 == nil {
+		return errors.New("注册表类对象为nil")
+	}
+	return s.调用(&I调用记录{Op: I操作_设置值, Path: 路径, Name: 值.Name, Type: 值.Type, Value: 值})
+}
+
+// I删除值 实现 I注册表会话。
+func (s *I拦截会话) I删除值(路径, 名称 string) error {
+	return s.调用(&I调用记录{Op: I操作_删除值, Path: 路径, Name: 名称})
+}
+
+// I创建表项 实现 I注册表会话。
+func (s *I拦截会话) I创建表项(路径 string, 访问权限 ...uint32) (bool, error) {
+	调用 := &I调用记录{Op: I操作_创建表项, Path: 路径, Access: 可选权限(访问权限)}
+	err := s.调用(调用)
+	return 调用.Existed, err
+}
+
+// I删除表项 实现 I注册表会话。
+func (s *I拦截会话) I删除表项(路径 string) error {
+	return s.调用(&I调用记录{Op: I操作_删除表项, Path: 路径})
+}
+
+// I取信息 实现 I注册表会话。
+func (s *I拦截会话) I取信息(路径 string) (*I表项统计, error) {
+	调用 := &I调用记录{Op: I操作_取信息, Path: 路径}
+	err := s.调用(调用)
+	return 调用.Stat, err
+}
+
+// 截取名称 按 os.File.Readdirnames 的方式处理数量: 大于0时最多返回n个, 否则返回全部。
+func 截取名称(名称 []string, n int) []string {
+	if n > 0 && len(名称) > n {
+		return 名称[:n]
+	}
+	return 名称
+}
+
+// I路径白名单 返回只允许访问白名单中的表项及其子项的拦截器, 其他调用返回 ErrSessionAccessDenied。
+// 模式的语法与 I故障规则.Path 相同, 与路径本身或它的任何上级表项匹配即允许。
+func I路径白名单(模式 ...string) (I拦截器, error) {
+	规范 := make([]string, len(模式))
+	for i, m := range 模式 {
+		规范[i] = 规则模式(m)
+		if _, err := path.Match(规范[i], ""); err != nil {
+			return nil, fmt.Errorf("路径模式 %q: %w", m, err)
+		}
+	}
+	return I拦截函数(func(调用 *I调用记录, 继续 func() error) error {
+		段 := strings.Split(规则模式(调用.Path), "/")
+		for n := len(段); n > 0; n-- {
+			p := strings.Join(段[:n], "/")
+			for _, m := range 规范 {
+				if ok, _ := path.Match(m, p); ok {
+					return 继续()
+				}
+			}
+		}
+		return ErrSessionAccessDenied
+	}), nil
+}
@@ -0,0 +1,118 @@
+package 注册表类_test
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+	"testing"
+
+	注册表类 "e.coding.net/gogit/go/gosdk/core/win_registry_cn"
+)
+
+func TestInterceptorChain(t *testing.T) {
+	var log []string
+	logger := 注册表类.I拦截函数(func(c *注册表类.I调用记录, next func() error) error {
+		err := next()
+		line := fmt.Sprintf("%s %s %q", c.Op, c.Path, c.Name)
+		if c.Result != nil {
+			line += " " + 注册表类.I值类型名称(c.Result.Type)
+		}
+		if c.Value != nil {
+			line += " " + 注册表类.I值类型名称(c.Value.Type)
+		}
+		log = append(log, line)
+		return err
+	})
+	// 缓存在日志之后, 命中时直接返回, 不经过内部会话。
+	cache := map[string]*注册表类.I离线值{"THEME": sz("Theme", "cached")}
+	cached := 注册表类.I拦截函数(func(c *注册表类.I调用记录, next func() error) error {
+		if v, ok := cache[strings.ToUpper(c.Name)]; ok && c.Op == 注册表类.I操作_取值 {
+			c.Result = v
+			return nil
+		}
+		return next()
+	})
+	// 把值名称改写为大写, 并隐藏名称以Secret开头的值。
+	rewrite := 注册表类.I拦截函数(func(c *注册表类.I调用记录, next func() error) error {
+		if c.Op == 注册表类.I操作_设置值 {
+			v := *c.Value
+			v.Name = strings.ToUpper(v.Name)
+			c.Value = &v
+		}
+		err := next()
+		kept := c.Values[:0]
+		for _, v := range c.Values {
+			if !strings.HasPrefix(strings.ToUpper(v.Name), "SECRET") {
+				kept = append(kept, v)
+			}
+		}
+		c.Values = kept
+		return err
+	})
+
+	inner := testSession(t)
+	s, err := 注册表类.I新建拦截会话(inner, logger, cached, rewrite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, err := s.I取值(`Software\Contoso`, "Theme"); err != nil {
+		t.Fatal(err)
+	} else if got, _ := v.I取文本(); got != "cached" {
+		t.Errorf("Theme: got %q, want cached", got)
+	}
+	if err := s.I设置值(`Software\Contoso`, sz("SecretKey", "x")); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := inner.I取值(`Software\Contoso`, "SecretKey"); err != nil || v.Name != "SECRETKEY" {
+		t.Errorf("rewritten name: got %v, %v", v, err)
+	}
+	s.I设置值(`Software\Contoso`, sz("Secret2", "y"))
+	values, err := s.I列出值(`Software\Contoso`)
+	if err != nil || len(values) != 2 {
+		t.Errorf("values: got %d, %v", len(values), err)
+	}
+	if _, err := s.I取值(`Software\Contoso`, "Missing"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Missing: got %v", err)
+	}
+	if st, err := s.I取信息(`Software\Contoso`); err != nil || st.SubKeyCount != 3 || st.ValueCount != 4 {
+		t.Errorf("stat: got %+v, %v", st, err)
+	}
+
+	want := []string{
+		`GetValue Software\Contoso "Theme" REG_SZ`,
+		`SetValue Software\Contoso "SecretKey" REG_SZ`,
+		`SetValue Software\Contoso "Secret2" REG_SZ`,
+		`EnumValues Software\Contoso ""`,
+		`GetValue Software\Contoso "Missing"`,
+		`Stat Software\Contoso ""`,
+	}
+	if strings.Join(log, "\n") != strings.Join(want, "\n") {
+		t.Errorf("log:\n%s\nwant:\n%s", strings.Join(log, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestPathAllowList(t *testing.T) {
+	allow, err := 注册表类.I路径白名单(`software\contoso`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := 注册表类.I新建拦截会话(testSession(t), allow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.I列出子项(`Software\Contoso`); err != nil {
+		t.Error(err)
+	}
+	if _, err := s.I创建表项(`Software\Contoso\A\Deep`); err != nil {
+		t.Error(err)
+	}
+	for _, p := range []string{`Software`, `Software\Fabrikam`, `Software\ContosoX`} {
+		if _, err := s.I列出值(p); !errors.Is(err, 注册表类.ErrSessionAccessDenied) {
+			t.Errorf("%s: got %v, want access denied", p, err)
+		}
+	}
+	if _, err := 注册表类.I路径白名单(`[`); err == nil {
+		t.Error("bad pattern accepted")
+	}
+}
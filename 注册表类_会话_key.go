@@ -0,0 +1,167 @@
+//go:build windows
+// +build windows
+
+package 注册表类
+
+import (
+	"errors"
+)
+
+// 操作权限 是 I表项会话 执行各操作时打开表项所用的访问权限。删除表项由 I删除表项 函数自己打开, 不在其中。
+var 操作权限 = map[string]uint32{
+	I操作_取值:   QUERY_VALUE,
+	I操作_列出值:  QUERY_VALUE,
+	I操作_列出子项: ENUMERATE_SUB_KEYS,
+	I操作_设置值:  SET_VALUE,
+	I操作_删除值:  SET_VALUE,
+	I操作_创建表项: CREATE_SUB_KEY,
+	I操作_取信息:  QUERY_VALUE,
+}
+
+// I表项会话 在注册表对象的根项下执行调用, 实现 I注册表会话。每次调用以操作需要的最小权限
+// 打开路径指向的表项, 用完即关闭。返回的错误是包装了注册表原始错误的 *I录制的错误,
+// 所以 errors.Is(err, ErrNotExist) 和 errors.Is(err, ErrSessionNotExist) 都可以使用。
+type I表项会话 struct {
+	根 *Key结构
+}
+
+// I新建表项会话 返回在根项下执行调用的会话。会话不拥有根项, 不会关闭它。
+func I新建表项会话(根 *Key结构) (*I表项会话, error) {
+	if 根 == nil {
+		return nil, errors.New("注册表类对象为nil")
+	}
+	return &I表项会话{根: 根}, nil
+}
+
+// 打开 以操作对应的权限打开路径指向的表项并执行f。
+func (s *I表项会话) 打开(操作, 路径 string, f func(k *Key结构) error) error {
+	k, err := I打开表项(s.根, 路径, 操作权限[操作])
+	if err == nil {
+		err = f(k)
+		k.I关闭()
+	}
+	if err != nil {
+		return 录制错误(err)
+	}
+	return nil
+}
+
+// I打开表项 实现 I注册表会话, 以'访问权限'打开表项后立即关闭。'访问权限'为0时与 I打开表项 函数一样依次降级。
+func (s *I表项会话) I打开表项(路径 string, 访问权限 uint32) error {
+	k, err := I打开表项(s.根, 路径, 访问权限)
+	if err != nil {
+		return 录制错误(err)
+	}
+	k.I关闭()
+	return nil
+}
+
+// I取值 实现 I注册表会话。
+func (s *I表项会话) I取值(路径, 名称 string) (结果 *I离线值, err error) {
+	err = s.打开(I操作_取值, 路径, func(k *Key结构) error {
+		结果, err = k.取原始值(名称)
+		return err
+	})
+	return 结果, err
+}
+
+// I列出值 实现 I注册表会话。
+func (s *I表项会话) I列出值(路径 string) (结果 []*I离线值, err error) {
+	err = s.打开(I操作_列出值, 路径, func(k *Key结构) error {
+		结果, err = k.I列出原始值()
+		return err
+	})
+	return 结果, err
+}
+
+// I列出子项 实现 I注册表会话。
+func (s *I表项会话) I列出子项(路径 string) (结果 []string, err error) {
+	err = s.打开(I操作_列出子项, 路径, func(k *Key结构) error {
+		结果, err = k.I取所有子项名称(-1)
+		return err
+	})
+	return 结果, err
+}
+
+// I设置值 实现 I注册表会话。
+func (s *I表项会话) I设置值(路径 string, 值 *I离线值) error {
+	if 值 == nil {
+		return errors.New("注册表类对象为nil")
+	}
+	return s.打开(I操作_设置值, 路径, func(k *Key结构) error {
+		return k.I写入原始值(值)
+	})
+}
+
+// I删除值 实现 I注册表会话。
+func (s *I表项会话) I删除值(路径, 名称 string) error {
+	return s.打开(I操作_删除值, 路径, func(k *Key结构) error {
+		return k.I删除值(名称)
+	})
+}
+
+// I创建表项 实现 I注册表会话。没有给出'访问权限'时以CREATE_SUB_KEY权限创建。
+func (s *I表项会话) I创建表项(路径 string, 访问权限 ...uint32) (bool, error) {
+	权限 := 可选权限(访问权限)
+	if 权限 == 0 {
+		权限 = 操作权限[I操作_创建表项]
+	}
+	k, 是否已存在, err := I创建表项(s.根, 路径, 权限)
+	if err != nil {
+		return false, 录制错误(err)
+	}
+	k.I关闭()
+	return 是否已存在, nil
+}
+
+// I删除表项 实现 I注册表会话。路径指向符号链接表项时只删除链接本身。
+func (s *I表项会话) I删除表项(路径 string) error {
+	删除 := I删除表项
+	if 链接, err := s.打开链接(路径); err == nil {
+		链接.I关闭()
+		删除 = I删除链接表项
+	}
+	if err := 删除(s.根, 路径); err != nil {
+		return 录制错误(err)
+	}
+	return nil
+}
+
+// 打开链接 以QUERY_VALUE权限打开路径指向的符号链接表项本身, 路径不是符号链接时返回错误。
+func (s *I表项会话) 打开链接(路径 string) (*Key结构, error) {
+	if len(拆分路径(路径)) == 0 {
+		return nil, ErrNotExist
+	}
+	k, err := I打开链接表项(s.根, 路径, QUERY_VALUE)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := k.I取链接目标(); err != nil {
+		k.I关闭()
+		return nil, err
+	}
+	return k, nil
+}
+
+// I取信息 实现 I注册表会话。路径指向符号链接表项时返回链接本身的信息。
+func (s *I表项会话) I取信息(路径 string) (结果 *I表项统计, err error) {
+	if 链接, err := s.打开链接(路径); err == nil {
+		defer 链接.I关闭()
+		信息, err := 链接.I取对象信息()
+		if err != nil {
+			return nil, 录制错误(err)
+		}
+		结果 = 对象信息转统计(信息)
+		结果.Link = true
+		return 结果, nil
+	}
+	err = s.打开(I操作_取信息, 路径, func(k *Key结构) error {
+		信息, err := k.I取对象信息()
+		if err != nil {
+			return err
+		}
+		结果 = 对象信息转统计(信息)
+		return nil
+	})
+	return 结果, err
+}
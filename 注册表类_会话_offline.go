@@ -0,0 +1,166 @@
+package 注册表类
+
+import (
+	"errors"
+	"strings"
+	"sync"
+)
+
+// I离线会话 在离线表项树上实现 I注册表会话, 路径相对于树根。
+// 每次调用像Windows上一样以操作需要的最小权限打开一个 I离线句柄, 所以错误与Windows上相同的调用一致:
+// 表项或值不存在时返回 ErrSessionNotExist, 权限不足或删除还有子项的表项时返回 ErrSessionAccessDenied。
+// 用 I设置访问令牌 设置模拟的调用者后, 每次打开都按表项的DACL检查, 读写值也不例外。读取返回的值是副本。
+type I离线会话 struct {
+	根  *I离线表项
+	令牌 *I访问令牌
+	锁  sync.Mutex
+}
+
+// I新建离线会话 返回在树根'根'上执行调用的会话。
+func I新建离线会话(根 *I离线表项) (*I离线会话, error) {
+	if 根 == nil {
+		return nil, errors.New("注册表类对象为nil")
+	}
+	return &I离线会话{根: 根}, nil
+}
+
+// I设置访问令牌 设置打开表项时检查DACL所用的令牌, 为nil时不检查。
+func (s *I离线会话) I设置访问令牌(令牌 *I访问令牌) {
+	s.锁.Lock()
+	defer s.锁.Unlock()
+	s.令牌 = 令牌
+}
+
+// 会话错误 把离线句柄返回的错误转换为会话的错误。
+func 会话错误(err error) error {
+	switch {
+	case errors.Is(err, ErrOfflineNotExist):
+		return ErrSessionNotExist
+	case errors.Is(err, ErrOfflineAccessDenied):
+		return ErrSessionAccessDenied
+	}
+	return err
+}
+
+// 打开 以'访问权限'打开路径指向的表项。
+func (s *I离线会话) 打开(路径 string, 访问权限 uint32) (*I离线句柄, error) {
+	h, err := I打开离线句柄(s.根, 路径, 访问权限, s.令牌)
+	return h, 会话错误(err)
+}
+
+// I打开表项 实现 I注册表会话。
+func (s *I离线会话) I打开表项(路径 string, 访问权限 uint32) error {
+	s.锁.Lock()
+	defer s.锁.Unlock()
+	_, err := s.打开(路径, 访问权限)
+	return err
+}
+
+// I取值 实现 I注册表会话。
+func (s *I离线会话) I取值(路径, 名称 string) (*I离线值, error) {
+	s.锁.Lock()
+	defer s.锁.Unlock()
+	h, err := s.打开(路径, key查询值权限)
+	if err != nil {
+		return nil, err
+	}
+	v, err := h.I取值(名称)
+	return v, 会话错误(err)
+}
+
+// I列出值 实现 I注册表会话。
+func (s *I离线会话) I列出值(路径 string) ([]*I离线值, error) {
+	s.锁.Lock()
+	defer s.锁.Unlock()
+	h, err := s.打开(路径, key查询值权限)
+	if err != nil {
+		return nil, err
+	}
+	值, err := h.I列出值()
+	return 值, 会话错误(err)
+}
+
+// I列出子项 实现 I注册表会话。
+func (s *I离线会话) I列出子项(路径 string) ([]string, error) {
+	s.锁.Lock()
+	defer s.锁.Unlock()
+	h, err := s.打开(路径, key枚举子项权限)
+	if err != nil {
+		return nil, err
+	}
+	名称, err := h.I取子项名称()
+	return 名称, 会话错误(err)
+}
+
+// I设置值 实现 I注册表会话。
+func (s *I离线会话) I设置值(路径 string, 值 *I离线值) error {
+	if 值 == nil {
+		return errors.New("注册表类对象为nil")
+	}
+	s.锁.Lock()
+	defer s.锁.Unlock()
+	h, err := s.打开(路径, key设置值权限)
+	if err != nil {
+		return err
+	}
+	return 会话错误(h.I设置值(值))
+}
+
+// I删除值 实现 I注册表会话。
+func (s *I离线会话) I删除值(路径, 名称 string) error {
+	s.锁.Lock()
+	defer s.锁.Unlock()
+	h, err := s.打开(路径, key设置值权限)
+	if err != nil {
+		return err
+	}
+	return 会话错误(h.I删除值(名称))
+}
+
+// I创建表项 实现 I注册表会话。已存在的表项以'访问权限'打开; 新建表项时
+// 以CREATE_SUB_KEY打开最近的已存在上级表项, 再在它下面创建。
+func (s *I离线会话) I创建表项(路径 string, 访问权限 ...uint32) (bool, error) {
+	s.锁.Lock()
+	defer s.锁.Unlock()
+	段 := 拆分路径(路径)
+	i := len(段)
+	for i > 0 && s.根.I查找(strings.Join(段[:i], `\`)) == nil {
+		i--
+	}
+	if i == len(段) {
+		_, err := s.打开(路径, 可选权限(访问权限))
+		return true, err
+	}
+	上级, err := s.打开(strings.Join(段[:i], `\`), key创建子项权限)
+	if err != nil {
+		return false, err
+	}
+	_, _, err = 上级.I创建子项(strings.Join(段[i:], `\`), 可选权限(访问权限))
+	return false, 会话错误(err)
+}
+
+// I删除表项 实现 I注册表会话。
+func (s *I离线会话) I删除表项(路径 string) error {
+	s.锁.Lock()
+	defer s.锁.Unlock()
+	if len(拆分路径(路径)) == 0 {
+		return errors.New("不能删除会话的根项")
+	}
+	根, err := s.打开("", 0)
+	if err != nil {
+		return err
+	}
+	return 会话错误(根.I删除子项(路径))
+}
+
+// I取信息 实现 I注册表会话。
+func (s *I离线会话) I取信息(路径 string) (*I表项统计, error) {
+	s.锁.Lock()
+	defer s.锁.Unlock()
+	h, err := s.打开(路径, key查询值权限)
+	if err != nil {
+		return nil, err
+	}
+	k := h.表项
+	return &I表项统计{Class: k.Class, SubKeyCount: len(k.SubKeys), ValueCount: len(k.Values), ModTime: k.ModTime, Link: k.I是链接()}, nil
+}
@@ -0,0 +1,506 @@
+package 注册表类
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// I覆盖会话 在只读的基础会话上叠加一个内存中的可写覆盖层, 实现 I注册表会话。
+// 写入和删除只修改覆盖层(删除用删除标记记录), 基础会话不会被写入;
+// 读取和枚举返回基础与覆盖层合并后的结果, 所以之后的读取能看到之前的写入。
+// I差异 返回覆盖层相对基础的全部变更, I提交 把它们写入基础, I丢弃 清空覆盖层。
+// 基础可以是离线配置单元上的 I离线会话, 也可以是 LOCAL_MACHINE 等真实表项上的 I表项会话。
+type I覆盖会话 struct {
+	基础 I注册表会话
+	表项 map[string]*覆盖表项 // 键为大写的规范路径
+	锁  sync.Mutex
+}
+
+// 覆盖表项 是覆盖层中被修改过的一个表项。
+type 覆盖表项 struct {
+	路径   string
+	已删除  bool
+	已创建  bool             // 在覆盖层中创建, 基础中的内容不可见
+	替换   bool             // 曾经被删除, 基础中的值和子项不再可见
+	值    map[string]*I离线值 // 键为大写名称, nil是删除标记
+	值顺序  []string
+	修改时间 time.Time
+}
+
+// 基础可见 报告基础会话中该表项的内容是否可见。
+func (e *覆盖表项) 基础可见() bool {
+	return e == nil || !e.替换 && !e.已创建
+}
+
+// I新建覆盖会话 返回叠加在基础会话上的空覆盖层。
+func I新建覆盖会话(基础 I注册表会话) (*I覆盖会话, error) {
+	if 基础 == nil {
+		return nil, errors.New("注册表类对象为nil")
+	}
+	return &I覆盖会话{基础: 基础, 表项: map[string]*覆盖表项{}}, nil
+}
+
+func 路径键(路径 string) string {
+	return strings.ToUpper(规范路径(路径))
+}
+
+func 父路径(路径 string) string {
+	段 := 拆分路径(路径)
+	if len(段) == 0 {
+		return ""
+	}
+	return strings.Join(段[:len(段)-1], `\`)
+}
+
+// 取项 返回覆盖层中的表项, 没有时返回nil。
+func (s *I覆盖会话) 取项(路径 string) *覆盖表项 {
+	return s.表项[路径键(路径)]
+}
+
+// 项 返回覆盖层中的表项, 没有时创建一个。
+func (s *I覆盖会话) 项(路径 string) *覆盖表项 {
+	键 := 路径键(路径)
+	e := s.表项[键]
+	if e == nil {
+		e = &覆盖表项{路径: 规范路径(路径), 值: map[string]*I离线值{}}
+		s.表项[键] = e
+	}
+	return e
+}
+
+// 基础存在 报告表项是否存在于基础会话中。
+func (s *I覆盖会话) 基础存在(路径 string) (bool, error) {
+	_, err := s.基础.I取信息(路径)
+	if errors.Is(err, ErrSessionNotExist) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// 存在 报告表项在合并后的视图中是否存在。上级表项被删除, 或者在覆盖层中新建或重新创建后,
+// 基础会话中它下面的表项不再可见, 只有在覆盖层中创建的表项存在。
+func (s *I覆盖会话) 存在(路径 string) error {
+	段 := 拆分路径(路径)
+	已创建 := false
+	for i := len(段); i > 0; i-- {
+		e := s.取项(strings.Join(段[:i], `\`))
+		switch {
+		case e == nil:
+		case e.已删除:
+			return ErrSessionNotExist
+		case !已创建 && !e.基础可见():
+			if i < len(段) {
+				return ErrSessionNotExist
+			}
+			已创建 = true
+		}
+	}
+	if 已创建 || len(段) == 0 {
+		return nil
+	}
+	有, err := s.基础存在(路径)
+	if err == nil && !有 {
+		err = ErrSessionNotExist
+	}
+	return err
+}
+
+func (s *I覆盖会话) 取值(路径, 名称 string) (*I离线值, error) {
+	if err := s.存在(路径); err != nil {
+		return nil, err
+	}
+	e := s.取项(路径)
+	if e != nil {
+		if v, 有 := e.值[strings.ToUpper(名称)]; 有 {
+			if v == nil {
+				return nil, ErrSessionNotExist
+			}
+			return 复制离线值(v), nil
+		}
+	}
+	if !e.基础可见() {
+		return nil, ErrSessionNotExist
+	}
+	return s.基础.I取值(路径, 名称)
+}
+
+func (s *I覆盖会话) 列出值(路径 string) ([]*I离线值, error) {
+	if err := s.存在(路径); err != nil {
+		return nil, err
+	}
+	e := s.取项(路径)
+	var 结果 []*I离线值
+	已列出 := map[string]bool{}
+	if e.基础可见() {
+		基础值, err := s.基础.I列出值(路径)
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range 基础值 {
+			键 := strings.ToUpper(v.Name)
+			已列出[键] = true
+			if e != nil {
+				if 新, 有 := e.值[键]; 有 {
+					if 新 != nil {
+						结果 = append(结果, 复制离线值(新))
+					}
+					continue
+				}
+			}
+			结果 = append(结果, v)
+		}
+	}
+	if e != nil {
+		for _, 键 := range e.值顺序 {
+			if v := e.值[键]; v != nil && !已列出[键] {
+				结果 = append(结果, 复制离线值(v))
+			}
+		}
+	}
+	return 结果, nil
+}
+
+func (s *I覆盖会话) 列出子项(路径 string) ([]string, error) {
+	if err := s.存在(路径); err != nil {
+		return nil, err
+	}
+	var 结果 []string
+	已列出 := map[string]bool{}
+	if s.取项(路径).基础可见() {
+		基础名称, err := s.基础.I列出子项(路径)
+		if err != nil {
+			return nil, err
+		}
+		for _, n := range 基础名称 {
+			if 子 := s.取项(hive连接路径(规范路径(路径), n)); 子 != nil && 子.已删除 {
+				continue
+			}
+			已列出[strings.ToUpper(n)] = true
+			结果 = append(结果, n)
+		}
+	}
+	父键 := 路径键(路径)
+	var 新增 []string
+	for _, e := range s.表项 {
+		段 := 拆分路径(e.路径)
+		if len(段) == 0 || !e.已创建 || e.已删除 || 路径键(父路径(e.路径)) != 父键 {
+			continue
+		}
+		if n := 段[len(段)-1]; !已列出[strings.ToUpper(n)] {
+			新增 = append(新增, n)
+		}
+	}
+	sort.Strings(新增)
+	return append(结果, 新增...), nil
+}
+
+// I打开表项 实现 I注册表会话, 只检查表项在合并后的视图中是否存在。
+// 写入不会到达基础会话, 所以不以'访问权限'打开基础中的表项。
+func (s *I覆盖会话) I打开表项(路径 string, 访问权限 uint32) error {
+	s.锁.Lock()
+	defer s.锁.Unlock()
+	return s.存在(路径)
+}
+
+// I取值 实现 I注册表会话。
+func (s *I覆盖会话) I取值(路径, 名称 string) (*I离线值, error) {
+	s.锁.Lock()
+	defer s.锁.Unlock()
+	return s.取值(路径, 名称)
+}
+
+// I列出值 实现 I注册表会话。
+func (s *I覆盖会话) I列出值(路径 string) ([]*I离线值, error) {
+	s.锁.Lock()
+	defer s.锁.Unlock()
+	return s.列出值(路径)
+}
+
+// I列出子项 实现 I注册表会话。
+func (s *I覆盖会话) I列出子项(路径 string) ([]string, error) {
+	s.锁.Lock()
+	defer s.锁.Unlock()
+	return s.列出子项(路径)
+}
+
+// I设置值 实现 I注册表会话, 只写入覆盖层。
+func (s *I覆盖会话) I设置值(路径 string, 值 *I离线值) error {
+	if 值 == nil {
+		return errors.New("注册表类对象为nil")
+	}
+	if err := hive检查名称(值.Name, true); err != nil {
+		return err
+	}
+	s.锁.Lock()
+	defer s.锁.Unlock()
+	if err := s.存在(路径); err != nil {
+		return err
+	}
+	s.项(路径).设置(strings.ToUpper(值.Name), 复制离线值(值))
+	return nil
+}
+
+func (e *覆盖表项) 设置(键 string, v *I离线值) {
+	if _, 有 := e.值[键]; !有 {
+		e.值顺序 = append(e.值顺序, 键)
+	}
+	e.值[键] = v
+	e.修改时间 = time.Now().UTC()
+}
+
+// I删除值 实现 I注册表会话, 在覆盖层中记录删除标记。
+func (s *I覆盖会话) I删除值(路径, 名称 string) error {
+	s.锁.Lock()
+	defer s.锁.Unlock()
+	if _, err := s.取值(路径, 名称); err != nil {
+		return err
+	}
+	s.项(路径).设置(strings.ToUpper(名称), nil)
+	return nil
+}
+
+// I创建表项 实现 I注册表会话, 缺少的上级表项也在覆盖层中创建。'访问权限'被忽略。
+func (s *I覆盖会话) I创建表项(路径 string, 访问权限 ...uint32) (bool, error) {
+	s.锁.Lock()
+	defer s.锁.Unlock()
+	err := s.存在(路径)
+	if err == nil {
+		return true, nil
+	}
+	if !errors.Is(err, ErrSessionNotExist) {
+		return false, err
+	}
+	段 := 拆分路径(路径)
+	for i := range 段 {
+		当前 := strings.Join(段[:i+1], `\`)
+		if err := hive检查名称(段[i], false); err != nil {
+			return false, err
+		}
+		err := s.存在(当前)
+		if err == nil {
+			continue
+		}
+		if !errors.Is(err, ErrSessionNotExist) {
+			return false, err
+		}
+		e := s.项(当前)
+		e.已删除, e.已创建 = false, true
+		e.值, e.值顺序, e.修改时间 = map[string]*I离线值{}, nil, time.Now().UTC()
+	}
+	return false, nil
+}
+
+// I删除表项 实现 I注册表会话, 在覆盖层中记录删除标记。与RegDeleteKey一样,
+// 表项还有子项时返回 ErrSessionAccessDenied; 基础会话中的符号链接表项不检查目标的子项。
+func (s *I覆盖会话) I删除表项(路径 string) error {
+	s.锁.Lock()
+	defer s.锁.Unlock()
+	if len(拆分路径(路径)) == 0 {
+		return errors.New("不能删除会话的根项")
+	}
+	链接, err := s.基础链接(路径)
+	if err != nil {
+		return err
+	}
+	if 链接 == nil {
+		子项, err := s.列出子项(路径)
+		if err != nil {
+			return err
+		}
+		if len(子项) > 0 {
+			return ErrSessionAccessDenied
+		}
+	}
+	e := s.项(路径)
+	e.已删除, e.已创建, e.替换 = true, false, true
+	e.值, e.值顺序, e.修改时间 = map[string]*I离线值{}, nil, time.Now().UTC()
+	return nil
+}
+
+// 基础链接 在覆盖层没有修改路径指向的表项并且它在基础会话中是符号链接表项时返回它的信息,
+// 否则返回nil。
+func (s *I覆盖会话) 基础链接(路径 string) (*I表项统计, error) {
+	if s.取项(路径) != nil || len(拆分路径(路径)) == 0 {
+		return nil, nil
+	}
+	if err := s.存在(路径); err != nil {
+		return nil, err
+	}
+	基础, err := s.基础.I取信息(路径)
+	if err != nil || !基础.Link {
+		return nil, err
+	}
+	return 基础, nil
+}
+
+// I取信息 实现 I注册表会话。
+func (s *I覆盖会话) I取信息(路径 string) (*I表项统计, error) {
+	s.锁.Lock()
+	defer s.锁.Unlock()
+	if 链接, err := s.基础链接(路径); err != nil || 链接 != nil {
+		return 链接, err
+	}
+	值, err := s.列出值(路径)
+	if err != nil {
+		return nil, err
+	}
+	子项, err := s.列出子项(路径)
+	if err != nil {
+		return nil, err
+	}
+	结果 := &I表项统计{SubKeyCount: len(子项), ValueCount: len(值)}
+	e := s.取项(路径)
+	if e.基础可见() {
+		基础, err := s.基础.I取信息(路径)
+		if err != nil {
+			return nil, err
+		}
+		结果.Class, 结果.ModTime = 基础.Class, 基础.ModTime
+	}
+	if e != nil && e.修改时间.After(结果.ModTime) {
+		结果.ModTime = e.修改时间
+	}
+	return 结果, nil
+}
+
+// I差异 返回覆盖层相对基础会话的全部变更, 按路径排序, 上级表项在前。
+// 结果的格式与 I计划期望状态 相同, 可以用 I应用计划 或 I应用到离线表项 应用到基础上:
+// 删除后又重新创建的表项表示为删除整个表项再新增。
+func (s *I覆盖会话) I差异() ([]I离线差异, error) {
+	s.锁.Lock()
+	defer s.锁.Unlock()
+	键 := make([]string, 0, len(s.表项))
+	for k := range s.表项 {
+		键 = append(键, k)
+	}
+	sort.Strings(键)
+	var 结果 []I离线差异
+	for _, k := range 键 {
+		e := s.表项[k]
+		上级已替换 := false
+		for p := 父路径(e.路径); p != ""; p = 父路径(p) {
+			if 上级 := s.取项(p); 上级 != nil && 上级.替换 {
+				上级已替换 = true
+				break
+			}
+		}
+		if e.替换 && !上级已替换 {
+			有, err := s.基础存在(e.路径)
+			if err != nil {
+				return nil, err
+			}
+			if 有 {
+				结果 = append(结果, I离线差异{Kind: I差异_删除表项, Path: e.路径})
+			}
+		}
+		if e.已删除 {
+			continue
+		}
+		if e.已创建 {
+			结果 = append(结果, I离线差异{Kind: I差异_新增表项, Path: e.路径})
+		}
+		for _, n := range e.值顺序 {
+			新 := e.值[n]
+			var 旧 *I离线值
+			if e.基础可见() {
+				var err error
+				if 旧, err = s.基础.I取值(e.路径, n); err != nil && !errors.Is(err, ErrSessionNotExist) {
+					return nil, err
+				}
+			}
+			switch {
+			case 新 == nil && 旧 != nil:
+				结果 = append(结果, I离线差异{Kind: I差异_删除值, Path: e.路径, Value: 旧.Name, Old: 旧})
+			case 新 != nil && 旧 == nil:
+				结果 = append(结果, I离线差异{Kind: I差异_新增值, Path: e.路径, Value: 新.Name, New: 复制离线值(新)})
+			case 新 != nil && !(旧.Type == 新.Type && slices.Equal(旧.Data, 新.Data)):
+				结果 = append(结果, I离线差异{Kind: I差异_修改值, Path: e.路径, Value: 新.Name, Old: 旧, New: 复制离线值(新)})
+			}
+		}
+	}
+	return 结果, nil
+}
+
+// I丢弃 清空覆盖层, 之后的读取只反映基础会话。
+func (s *I覆盖会话) I丢弃() {
+	s.锁.Lock()
+	defer s.锁.Unlock()
+	s.表项 = map[string]*覆盖表项{}
+}
+
+// I提交 把 I差异 返回的变更通过 I应用到会话 写入目标会话, 没有给出目标时写入基础会话。
+// 成功后清空覆盖层。失败时覆盖层保持不变, 已经写入的变更不会回滚; 修正原因后再次提交即可,
+// 已经生效的部分不会再产生变更。提交期间不应再修改覆盖层。
+func (s *I覆盖会话) I提交(目标 ...I注册表会话) error {
+	写入 := s.基础
+	if len(目标) > 0 {
+		写入 = 目标[0]
+	}
+	计划, err := s.I差异()
+	if err != nil {
+		return err
+	}
+	if err := I应用到会话(写入, 计划); err != nil {
+		return err
+	}
+	s.I丢弃()
+	return nil
+}
+
+// I应用到会话 通过会话执行计划中的变更, 规则与 I应用计划 相同: 缺少的上级表项会被创建,
+// 已经处于期望状态的部分被跳过, 出错时停止且不回滚。
+func I应用到会话(会话 I注册表会话, 计划 []I离线差异) error {
+	if 会话 == nil {
+		return errors.New("注册表类对象为nil")
+	}
+	for _, d := range 计划 {
+		var err error
+		switch d.Kind {
+		case I差异_新增表项:
+			_, err = 会话.I创建表项(d.Path)
+		case I差异_删除表项:
+			err = 删除会话表项树(会话, d.Path)
+		case I差异_新增值, I差异_修改值:
+			if _, err = 会话.I创建表项(d.Path); err == nil {
+				err = 会话.I设置值(d.Path, d.New)
+			}
+		case I差异_删除值:
+			err = 会话.I删除值(d.Path, d.Value)
+		default:
+			err = errors.New("不能应用的变更")
+		}
+		if (d.Kind == I差异_删除表项 || d.Kind == I差异_删除值) && errors.Is(err, ErrSessionNotExist) {
+			err = nil // 已经被删除
+		}
+		if err != nil {
+			return fmt.Errorf("%v: %w", d, err)
+		}
+	}
+	return nil
+}
+
+// 删除会话表项树 删除表项及其所有子项。符号链接表项只删除链接本身, 不删除目标中的子项。
+func 删除会话表项树(会话 I注册表会话, 路径 string) error {
+	统计, err := 会话.I取信息(路径)
+	if err != nil {
+		return err
+	}
+	if 统计.Link {
+		return 会话.I删除表项(路径)
+	}
+	子项, err := 会话.I列出子项(路径)
+	if err != nil {
+		return err
+	}
+	for _, n := range 子项 {
+		if err := 删除会话表项树(会话, hive连接路径(路径, n)); err != nil && !errors.Is(err, ErrSessionNotExist) {
+			return err
+		}
+	}
+	return 会话.I删除表项(路径)
+}
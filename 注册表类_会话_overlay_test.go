@@ -0,0 +1,162 @@
+package 注册表类_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	注册表类 "e.coding.net/gogit/go/gosdk/core/win_registry_cn"
+)
+
+func TestOverlayMergedView(t *testing.T) {
+	base := testSession(t)
+	s, err := 注册表类.I新建覆盖会话(base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const p = `Software\Contoso`
+	if err := s.I设置值(p, dword("Port", 9090)); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.I设置值(p, sz("New", "x")); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.I删除值(p, "theme"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.I删除值(p, "Theme"); !errors.Is(err, 注册表类.ErrSessionNotExist) {
+		t.Errorf("deleting a deleted value: got %v", err)
+	}
+	if err := s.I删除表项(p + `\B`); err != nil {
+		t.Fatal(err)
+	}
+	if existed, err := s.I创建表项(p + `\Z\Deep`); err != nil || existed {
+		t.Fatalf("create: got %v, %v", existed, err)
+	}
+	if err := s.I删除表项(p + `\Z`); !errors.Is(err, 注册表类.ErrSessionAccessDenied) {
+		t.Errorf("deleting a key with subkeys: got %v", err)
+	}
+	if err := s.I设置值(p+`\Missing`, sz("a", "b")); !errors.Is(err, 注册表类.ErrSessionNotExist) {
+		t.Errorf("setting a value under a missing key: got %v", err)
+	}
+
+	if port, _ := 注册表类.I会话取[uint32](s, p, "Port"); port != 9090 {
+		t.Errorf("overlay Port: got %d", port)
+	}
+	if port, _ := 注册表类.I会话取[uint32](base, p, "Port"); port != 8080 {
+		t.Errorf("base was written: Port = %d", port)
+	}
+	var names []string
+	values, _ := s.I列出值(p)
+	for _, v := range values {
+		names = append(names, v.Name)
+	}
+	if got := strings.Join(names, ","); got != "Port,New" {
+		t.Errorf("values: got %s", got)
+	}
+	if keys, _ := s.I列出子项(p); strings.Join(keys, ",") != "A,C,Z" {
+		t.Errorf("subkeys: got %q", keys)
+	}
+	if keys, _ := base.I列出子项(p); len(keys) != 3 {
+		t.Errorf("base subkeys changed: %q", keys)
+	}
+	if st, err := s.I取信息(p); err != nil || st.ValueCount != 2 || st.SubKeyCount != 3 {
+		t.Errorf("stat: got %+v, %v", st, err)
+	}
+	if _, err := s.I取信息(p + `\B`); !errors.Is(err, 注册表类.ErrSessionNotExist) {
+		t.Errorf("deleted key: got %v", err)
+	}
+
+	// 删除后重新创建的表项不再显示基础中的内容。
+	if err := s.I删除表项(p + `\A`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.I创建表项(p + `\A`); err != nil {
+		t.Fatal(err)
+	}
+	if st, _ := s.I取信息(p + `\A`); st.ValueCount != 0 {
+		t.Errorf("recreated key shows base values: %+v", st)
+	}
+}
+
+func TestOverlayLinkDelete(t *testing.T) {
+	root := &注册表类.I离线表项{SubKeys: []*注册表类.I离线表项{
+		{Name: "Target", SubKeys: []*注册表类.I离线表项{{Name: "Child"}}},
+		{Name: "Link"},
+	}}
+	if err := root.I取子项("Link").I设置链接目标(`HKLM\SOFTWARE\Target`); err != nil {
+		t.Fatal(err)
+	}
+	base, err := 注册表类.I新建离线会话(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	o, err := 注册表类.I新建覆盖会话(base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st, err := o.I取信息(`Link`); err != nil || !st.Link {
+		t.Fatalf("stat of the link: got %+v, %v", st, err)
+	}
+	if st, err := o.I取信息(`Target`); err != nil || st.Link || st.SubKeyCount != 1 {
+		t.Errorf("stat of the target: got %+v, %v", st, err)
+	}
+	plan := []注册表类.I离线差异{{Kind: 注册表类.I差异_删除表项, Path: `Link`}}
+	if err := 注册表类.I应用到会话(o, plan); err != nil {
+		t.Fatal(err)
+	}
+	if diff, err := o.I差异(); err != nil || len(diff) != 1 || diff[0].Kind != 注册表类.I差异_删除表项 || diff[0].Path != `Link` {
+		t.Errorf("diff: got %v, %v", diff, err)
+	}
+	if err := 注册表类.I应用到会话(base, plan); err != nil {
+		t.Fatal(err)
+	}
+	if keys, _ := base.I列出子项(`Target`); strings.Join(keys, ",") != "Child" {
+		t.Errorf("deleting the link changed the target: %q", keys)
+	}
+}
+
+func TestOverlayDeletedAncestor(t *testing.T) {
+	// 离线会话不跟随链接, 所以给链接表项加上子项X, 模拟真实注册表中通过链接看到的目标的子项。
+	link := &注册表类.I离线表项{Name: "L", SubKeys: []*注册表类.I离线表项{{Name: "X", Values: []*注册表类.I离线值{dword("Port", 1)}}}}
+	if err := link.I设置链接目标(`HKLM\SOFTWARE\Target`); err != nil {
+		t.Fatal(err)
+	}
+	base, err := 注册表类.I新建离线会话(&注册表类.I离线表项{SubKeys: []*注册表类.I离线表项{{Name: "A", SubKeys: []*注册表类.I离线表项{link}}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	o, err := 注册表类.I新建覆盖会话(base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := o.I删除表项(`A\L`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := o.I取信息(`A\L\X`); !errors.Is(err, 注册表类.ErrSessionNotExist) {
+		t.Errorf("stat under the deleted link: got %v", err)
+	}
+	if _, err := o.I取值(`A\L\X`, "Port"); !errors.Is(err, 注册表类.ErrSessionNotExist) {
+		t.Errorf("value under the deleted link: got %v", err)
+	}
+
+	// 重新创建后, 基础中链接下的内容仍然不可见。
+	if existed, err := o.I创建表项(`A\L\X\Y`); err != nil || existed {
+		t.Fatalf("create under the deleted link: got %v, %v", existed, err)
+	}
+	if st, err := o.I取信息(`A\L\X`); err != nil || st.ValueCount != 0 || st.SubKeyCount != 1 {
+		t.Errorf("recreated X: got %+v, %v", st, err)
+	}
+	diff, err := o.I差异()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, d := range diff {
+		got = append(got, d.String())
+	}
+	want := []string{`key deleted A\L`, `key added A\L`, `key added A\L\X`, `key added A\L\X\Y`}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("diff:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
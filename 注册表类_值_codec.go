@@ -0,0 +1,334 @@
+package 注册表类
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrOverflow 当注册表中的数值超出目标类型的范围时返回。
+var ErrOverflow = errors.New("registry value overflows target type")
+
+// I转换 控制 I解码值 在值的类型与目标类型不一致时是否尝试转换, 可以组合使用。
+type I转换 uint32
+
+const (
+	// COERCE_TEXT 允许从SZ和EXPAND_SZ文本解析整数、布尔、时长和时间, 例如把"1"读为DWORD。
+	COERCE_TEXT I转换 = 1 << iota
+	// COERCE_NUMBER 允许把DWORD和QWORD读为十进制文本。
+	COERCE_NUMBER
+	// COERCE_MULTI 允许把SZ读为单元素的数组, 把只有一个元素的MULTI_SZ读为文本。
+	COERCE_MULTI
+	// COERCE_BINARY 允许把任意类型的值读为原始的[]byte。
+	COERCE_BINARY
+
+	COERCE_NONE I转换 = 0
+	COERCE_ALL      = COERCE_TEXT | COERCE_NUMBER | COERCE_MULTI | COERCE_BINARY
+)
+
+// I编码值 把Go值编码为注册表值, 支持的类型和对应的注册表类型为:
+//
+//	string                      SZ
+//	[]string                    MULTI_SZ
+//	[]byte                      BINARY
+//	bool                        DWORD, 0或1
+//	8到32位的整数                DWORD, 有符号数按补码保存
+//	int、uint、64位整数和uintptr   QWORD
+//	time.Duration               QWORD, 单位为纳秒
+//	time.Time                   QWORD, FILETIME格式
+//	encoding.TextMarshaler      SZ
+//
+// 以这些类型为底层类型的命名类型按底层类型编码。文本不能包含NUL。
+func I编码值[T any](名称 string, 值 T) (*I离线值, error) {
+	switch x := any(值).(type) {
+	case time.Duration:
+		return 数字值(名称, QWORD, uint64(x)), nil
+	case time.Time:
+		return 数字值(名称, QWORD, hive取filetime(x)), nil
+	case encoding.TextMarshaler:
+		文本, err := x.MarshalText()
+		if err != nil {
+			return nil, err
+		}
+		return 编码文本(名称, string(文本))
+	}
+	rv := reflect.ValueOf(any(值))
+	switch rv.Kind() {
+	case reflect.String:
+		return 编码文本(名称, rv.String())
+	case reflect.Bool:
+		var n uint64
+		if rv.Bool() {
+			n = 1
+		}
+		return 数字值(名称, DWORD, n), nil
+	case reflect.Int8, reflect.Int16, reflect.Int32:
+		return 数字值(名称, DWORD, uint64(uint32(rv.Int()))), nil
+	case reflect.Int, reflect.Int64:
+		return 数字值(名称, QWORD, uint64(rv.Int())), nil
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return 数字值(名称, DWORD, rv.Uint()), nil
+	case reflect.Uint, reflect.Uint64, reflect.Uintptr:
+		return 数字值(名称, QWORD, rv.Uint()), nil
+	case reflect.Slice:
+		switch rv.Type().Elem().Kind() {
+		case reflect.Uint8:
+			return &I离线值{Name: 名称, Type: BINARY, Data: append([]byte(nil), rv.Bytes()...)}, nil
+		case reflect.String:
+			var b strings.Builder
+			for i := 0; i < rv.Len(); i++ {
+				s := rv.Index(i).String()
+				if strings.IndexByte(s, 0) >= 0 {
+					return nil, fmt.Errorf("值 %q 的第%d个文本包含NUL", 名称, i)
+				}
+				b.WriteString(s)
+				b.WriteByte(0)
+			}
+			b.WriteByte(0)
+			return &I离线值{Name: 名称, Type: MULTI_SZ, Data: hive编码UTF16(b.String())}, nil
+		}
+	}
+	return nil, fmt.Errorf("不支持编码类型 %T", 值)
+}
+
+// I解码值 把注册表值解码为类型T, 支持的类型见 I编码值。
+// 整数可以从DWORD、DWORD_BIG_ENDIAN和QWORD读取, 超出目标类型范围时返回 ErrOverflow。
+// 32位及以下的有符号整数把DWORD按补码解释, 64位有符号整数把DWORD按无符号数扩展,
+// 与 I取整数值64 一致。布尔值从整数读取, 非0为true。time.Time 还可以从8字节的BINARY读取。
+// 值的类型不匹配时返回 ErrUnexpectedType, 除非'转换'中允许了相应的转换。
+func I解码值[T any](v *I离线值, 转换 ...I转换) (T, error) {
+	var 结果 T
+	if v == nil {
+		return 结果, errors.New("注册表类对象为nil")
+	}
+	var 模式 I转换
+	for _, c := range 转换 {
+		模式 |= c
+	}
+	if err := 解码到(v, 模式, &结果); err != nil {
+		var 零 T
+		return 零, err
+	}
+	return 结果, nil
+}
+
+// 解码到 把注册表值解码到指针p指向的变量。
+func 解码到(v *I离线值, 模式 I转换, p any) error {
+	switch p := p.(type) {
+	case *time.Duration:
+		return 解码时长(v, 模式, p)
+	case *time.Time:
+		return 解码时间(v, 模式, p)
+	case encoding.TextUnmarshaler:
+		s, err := 解码文本(v, 模式)
+		if err != nil {
+			return err
+		}
+		return p.UnmarshalText([]byte(s))
+	}
+	return 解码基本类型(v, 模式, reflect.ValueOf(p).Elem())
+}
+
+func 解码基本类型(v *I离线值, 模式 I转换, rv reflect.Value) error {
+	switch rv.Kind() {
+	case reflect.String:
+		s, err := 解码文本(v, 模式)
+		rv.SetString(s)
+		return err
+	case reflect.Bool:
+		if 模式&COERCE_TEXT != 0 && 是文本类型(v.Type) {
+			s, _ := v.I取文本()
+			b, err := strconv.ParseBool(strings.TrimSpace(s))
+			if err != nil {
+				return fmt.Errorf("值 %q: %w", v.Name, err)
+			}
+			rv.SetBool(b)
+			return nil
+		}
+		n, _, err := 解码数字(v)
+		rv.SetBool(n != 0)
+		return err
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := 解码有符号(v, 模式, rv.Type().Bits())
+		rv.SetInt(n)
+		return err
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, err := 解码无符号(v, 模式, rv.Type().Bits())
+		rv.SetUint(n)
+		return err
+	case reflect.Slice:
+		switch rv.Type().Elem().Kind() {
+		case reflect.Uint8:
+			if v.Type != BINARY && v.Type != NONE && 模式&COERCE_BINARY == 0 {
+				return ErrUnexpectedType
+			}
+			rv.SetBytes(append([]byte(nil), v.Data...))
+			return nil
+		case reflect.String:
+			a, err := 解码文本数组(v, 模式)
+			if err != nil {
+				return err
+			}
+			s := reflect.MakeSlice(rv.Type(), len(a), len(a))
+			for i := range a {
+				s.Index(i).SetString(a[i])
+			}
+			rv.Set(s)
+			return nil
+		}
+	}
+	return fmt.Errorf("不支持解码类型 %s", rv.Type())
+}
+
+func 数字值(名称 string, 值类型 uint32, n uint64) *I离线值 {
+	if 值类型 == DWORD {
+		b := make([]byte, 4)
+		le.PutUint32(b, uint32(n))
+		return &I离线值{Name: 名称, Type: DWORD, Data: b}
+	}
+	b := make([]byte, 8)
+	le.PutUint64(b, n)
+	return &I离线值{Name: 名称, Type: QWORD, Data: b}
+}
+
+func 编码文本(名称, s string) (*I离线值, error) {
+	if strings.IndexByte(s, 0) >= 0 {
+		return nil, fmt.Errorf("值 %q 的文本包含NUL", 名称)
+	}
+	return I新建文本值(名称, s), nil
+}
+
+func 是文本类型(值类型 uint32) bool {
+	return 值类型 == SZ || 值类型 == EXPAND_SZ
+}
+
+// 解码数字 返回DWORD、DWORD_BIG_ENDIAN或QWORD值的数值和存储的位数。
+func 解码数字(v *I离线值) (uint64, int, error) {
+	switch {
+	case v.Type == DWORD && len(v.Data) == 4:
+		return uint64(le.Uint32(v.Data)), 32, nil
+	case v.Type == DWORD_BIG_ENDIAN && len(v.Data) == 4:
+		d := v.Data
+		return uint64(d[0])<<24 | uint64(d[1])<<16 | uint64(d[2])<<8 | uint64(d[3]), 32, nil
+	case v.Type == QWORD && len(v.Data) == 8:
+		return le.Uint64(v.Data), 64, nil
+	}
+	return 0, 0, ErrUnexpectedType
+}
+
+func 解码有符号(v *I离线值, 模式 I转换, 位数 int) (int64, error) {
+	if 模式&COERCE_TEXT != 0 && 是文本类型(v.Type) {
+		s, _ := v.I取文本()
+		n, err := strconv.ParseInt(strings.TrimSpace(s), 0, 位数)
+		return n, 数字错误(v, err)
+	}
+	u, 存储位数, err := 解码数字(v)
+	if err != nil {
+		return 0, err
+	}
+	n := int64(u)
+	if 存储位数 == 32 && 位数 <= 32 {
+		n = int64(int32(u))
+	}
+	if 位数 < 64 && (n < -1<<(位数-1) || n >= 1<<(位数-1)) {
+		return 0, fmt.Errorf("值 %q: %w: %d 超出%d位有符号整数的范围", v.Name, ErrOverflow, n, 位数)
+	}
+	return n, nil
+}
+
+func 解码无符号(v *I离线值, 模式 I转换, 位数 int) (uint64, error) {
+	if 模式&COERCE_TEXT != 0 && 是文本类型(v.Type) {
+		s, _ := v.I取文本()
+		n, err := strconv.ParseUint(strings.TrimSpace(s), 0, 位数)
+		return n, 数字错误(v, err)
+	}
+	u, _, err := 解码数字(v)
+	if err != nil {
+		return 0, err
+	}
+	if 位数 < 64 && u>>位数 != 0 {
+		return 0, fmt.Errorf("值 %q: %w: %d 超出%d位无符号整数的范围", v.Name, ErrOverflow, u, 位数)
+	}
+	return u, nil
+}
+
+// 数字错误 把strconv的范围错误转换为 ErrOverflow。
+func 数字错误(v *I离线值, err error) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, strconv.ErrRange) {
+		return fmt.Errorf("值 %q: %w: %v", v.Name, ErrOverflow, err)
+	}
+	return fmt.Errorf("值 %q: %w", v.Name, err)
+}
+
+func 解码文本(v *I离线值, 模式 I转换) (string, error) {
+	switch {
+	case 是文本类型(v.Type):
+		return v.I取文本()
+	case 模式&COERCE_NUMBER != 0 && (v.Type == DWORD || v.Type == DWORD_BIG_ENDIAN || v.Type == QWORD):
+		n, _, err := 解码数字(v)
+		return strconv.FormatUint(n, 10), err
+	case 模式&COERCE_MULTI != 0 && v.Type == MULTI_SZ:
+		a, err := 解码文本数组(v, 模式)
+		if err != nil {
+			return "", err
+		}
+		if len(a) != 1 {
+			return "", ErrUnexpectedType
+		}
+		return a[0], nil
+	}
+	return "", ErrUnexpectedType
+}
+
+func 解码文本数组(v *I离线值, 模式 I转换) ([]string, error) {
+	switch {
+	case v.Type == MULTI_SZ:
+	case 模式&COERCE_MULTI != 0 && 是文本类型(v.Type):
+		s, err := v.I取文本()
+		return []string{s}, err
+	default:
+		return nil, ErrUnexpectedType
+	}
+	// 与RegGetValue一样, 遇到第一个空文本即结束。
+	a := []string{}
+	for s := range strings.SplitSeq(hive解码UTF16(v.Data), "\x00") {
+		if s == "" {
+			break
+		}
+		a = append(a, s)
+	}
+	return a, nil
+}
+
+func 解码时长(v *I离线值, 模式 I转换, p *time.Duration) error {
+	if 模式&COERCE_TEXT != 0 && 是文本类型(v.Type) {
+		s, _ := v.I取文本()
+		d, err := time.ParseDuration(strings.TrimSpace(s))
+		*p = d
+		return 数字错误(v, err)
+	}
+	n, err := 解码有符号(v, 模式, 64)
+	*p = time.Duration(n)
+	return err
+}
+
+func 解码时间(v *I离线值, 模式 I转换, p *time.Time) error {
+	switch {
+	case v.Type == QWORD && len(v.Data) == 8, v.Type == BINARY && len(v.Data) == 8:
+		*p = hive解析filetime(le.Uint64(v.Data))
+		return nil
+	case 模式&COERCE_TEXT != 0 && 是文本类型(v.Type):
+		s, _ := v.I取文本()
+		t, err := time.Parse(time.RFC3339Nano, strings.TrimSpace(s))
+		*p = t
+		return 数字错误(v, err)
+	}
+	return ErrUnexpectedType
+}
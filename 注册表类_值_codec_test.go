@@ -0,0 +1,116 @@
+package 注册表类_test
+
+import (
+	"errors"
+	"net/netip"
+	"reflect"
+	"testing"
+	"time"
+
+	注册表类 "e.coding.net/gogit/go/gosdk/core/win_registry_cn"
+)
+
+type level uint8
+
+func roundTrip[T any](t *testing.T, in T, wantType uint32) {
+	t.Helper()
+	v, err := 注册表类.I编码值("v", in)
+	if err != nil {
+		t.Fatalf("encode %T: %v", in, err)
+	}
+	if v.Type != wantType {
+		t.Errorf("encode %T: got type %d, want %d", in, v.Type, wantType)
+	}
+	out, err := 注册表类.I解码值[T](v)
+	if err != nil {
+		t.Fatalf("decode %T: %v", in, err)
+	}
+	if !reflect.DeepEqual(in, out) {
+		t.Errorf("round trip %T: got %v, want %v", in, out, in)
+	}
+}
+
+func TestValueCodecRoundTrip(t *testing.T) {
+	roundTrip(t, "hello, 世界", 注册表类.SZ)
+	roundTrip(t, []string{"a", "b c", "ü"}, 注册表类.MULTI_SZ)
+	roundTrip(t, []string{}, 注册表类.MULTI_SZ)
+	roundTrip(t, []byte{0, 1, 2, 0xff}, 注册表类.BINARY)
+	roundTrip(t, true, 注册表类.DWORD)
+	roundTrip(t, false, 注册表类.DWORD)
+	roundTrip(t, int8(-128), 注册表类.DWORD)
+	roundTrip(t, int16(-2), 注册表类.DWORD)
+	roundTrip(t, int32(-1), 注册表类.DWORD)
+	roundTrip(t, int64(-1<<63), 注册表类.QWORD)
+	roundTrip(t, int(-5), 注册表类.QWORD)
+	roundTrip(t, uint8(255), 注册表类.DWORD)
+	roundTrip(t, uint32(1<<32-1), 注册表类.DWORD)
+	roundTrip(t, uint64(1<<64-1), 注册表类.QWORD)
+	roundTrip(t, level(3), 注册表类.DWORD)
+	roundTrip(t, 90*time.Second, 注册表类.QWORD)
+	roundTrip(t, time.Date(2024, 5, 6, 7, 8, 9, 100, time.UTC), 注册表类.QWORD)
+	roundTrip(t, time.Time{}, 注册表类.QWORD)
+	roundTrip(t, netip.MustParseAddr("192.0.2.1"), 注册表类.SZ)
+
+	if _, err := 注册表类.I编码值("v", "a\x00b"); err == nil {
+		t.Error("encoding text with NUL: want error")
+	}
+	if _, err := 注册表类.I编码值("v", 1.5); err == nil {
+		t.Error("encoding float64: want error")
+	}
+}
+
+func TestValueCodecOverflow(t *testing.T) {
+	big := dword("v", 300)
+	if _, err := 注册表类.I解码值[uint8](big); !errors.Is(err, 注册表类.ErrOverflow) {
+		t.Errorf("uint8 from 300: got %v, want ErrOverflow", err)
+	}
+	if n, err := 注册表类.I解码值[uint16](big); err != nil || n != 300 {
+		t.Errorf("uint16 from 300: got %d, %v", n, err)
+	}
+	neg := dword("v", 0xffffffff)
+	if n, err := 注册表类.I解码值[int8](neg); err != nil || n != -1 {
+		t.Errorf("int8 from 0xffffffff: got %d, %v", n, err)
+	}
+	if n, err := 注册表类.I解码值[int64](neg); err != nil || n != 0xffffffff {
+		t.Errorf("int64 from 0xffffffff: got %d, %v", n, err)
+	}
+	q, _ := 注册表类.I编码值("v", uint64(1<<40))
+	if _, err := 注册表类.I解码值[int32](q); !errors.Is(err, 注册表类.ErrOverflow) {
+		t.Errorf("int32 from 1<<40: got %v, want ErrOverflow", err)
+	}
+	if _, err := 注册表类.I解码值[uint16](sz("v", "70000"), 注册表类.COERCE_TEXT); !errors.Is(err, 注册表类.ErrOverflow) {
+		t.Errorf("uint16 from text 70000: got %v, want ErrOverflow", err)
+	}
+}
+
+func TestValueCodecCoercion(t *testing.T) {
+	one := sz("v", "1")
+	if _, err := 注册表类.I解码值[uint32](one); err != 注册表类.ErrUnexpectedType {
+		t.Errorf("uint32 from SZ without coercion: got %v", err)
+	}
+	if n, err := 注册表类.I解码值[uint32](one, 注册表类.COERCE_TEXT); err != nil || n != 1 {
+		t.Errorf("uint32 from SZ: got %d, %v", n, err)
+	}
+	if b, err := 注册表类.I解码值[bool](sz("v", "true"), 注册表类.COERCE_TEXT); err != nil || !b {
+		t.Errorf("bool from SZ: got %v, %v", b, err)
+	}
+	if d, err := 注册表类.I解码值[time.Duration](sz("v", "1m30s"), 注册表类.COERCE_TEXT); err != nil || d != 90*time.Second {
+		t.Errorf("duration from SZ: got %v, %v", d, err)
+	}
+	if s, err := 注册表类.I解码值[string](dword("v", 42), 注册表类.COERCE_NUMBER); err != nil || s != "42" {
+		t.Errorf("string from DWORD: got %q, %v", s, err)
+	}
+	if a, err := 注册表类.I解码值[[]string](one, 注册表类.COERCE_MULTI); err != nil || !reflect.DeepEqual(a, []string{"1"}) {
+		t.Errorf("[]string from SZ: got %q, %v", a, err)
+	}
+	if _, err := 注册表类.I解码值[[]byte](one); err != 注册表类.ErrUnexpectedType {
+		t.Errorf("[]byte from SZ without coercion: got %v", err)
+	}
+	if b, err := 注册表类.I解码值[[]byte](one, 注册表类.COERCE_ALL); err != nil || len(b) != 4 {
+		t.Errorf("[]byte from SZ: got %v, %v", b, err)
+	}
+	be := &注册表类.I离线值{Name: "v", Type: 注册表类.DWORD_BIG_ENDIAN, Data: []byte{0, 0, 1, 2}}
+	if n, err := 注册表类.I解码值[int](be); err != nil || n != 0x102 {
+		t.Errorf("int from DWORD_BIG_ENDIAN: got %d, %v", n, err)
+	}
+}
@@ -0,0 +1,45 @@
+//go:build windows
+// +build windows
+
+package 注册表类
+
+import "errors"
+
+// I取 读取注册表对象k下名称为'名称'的值并解码为类型T, 支持的类型和转换规则见 I解码值。
+// 如果值不存在, 返回ErrNotExist。
+//
+//	端口, err := 注册表类.I取[uint16](k, "Port")
+//	超时, err := 注册表类.I取[time.Duration](k, "Timeout", 注册表类.COERCE_TEXT)
+func I取[T any](k *Key结构, 名称 string, 转换 ...I转换) (T, error) {
+	var 零 T
+	if k == nil {
+		return 零, errors.New("注册表类对象为nil")
+	}
+	v, err := k.取原始值(名称)
+	if err != nil {
+		return 零, err
+	}
+	return I解码值[T](v, 转换...)
+}
+
+// I取或默认 与 I取 相同, 但值不存在时返回'默认'而不是ErrNotExist。
+// 值存在但类型不匹配或溢出时仍然返回错误。
+func I取或默认[T any](k *Key结构, 名称 string, 默认 T, 转换 ...I转换) (T, error) {
+	v, err := I取[T](k, 名称, 转换...)
+	if errors.Is(err, ErrNotExist) {
+		return 默认, nil
+	}
+	return v, err
+}
+
+// I设置 把值按 I编码值 的规则编码后写入注册表对象k下名称为'名称'的值。
+func I设置[T any](k *Key结构, 名称 string, 值 T) error {
+	if k == nil {
+		return errors.New("注册表类对象为nil")
+	}
+	v, err := I编码值(名称, 值)
+	if err != nil {
+		return err
+	}
+	return k.setValue(名称, v.Type, v.Data)
+}
@@ -0,0 +1,71 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package 注册表类
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrUnexpectedType 当值的类型意外时，GetValue返回。
+var ErrUnexpectedType = errors.New("unexpected key value type")
+
+const (
+	// NONE 注册表值类型。
+	NONE                       = 0
+	SZ                         = 1
+	EXPAND_SZ                  = 2
+	BINARY                     = 3
+	DWORD                      = 4
+	DWORD_BIG_ENDIAN           = 5
+	LINK                       = 6
+	MULTI_SZ                   = 7
+	RESOURCE_LIST              = 8
+	FULL_RESOURCE_DESCRIPTOR   = 9
+	RESOURCE_REQUIREMENTS_LIST = 10
+	QWORD                      = 11
+)
+
+var 值类型名称 = [...]string{
+	NONE:                       "REG_NONE",
+	SZ:                         "REG_SZ",
+	EXPAND_SZ:                  "REG_EXPAND_SZ",
+	BINARY:                     "REG_BINARY",
+	DWORD:                      "REG_DWORD",
+	DWORD_BIG_ENDIAN:           "REG_DWORD_BIG_ENDIAN",
+	LINK:                       "REG_LINK",
+	MULTI_SZ:                   "REG_MULTI_SZ",
+	RESOURCE_LIST:              "REG_RESOURCE_LIST",
+	FULL_RESOURCE_DESCRIPTOR:   "REG_FULL_RESOURCE_DESCRIPTOR",
+	RESOURCE_REQUIREMENTS_LIST: "REG_RESOURCE_REQUIREMENTS_LIST",
+	QWORD:                      "REG_QWORD",
+}
+
+// I值类型名称 返回值类型的名称, 例如 REG_SZ。未知的类型返回 REG_0x<十六进制>。
+func I值类型名称(值类型 uint32) string {
+	if int(值类型) < len(值类型名称) {
+		return 值类型名称[值类型]
+	}
+	return fmt.Sprintf("REG_0x%x", 值类型)
+}
+
+// I解析值类型 是 I值类型名称 的逆操作, 不区分大小写, 可以省略 REG_ 前缀。
+func I解析值类型(名称 string) (uint32, error) {
+	s := strings.ToUpper(名称)
+	if !strings.HasPrefix(s, "REG_") {
+		s = "REG_" + s
+	}
+	for t, n := range 值类型名称 {
+		if n == s {
+			return uint32(t), nil
+		}
+	}
+	if n, err := strconv.ParseUint(strings.TrimPrefix(s, "REG_0X"), 16, 32); err == nil && strings.HasPrefix(s, "REG_0X") {
+		return uint32(n), nil
+	}
+	return 0, fmt.Errorf("未知的值类型 %q", 名称)
+}
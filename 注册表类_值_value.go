@@ -13,31 +13,12 @@ import (
 	"syscall"
 )
 
-const (
-	// NONE 注册表值类型。
-	NONE                       = 0
-	SZ                         = 1
-	EXPAND_SZ                  = 2
-	BINARY                     = 3
-	DWORD                      = 4
-	DWORD_BIG_ENDIAN           = 5
-	LINK                       = 6
-	MULTI_SZ                   = 7
-	RESOURCE_LIST              = 8
-	FULL_RESOURCE_DESCRIPTOR   = 9
-	RESOURCE_REQUIREMENTS_LIST = 10
-	QWORD                      = 11
-)
-
 var (
 	// ErrShortBuffer 当缓冲区太短时返回。
 	ErrShortBuffer = syscall.ERROR_MORE_DATA
 
 	// ErrNotExist 当注册表项或值不存在时返回。
 	ErrNotExist = syscall.ERROR_FILE_NOT_FOUND
-
-	// ErrUnexpectedType 当值的类型意外时，GetValue返回。
-	ErrUnexpectedType = errors.New("unexpected key value type")
 )
 
 // I取值 检索与开放注册表对象k关联的指定值的类型和数据. 它填充缓冲区buf并返回检索到的字节计数n.
@@ -51,6 +32,9 @@ func (k *Key结构) I取值(名称 string, 缓冲区 []byte) (n int, 值类型 u
 	if k == nil {
 		return 0, 0, errors.New("注册表类对象为nil")
 	}
+	if k.拦截 != nil {
+		return k.拦截取值(名称, 缓冲区)
+	}
 	return k.Key父类.GetValue(名称, 缓冲区)
 }
 
@@ -62,6 +46,9 @@ func (k *Key结构) I取文本值(名称 string) (值 string, 值类型 uint32,
 	if k == nil {
 		return "", 0, errors.New("注册表类对象为nil")
 	}
+	if k.拦截 != nil {
+		return k.拦截取文本值(名称)
+	}
 	return k.Key父类.GetStringValue(名称)
 }
 
@@ -89,6 +76,9 @@ func (k *Key结构) I取文本值_数组(名称 string) (值 []string, 值类型
 	if k == nil {
 		return nil, 0, errors.New("注册表类对象为nil")
 	}
+	if k.拦截 != nil {
+		return k.拦截取文本值_数组(名称)
+	}
 	return k.Key父类.GetStringsValue(名称)
 }
 
@@ -99,6 +89,10 @@ func (k *Key结构) I取整数值64(名称 string) (值 int64, 值类型 uint32,
 	if k == nil {
 		return 0, 0, errors.New("注册表类对象为nil")
 	}
+	if k.拦截 != nil {
+		返回整数, 值类型, err := k.拦截取整数值(名称)
+		return int64(返回整数), 值类型, err
+	}
 	返回整数, 值类型, err := k.Key父类.GetIntegerValue(名称)
 	return int64(返回整数), 值类型, err
 }
@@ -110,6 +104,9 @@ func (k *Key结构) I取字节集值(名称 string) (值 []byte, 值类型 uint3
 	if k == nil {
 		return nil, 0, errors.New("注册表类对象为nil")
 	}
+	if k.拦截 != nil {
+		return k.拦截取字节集值(名称)
+	}
 	return k.Key父类.GetBinaryValue(名称)
 }
 
@@ -117,6 +114,9 @@ func (k *Key结构) setValue(名称 string, 值类型 uint32, data []byte) error
 	if k == nil {
 		return errors.New("注册表类对象为nil")
 	}
+	if k.拦截 != nil {
+		return k.拦截设置值(名称, 值类型, data)
+	}
 	p, err := syscall.UTF16PtrFromString(名称)
 	if err != nil {
 		return err
@@ -132,7 +132,9 @@ func (k *Key结构) I设置整数值32(名称 string, 值 int32) error {
 	if k == nil {
 		return errors.New("注册表类对象为nil")
 	}
-
+	if k.拦截 != nil {
+		return k.setValue(名称, DWORD, 编码整数值(uint64(uint32(值)), 4))
+	}
 	return k.Key父类.SetDWordValue(名称, uint32(值))
 }
 
@@ -141,6 +143,9 @@ func (k *Key结构) I设置整数值64(名称 string, 值 int64) error {
 	if k == nil {
 		return errors.New("注册表类对象为nil")
 	}
+	if k.拦截 != nil {
+		return k.setValue(名称, QWORD, 编码整数值(uint64(值), 8))
+	}
 	return k.Key父类.SetQWordValue(名称, uint64(值))
 }
 
@@ -149,6 +154,9 @@ func (k *Key结构) I设置文本值(名称, 值 string) error {
 	if k == nil {
 		return errors.New("注册表类对象为nil")
 	}
+	if k.拦截 != nil {
+		return k.拦截设置文本值(名称, SZ, 值)
+	}
 	return k.Key父类.SetStringValue(名称, 值)
 }
 
@@ -157,6 +165,9 @@ func (k *Key结构) I按环境变量设置文本值(名称, 值 string) error {
 	if k == nil {
 		return errors.New("注册表类对象为nil")
 	}
+	if k.拦截 != nil {
+		return k.拦截设置文本值(名称, EXPAND_SZ, 值)
+	}
 	return k.Key父类.SetExpandStringValue(名称, 值)
 }
 
@@ -165,6 +176,9 @@ func (k *Key结构) I设置文本值_数组(名称 string, 值 []string) error {
 	if k == nil {
 		return errors.New("注册表类对象为nil")
 	}
+	if k.拦截 != nil {
+		return k.拦截设置文本值_数组(名称, 值)
+	}
 	return k.Key父类.SetStringsValue(名称, 值)
 }
 
@@ -173,6 +187,9 @@ func (k *Key结构) I设置字节集值(名称 string, 值 []byte) error {
 	if k == nil {
 		return errors.New("注册表类对象为nil")
 	}
+	if k.拦截 != nil {
+		return k.setValue(名称, BINARY, 值)
+	}
 	return k.Key父类.SetBinaryValue(名称, 值)
 }
 
@@ -181,7 +198,9 @@ func (k *Key结构) I删除值(名称 string) error {
 	if k == nil {
 		return errors.New("注册表类对象为nil")
 	}
-	return k.Key父类.DeleteValue(名称)
+	return k.拦截调用(&I调用记录{Op: I操作_删除值, Path: k.路径, Name: 名称}, func() error {
+		return k.Key父类.DeleteValue(名称)
+	})
 }
 
 // I取所有子项值 返回key k的值名称。
@@ -190,5 +209,8 @@ func (k *Key结构) I取所有子项值(返回数量 int) ([]string, error) {
 	if k == nil {
 		return nil, errors.New("注册表类对象为nil")
 	}
+	if k.拦截 != nil {
+		return k.拦截取值名称(返回数量)
+	}
 	return k.Key父类.ReadValueNames(返回数量)
 }
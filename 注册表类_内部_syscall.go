@@ -12,6 +12,8 @@ import "syscall"
 const (
 	_REG_OPTION_NON_VOLATILE = 0
 
+	_KeyWriteTimeInformation = 0
+
 	_REG_CREATED_NEW_KEY     = 1
 	_REG_OPENED_EXISTING_KEY = 2
 
@@ -29,5 +31,11 @@ func LoadRegLoadMUIString() error {
 //sys	regDeleteValue(key syscall.Handle, name *uint16) (regerrno error) = advapi32.RegDeleteValueW
 //sys   regLoadMUIString(key syscall.Handle, name *uint16, buf *uint16, buflen uint32, buflenCopied *uint32, flags uint32, dir *uint16) (regerrno error) = advapi32.RegLoadMUIStringW
 //sys	regConnectRegistry(machinename *uint16, key syscall.Handle, result *syscall.Handle) (regerrno error) = advapi32.RegConnectRegistryW
+//sys	regGetKeySecurity(key syscall.Handle, securityInformation uint32, securityDescriptor *byte, securityDescriptorLen *uint32) (regerrno error) = advapi32.RegGetKeySecurity
+//sys	regSetKeySecurity(key syscall.Handle, securityInformation uint32, securityDescriptor *byte) (regerrno error) = advapi32.RegSetKeySecurity
+//sys	regLoadKey(key syscall.Handle, subkey *uint16, file *uint16) (regerrno error) = advapi32.RegLoadKeyW
+//sys	regUnLoadKey(key syscall.Handle, subkey *uint16) (regerrno error) = advapi32.RegUnLoadKeyW
+//sys	ntDeleteKey(key syscall.Handle) (ntstatus error) = ntdll.NtDeleteKey
+//sys	ntSetInformationKey(key syscall.Handle, infoClass uint32, info *uint64, infoLen uint32) (ntstatus error) = ntdll.NtSetInformationKey
 
 //sys	expandEnvironmentStrings(src *uint16, dst *uint16, size uint32) (n uint32, err error) = kernel32.ExpandEnvironmentStringsW
@@ -37,15 +37,22 @@ func errnoErr(e syscall.Errno) error {
 var (
 	modadvapi32 = windows.NewLazySystemDLL("advapi32.dll")
 	modkernel32 = windows.NewLazySystemDLL("kernel32.dll")
+	modntdll    = windows.NewLazySystemDLL("ntdll.dll")
 
 	procRegConnectRegistryW       = modadvapi32.NewProc("RegConnectRegistryW")
 	procRegCreateKeyExW           = modadvapi32.NewProc("RegCreateKeyExW")
 	procRegDeleteKeyW             = modadvapi32.NewProc("RegDeleteKeyW")
 	procRegDeleteValueW           = modadvapi32.NewProc("RegDeleteValueW")
 	procRegEnumValueW             = modadvapi32.NewProc("RegEnumValueW")
+	procRegGetKeySecurity         = modadvapi32.NewProc("RegGetKeySecurity")
+	procRegLoadKeyW               = modadvapi32.NewProc("RegLoadKeyW")
 	procRegLoadMUIStringW         = modadvapi32.NewProc("RegLoadMUIStringW")
+	procRegSetKeySecurity         = modadvapi32.NewProc("RegSetKeySecurity")
 	procRegSetValueExW            = modadvapi32.NewProc("RegSetValueExW")
+	procRegUnLoadKeyW             = modadvapi32.NewProc("RegUnLoadKeyW")
 	procExpandEnvironmentStringsW = modkernel32.NewProc("ExpandEnvironmentStringsW")
+	procNtDeleteKey               = modntdll.NewProc("NtDeleteKey")
+	procNtSetInformationKey       = modntdll.NewProc("NtSetInformationKey")
 )
 
 func regConnectRegistry(machinename *uint16, key syscall.Handle, result *syscall.Handle) (regerrno error) {
@@ -88,6 +95,22 @@ func regEnumValue(key syscall.Handle, index uint32, name *uint16, nameLen *uint3
 	return
 }
 
+func regGetKeySecurity(key syscall.Handle, securityInformation uint32, securityDescriptor *byte, securityDescriptorLen *uint32) (regerrno error) {
+	r0, _, _ := syscall.Syscall6(procRegGetKeySecurity.Addr(), 4, uintptr(key), uintptr(securityInformation), uintptr(unsafe.Pointer(securityDescriptor)), uintptr(unsafe.Pointer(securityDescriptorLen)), 0, 0)
+	if r0 != 0 {
+		regerrno = syscall.Errno(r0)
+	}
+	return
+}
+
+func regLoadKey(key syscall.Handle, subkey *uint16, file *uint16) (regerrno error) {
+	r0, _, _ := syscall.Syscall(procRegLoadKeyW.Addr(), 3, uintptr(key), uintptr(unsafe.Pointer(subkey)), uintptr(unsafe.Pointer(file)))
+	if r0 != 0 {
+		regerrno = syscall.Errno(r0)
+	}
+	return
+}
+
 func regLoadMUIString(key syscall.Handle, name *uint16, buf *uint16, buflen uint32, buflenCopied *uint32, flags uint32, dir *uint16) (regerrno error) {
 	r0, _, _ := syscall.Syscall9(procRegLoadMUIStringW.Addr(), 7, uintptr(key), uintptr(unsafe.Pointer(name)), uintptr(unsafe.Pointer(buf)), uintptr(buflen), uintptr(unsafe.Pointer(buflenCopied)), uintptr(flags), uintptr(unsafe.Pointer(dir)), 0, 0)
 	if r0 != 0 {
@@ -96,6 +119,14 @@ func regLoadMUIString(key syscall.Handle, name *uint16, buf *uint16, buflen uint
 	return
 }
 
+func regSetKeySecurity(key syscall.Handle, securityInformation uint32, securityDescriptor *byte) (regerrno error) {
+	r0, _, _ := syscall.Syscall(procRegSetKeySecurity.Addr(), 3, uintptr(key), uintptr(securityInformation), uintptr(unsafe.Pointer(securityDescriptor)))
+	if r0 != 0 {
+		regerrno = syscall.Errno(r0)
+	}
+	return
+}
+
 func regSetValueEx(key syscall.Handle, valueName *uint16, reserved uint32, vtype uint32, buf *byte, bufsize uint32) (regerrno error) {
 	r0, _, _ := syscall.Syscall6(procRegSetValueExW.Addr(), 6, uintptr(key), uintptr(unsafe.Pointer(valueName)), uintptr(reserved), uintptr(vtype), uintptr(unsafe.Pointer(buf)), uintptr(bufsize))
 	if r0 != 0 {
@@ -104,6 +135,14 @@ func regSetValueEx(key syscall.Handle, valueName *uint16, reserved uint32, vtype
 	return
 }
 
+func regUnLoadKey(key syscall.Handle, subkey *uint16) (regerrno error) {
+	r0, _, _ := syscall.Syscall(procRegUnLoadKeyW.Addr(), 2, uintptr(key), uintptr(unsafe.Pointer(subkey)), 0)
+	if r0 != 0 {
+		regerrno = syscall.Errno(r0)
+	}
+	return
+}
+
 func expandEnvironmentStrings(src *uint16, dst *uint16, size uint32) (n uint32, err error) {
 	r0, _, e1 := syscall.Syscall(procExpandEnvironmentStringsW.Addr(), 3, uintptr(unsafe.Pointer(src)), uintptr(unsafe.Pointer(dst)), uintptr(size))
 	n = uint32(r0)
@@ -112,3 +151,19 @@ func expandEnvironmentStrings(src *uint16, dst *uint16, size uint32) (n uint32,
 	}
 	return
 }
+
+func ntDeleteKey(key syscall.Handle) (ntstatus error) {
+	r0, _, _ := syscall.Syscall(procNtDeleteKey.Addr(), 1, uintptr(key), 0, 0)
+	if r0 != 0 {
+		ntstatus = windows.NTStatus(r0)
+	}
+	return
+}
+
+func ntSetInformationKey(key syscall.Handle, infoClass uint32, info *uint64, infoLen uint32) (ntstatus error) {
+	r0, _, _ := syscall.Syscall6(procNtSetInformationKey.Addr(), 4, uintptr(key), uintptr(infoClass), uintptr(unsafe.Pointer(info)), uintptr(infoLen), 0, 0)
+	if r0 != 0 {
+		ntstatus = windows.NTStatus(r0)
+	}
+	return
+}
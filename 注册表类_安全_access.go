@@ -0,0 +1,122 @@
+package 注册表类
+
+const (
+	// GENERIC_READ 等通用权限在访问检查时按注册表项的通用映射转换为具体权限。
+	GENERIC_READ    = 0x80000000
+	GENERIC_WRITE   = 0x40000000
+	GENERIC_EXECUTE = 0x20000000
+	GENERIC_ALL     = 0x10000000
+
+	// MAXIMUM_ALLOWED 请求调用者能够获得的全部权限。
+	MAXIMUM_ALLOWED = 0x02000000
+
+	key全部权限   = 0xf003f // KEY_ALL_ACCESS
+	key读取权限   = 0x20019 // KEY_READ
+	key写入权限   = 0x20006 // KEY_WRITE
+	key查询值权限  = 0x00001 // KEY_QUERY_VALUE
+	key设置值权限  = 0x00002 // KEY_SET_VALUE
+	key创建子项权限 = 0x00004 // KEY_CREATE_SUB_KEY
+	key枚举子项权限 = 0x00008 // KEY_ENUMERATE_SUB_KEYS
+	key视图标志   = 0x00300 // KEY_WOW64_32KEY | KEY_WOW64_64KEY, 不参与访问检查
+)
+
+// I访问令牌 是模拟的调用者令牌, 用于 I访问检查。
+// SID可以是 "S-1-5-32-544" 形式或SDDL两字母别名。
+// Groups 只应包含已启用的组, Everyone(WD)和Authenticated Users(AU)等也需要显式列出。
+type I访问令牌 struct {
+	User   string
+	Groups []string
+}
+
+// I映射通用权限 把掩码中的 GENERIC_* 权限按注册表项的通用映射转换为具体权限。
+func I映射通用权限(掩码 uint32) uint32 {
+	for _, m := range []struct{ 通用, 具体 uint32 }{
+		{GENERIC_READ, key读取权限},
+		{GENERIC_WRITE, key写入权限},
+		{GENERIC_EXECUTE, key读取权限},
+		{GENERIC_ALL, key全部权限},
+	} {
+		if 掩码&m.通用 != 0 {
+			掩码 = 掩码&^m.通用 | m.具体
+		}
+	}
+	return 掩码
+}
+
+// I访问检查 按Windows AccessCheck的规则, 用安全描述符sd的DACL评估令牌能否获得'期望权限',
+// 返回授予的权限和是否允许, 不允许时授予的权限为0。期望权限中可以包含 GENERIC_* 和 MAXIMUM_ALLOWED,
+// WOW64_32KEY/WOW64_64KEY 会被忽略。
+//
+// 没有DACL或DACL为空指针时允许所有访问; 拒绝项只在对应权限尚未被前面的允许项授予时生效;
+// 所有者隐含READ_CONTROL和WRITE_DAC权限, 除非DACL中有OWNER RIGHTS(OW)项。
+// 不评估特权、强制完整性标签和对象ACE。
+func I访问检查(sd *I安全描述符, 令牌 *I访问令牌, 期望权限 uint32) (授予 uint32, 允许 bool) {
+	期望 := I映射通用权限(期望权限 &^ key视图标志)
+	最大 := 期望&MAXIMUM_ALLOWED != 0
+	期望 &^= MAXIMUM_ALLOWED
+	if sd == nil || sd.DACL == nil {
+		if 最大 {
+			return 期望 | key全部权限, true
+		}
+		return 期望, true
+	}
+
+	sid集合 := map[string]bool{}
+	if 令牌 != nil {
+		for _, s := range append([]string{令牌.User}, 令牌.Groups...) {
+			if 规范, err := sddl解析SID(s); err == nil {
+				sid集合[规范] = true
+			}
+		}
+	}
+
+	var 拒绝 uint32
+	所有者权限 := true
+	for _, ace := range sd.DACL.Entries {
+		if ace.SID == sddl别名["OW"] {
+			所有者权限 = false
+		}
+	}
+	if 所有者权限 && sd.Owner != "" && sid集合[sd.Owner] {
+		授予 |= READ_CONTROL | WRITE_DAC
+	}
+	for _, ace := range sd.DACL.Entries {
+		if ace.Flags&INHERIT_ONLY_ACE != 0 || !sid集合[ace.SID] {
+			continue
+		}
+		掩码 := I映射通用权限(ace.Mask)
+		switch ace.Type {
+		case ACCESS_ALLOWED_ACE_TYPE:
+			授予 |= 掩码 &^ 拒绝
+		case ACCESS_DENIED_ACE_TYPE:
+			拒绝 |= 掩码 &^ 授予
+		}
+		if !最大 && 期望&^授予 == 0 {
+			break
+		}
+	}
+	if 最大 {
+		授予 &= key全部权限
+	} else {
+		授予 &= 期望
+	}
+	if 期望&^授予 != 0 || 最大 && 授予 == 0 {
+		return 0, false
+	}
+	return 授予, true
+}
+
+// I访问检查 用表项的安全描述符评估令牌能否获得'期望权限', 规则与 I访问检查 函数相同。
+// 表项没有安全描述符时允许所有访问。
+func (k *I离线表项) I访问检查(令牌 *I访问令牌, 期望权限 uint32) (uint32, bool, error) {
+	if k == nil || len(k.SecurityDescriptor) == 0 {
+		授予, 允许 := I访问检查(nil, 令牌, 期望权限)
+		return 授予, 允许, nil
+	}
+	sd, err := k.I取安全描述符()
+	if err != nil {
+		return 0, false, err
+	}
+	授予, 允许 := I访问检查(sd, 令牌, 期望权限)
+	return 授予, 允许, nil
+}
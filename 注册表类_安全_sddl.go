@@ -0,0 +1,701 @@
+package 注册表类
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	// DELETE 等是打开注册表项时可以请求的标准访问权限, 与 ALL_ACCESS 等注册表权限组合使用。
+	DELETE       = 0x00010000 //删除注册表项所必需的。
+	READ_CONTROL = 0x00020000 //读取安全描述符中的所有者、主组和DACL所必需的。
+	WRITE_DAC    = 0x00040000 //修改DACL所必需的。
+	WRITE_OWNER  = 0x00080000 //修改所有者所必需的。
+
+	// OWNER_SECURITY_INFORMATION 等常量选择 I取安全描述符 和 I设置安全描述符 读写安全描述符的哪些部分。
+	// 见 https://learn.microsoft.com/en-us/windows/win32/secauthz/security-information
+	OWNER_SECURITY_INFORMATION            = 0x00000001
+	GROUP_SECURITY_INFORMATION            = 0x00000002
+	DACL_SECURITY_INFORMATION             = 0x00000004
+	SACL_SECURITY_INFORMATION             = 0x00000008 // 需要SeSecurityPrivilege特权
+	LABEL_SECURITY_INFORMATION            = 0x00000010
+	PROTECTED_DACL_SECURITY_INFORMATION   = 0x80000000
+	PROTECTED_SACL_SECURITY_INFORMATION   = 0x40000000
+	UNPROTECTED_DACL_SECURITY_INFORMATION = 0x20000000
+	UNPROTECTED_SACL_SECURITY_INFORMATION = 0x10000000
+
+	// SE_OWNER_DEFAULTED 等是安全描述符的控制位。
+	SE_OWNER_DEFAULTED       = 0x0001
+	SE_GROUP_DEFAULTED       = 0x0002
+	SE_DACL_PRESENT          = 0x0004
+	SE_DACL_DEFAULTED        = 0x0008
+	SE_SACL_PRESENT          = 0x0010
+	SE_SACL_DEFAULTED        = 0x0020
+	SE_DACL_AUTO_INHERIT_REQ = 0x0100
+	SE_SACL_AUTO_INHERIT_REQ = 0x0200
+	SE_DACL_AUTO_INHERITED   = 0x0400
+	SE_SACL_AUTO_INHERITED   = 0x0800
+	SE_DACL_PROTECTED        = 0x1000
+	SE_SACL_PROTECTED        = 0x2000
+	SE_SELF_RELATIVE         = 0x8000
+
+	// ACCESS_ALLOWED_ACE_TYPE 等是支持的访问控制项类型。
+	ACCESS_ALLOWED_ACE_TYPE         = 0x00
+	ACCESS_DENIED_ACE_TYPE          = 0x01
+	SYSTEM_AUDIT_ACE_TYPE           = 0x02
+	SYSTEM_ALARM_ACE_TYPE           = 0x03
+	SYSTEM_MANDATORY_LABEL_ACE_TYPE = 0x11
+
+	// OBJECT_INHERIT_ACE 等是访问控制项的标志位。
+	OBJECT_INHERIT_ACE         = 0x01
+	CONTAINER_INHERIT_ACE      = 0x02
+	NO_PROPAGATE_INHERIT_ACE   = 0x04
+	INHERIT_ONLY_ACE           = 0x08
+	INHERITED_ACE              = 0x10
+	SUCCESSFUL_ACCESS_ACE_FLAG = 0x40
+	FAILED_ACCESS_ACE_FLAG     = 0x80
+)
+
+// I安全描述符 是解析后的安全描述符, 可以与自相对格式的二进制数据和SDDL字符串互相转换。
+// SID以字符串形式保存, 例如 "S-1-5-32-544"。
+type I安全描述符 struct {
+	// Control 是 SE_* 控制位。SE_SELF_RELATIVE 总是在生成时设置;
+	// DACL或SACL不为nil时生成的数据会设置对应的 SE_*_PRESENT 位,
+	// DACL为nil而设置了 SE_DACL_PRESENT 表示空DACL(允许所有访问)。
+	Control uint16
+	Owner   string // 为空表示没有所有者
+	Group   string // 为空表示没有主组
+	DACL    *I访问控制列表
+	SACL    *I访问控制列表
+}
+
+// I访问控制列表 是DACL或SACL中的访问控制项列表。
+type I访问控制列表 struct {
+	Entries []I访问控制项
+}
+
+// I访问控制项 是一个ACE。只支持允许、拒绝、审核、警报和强制标签类型。
+type I访问控制项 struct {
+	Type  byte   // ACCESS_ALLOWED_ACE_TYPE 等
+	Flags byte   // OBJECT_INHERIT_ACE 等
+	Mask  uint32 // 访问掩码, 例如 ALL_ACCESS、READ
+	SID   string
+}
+
+// I解析安全描述符 解析自相对格式的二进制安全描述符。
+func I解析安全描述符(数据 []byte) (*I安全描述符, error) {
+	if len(数据) < 20 || 数据[0] != 1 {
+		return nil, errors.New("无效的安全描述符")
+	}
+	控制 := le.Uint16(数据[2:])
+	if 控制&SE_SELF_RELATIVE == 0 {
+		return nil, errors.New("安全描述符不是自相对格式")
+	}
+	sd := &I安全描述符{Control: 控制 &^ SE_SELF_RELATIVE}
+	var err error
+	if o := le.Uint32(数据[4:]); o != 0 {
+		if sd.Owner, _, err = 解析SID(数据, o); err != nil {
+			return nil, err
+		}
+	}
+	if o := le.Uint32(数据[8:]); o != 0 {
+		if sd.Group, _, err = 解析SID(数据, o); err != nil {
+			return nil, err
+		}
+	}
+	if o := le.Uint32(数据[12:]); o != 0 && 控制&SE_SACL_PRESENT != 0 {
+		if sd.SACL, err = 解析ACL(数据, o); err != nil {
+			return nil, err
+		}
+	}
+	if o := le.Uint32(数据[16:]); o != 0 && 控制&SE_DACL_PRESENT != 0 {
+		if sd.DACL, err = 解析ACL(数据, o); err != nil {
+			return nil, err
+		}
+	}
+	return sd, nil
+}
+
+// I生成 把安全描述符编码为自相对格式的二进制数据。
+// 各部分按SACL、DACL、所有者、主组的顺序排列, 与RtlMakeSelfRelativeSD相同。
+func (sd *I安全描述符) I生成() ([]byte, error) {
+	if sd == nil {
+		return nil, errors.New("安全描述符为nil")
+	}
+	控制 := sd.Control | SE_SELF_RELATIVE
+	if sd.DACL != nil {
+		控制 |= SE_DACL_PRESENT
+	}
+	if sd.SACL != nil {
+		控制 |= SE_SACL_PRESENT
+	}
+	数据 := make([]byte, 20)
+	数据[0] = 1
+	le.PutUint16(数据[2:], 控制)
+	for _, 部分 := range []struct {
+		位置  int
+		acl *I访问控制列表
+		sid string
+	}{{12, sd.SACL, ""}, {16, sd.DACL, ""}, {4, nil, sd.Owner}, {8, nil, sd.Group}} {
+		var b []byte
+		var err error
+		switch {
+		case 部分.acl != nil:
+			b, err = 部分.acl.生成()
+		case 部分.sid != "":
+			b, err = I编码SID(部分.sid)
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		le.PutUint32(数据[部分.位置:], uint32(len(数据)))
+		数据 = append(数据, b...)
+	}
+	return 数据, nil
+}
+
+// I取信息标志 返回安全描述符中存在的部分对应的 *_SECURITY_INFORMATION 标志,
+// 用于 I设置安全描述符 没有指定信息标志的情况。
+func (sd *I安全描述符) I取信息标志() uint32 {
+	var 信息 uint32
+	if sd.Owner != "" {
+		信息 |= OWNER_SECURITY_INFORMATION
+	}
+	if sd.Group != "" {
+		信息 |= GROUP_SECURITY_INFORMATION
+	}
+	if sd.DACL != nil || sd.Control&SE_DACL_PRESENT != 0 {
+		信息 |= DACL_SECURITY_INFORMATION
+	}
+	if sd.SACL != nil || sd.Control&SE_SACL_PRESENT != 0 {
+		信息 |= SACL_SECURITY_INFORMATION
+	}
+	return 信息
+}
+
+func 解析ACL(数据 []byte, 偏移 uint32) (*I访问控制列表, error) {
+	if uint64(偏移)+8 > uint64(len(数据)) {
+		return nil, fmt.Errorf("ACL偏移 %#x 超出安全描述符", 偏移)
+	}
+	头 := 数据[偏移:]
+	大小, 数量 := int(le.Uint16(头[2:])), int(le.Uint16(头[4:]))
+	if 大小 < 8 || 大小 > len(头) {
+		return nil, fmt.Errorf("ACL大小 %d 无效", 大小)
+	}
+	acl := &I访问控制列表{Entries: []I访问控制项{}}
+	位置 := 8
+	for i := 0; i < 数量; i++ {
+		if 位置+4 > 大小 {
+			return nil, errors.New("ACE超出ACL")
+		}
+		类型, 标志, ace大小 := 头[位置], 头[位置+1], int(le.Uint16(头[位置+2:]))
+		if ace大小 < 12 || 位置+ace大小 > 大小 {
+			return nil, fmt.Errorf("ACE大小 %d 无效", ace大小)
+		}
+		switch 类型 {
+		case ACCESS_ALLOWED_ACE_TYPE, ACCESS_DENIED_ACE_TYPE, SYSTEM_AUDIT_ACE_TYPE, SYSTEM_ALARM_ACE_TYPE, SYSTEM_MANDATORY_LABEL_ACE_TYPE:
+		default:
+			return nil, fmt.Errorf("不支持的ACE类型 %#x", 类型)
+		}
+		ace := 头[位置 : 位置+ace大小]
+		sid, n, err := 解析SID(ace, 8)
+		if err != nil {
+			return nil, err
+		}
+		if 8+n > ace大小 {
+			return nil, errors.New("ACE中的SID超出ACE")
+		}
+		acl.Entries = append(acl.Entries, I访问控制项{Type: 类型, Flags: 标志, Mask: le.Uint32(ace[4:]), SID: sid})
+		位置 += ace大小
+	}
+	return acl, nil
+}
+
+func (acl *I访问控制列表) 生成() ([]byte, error) {
+	数据 := []byte{2, 0, 0, 0, 0, 0, 0, 0} // ACL_REVISION
+	for _, ace := range acl.Entries {
+		sid, err := I编码SID(ace.SID)
+		if err != nil {
+			return nil, err
+		}
+		b := []byte{ace.Type, ace.Flags, 0, 0}
+		le.PutUint16(b[2:], uint16(8+len(sid)))
+		b = le.AppendUint32(b, ace.Mask)
+		数据 = append(数据, append(b, sid...)...)
+	}
+	if len(数据) > 0xffff {
+		return nil, errors.New("ACL过大")
+	}
+	le.PutUint16(数据[2:], uint16(len(数据)))
+	le.PutUint16(数据[4:], uint16(len(acl.Entries)))
+	return 数据, nil
+}
+
+// 解析SID 解析偏移处的二进制SID, 返回字符串形式和占用的字节数。
+func 解析SID(数据 []byte, 偏移 uint32) (string, int, error) {
+	if uint64(偏移)+8 > uint64(len(数据)) {
+		return "", 0, fmt.Errorf("SID偏移 %#x 超出范围", 偏移)
+	}
+	b := 数据[偏移:]
+	数量 := int(b[1])
+	n := 8 + 4*数量
+	if b[0] != 1 || 数量 > 15 || n > len(b) {
+		return "", 0, errors.New("无效的SID")
+	}
+	var 权威 uint64
+	for _, c := range b[2:8] {
+		权威 = 权威<<8 | uint64(c)
+	}
+	var s strings.Builder
+	if 权威 >= 1<<32 {
+		fmt.Fprintf(&s, "S-1-0x%012X", 权威)
+	} else {
+		fmt.Fprintf(&s, "S-1-%d", 权威)
+	}
+	for i := 0; i < 数量; i++ {
+		fmt.Fprintf(&s, "-%d", le.Uint32(b[8+4*i:]))
+	}
+	return s.String(), n, nil
+}
+
+// I编码SID 把 "S-1-5-32-544" 形式的SID字符串或SDDL中的两字母别名(如 "BA")编码为二进制SID。
+func I编码SID(sid string) ([]byte, error) {
+	if s, ok := sddl别名[strings.ToUpper(sid)]; ok {
+		sid = s
+	}
+	段 := strings.Split(sid, "-")
+	if len(段) < 3 || len(段) > 18 || !strings.EqualFold(段[0], "S") || 段[1] != "1" {
+		return nil, fmt.Errorf("无效的SID %q", sid)
+	}
+	权威, err := strconv.ParseUint(段[2], 0, 48)
+	if err != nil {
+		return nil, fmt.Errorf("无效的SID %q", sid)
+	}
+	b := []byte{1, byte(len(段) - 3), byte(权威 >> 40), byte(权威 >> 32), byte(权威 >> 24), byte(权威 >> 16), byte(权威 >> 8), byte(权威)}
+	for _, s := range 段[3:] {
+		子权威, err := strconv.ParseUint(s, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("无效的SID %q", sid)
+		}
+		b = le.AppendUint32(b, uint32(子权威))
+	}
+	return b, nil
+}
+
+// sddl别名 是SDDL中与域无关的两字母SID别名。
+var sddl别名 = map[string]string{
+	"AN": "S-1-5-7",
+	"AO": "S-1-5-32-548",
+	"AU": "S-1-5-11",
+	"AC": "S-1-15-2-1",
+	"BA": "S-1-5-32-544",
+	"BG": "S-1-5-32-546",
+	"BO": "S-1-5-32-551",
+	"BU": "S-1-5-32-545",
+	"CG": "S-1-3-1",
+	"CO": "S-1-3-0",
+	"CY": "S-1-5-32-569",
+	"ED": "S-1-5-9",
+	"ER": "S-1-5-32-573",
+	"HI": "S-1-16-12288",
+	"IS": "S-1-5-32-568",
+	"IU": "S-1-5-4",
+	"LS": "S-1-5-19",
+	"LU": "S-1-5-32-559",
+	"LW": "S-1-16-4096",
+	"ME": "S-1-16-8192",
+	"MP": "S-1-16-8448",
+	"MU": "S-1-5-32-558",
+	"NO": "S-1-5-32-556",
+	"NS": "S-1-5-20",
+	"NU": "S-1-5-2",
+	"OW": "S-1-3-4",
+	"PO": "S-1-5-32-550",
+	"PS": "S-1-5-10",
+	"PU": "S-1-5-32-547",
+	"RC": "S-1-5-12",
+	"RD": "S-1-5-32-555",
+	"RE": "S-1-5-32-552",
+	"RU": "S-1-5-32-554",
+	"SI": "S-1-16-16384",
+	"SO": "S-1-5-32-549",
+	"SU": "S-1-5-6",
+	"SY": "S-1-5-18",
+	"WD": "S-1-1-0",
+	"WR": "S-1-5-33",
+}
+
+var sddl别名反查 = func() map[string]string {
+	m := make(map[string]string, len(sddl别名))
+	for k, v := range sddl别名 {
+		m[v] = k
+	}
+	return m
+}()
+
+type sddl代码 struct {
+	代码 string
+	值  uint32
+}
+
+var (
+	sddlACE类型 = []sddl代码{
+		{"A", ACCESS_ALLOWED_ACE_TYPE},
+		{"D", ACCESS_DENIED_ACE_TYPE},
+		{"AU", SYSTEM_AUDIT_ACE_TYPE},
+		{"AL", SYSTEM_ALARM_ACE_TYPE},
+		{"ML", SYSTEM_MANDATORY_LABEL_ACE_TYPE},
+	}
+	sddlACE标志 = []sddl代码{
+		{"OI", OBJECT_INHERIT_ACE},
+		{"CI", CONTAINER_INHERIT_ACE},
+		{"NP", NO_PROPAGATE_INHERIT_ACE},
+		{"IO", INHERIT_ONLY_ACE},
+		{"ID", INHERITED_ACE},
+		{"SA", SUCCESSFUL_ACCESS_ACE_FLAG},
+		{"FA", FAILED_ACCESS_ACE_FLAG},
+	}
+	// sddl组合权限 只在掩码完全相等时使用。
+	sddl组合权限 = []sddl代码{
+		{"KA", 0xf003f},
+		{"KR", 0x20019},
+		{"KW", 0x20006},
+		{"KX", 0x20019},
+		{"FA", 0x1f01ff},
+		{"FR", 0x120089},
+		{"FW", 0x120116},
+		{"FX", 0x1200a0},
+	}
+	// sddl权限 的顺序与ConvertSecurityDescriptorToStringSecurityDescriptor的输出一致。
+	sddl权限 = []sddl代码{
+		{"GA", 0x10000000},
+		{"GR", 0x80000000},
+		{"GW", 0x40000000},
+		{"GX", 0x20000000},
+		{"CC", 0x00000001},
+		{"DC", 0x00000002},
+		{"LC", 0x00000004},
+		{"SW", 0x00000008},
+		{"RP", 0x00000010},
+		{"WP", 0x00000020},
+		{"DT", 0x00000040},
+		{"LO", 0x00000080},
+		{"CR", 0x00000100},
+		{"SD", 0x00010000},
+		{"RC", 0x00020000},
+		{"WD", 0x00040000},
+		{"WO", 0x00080000},
+	}
+	sddl标签权限 = []sddl代码{
+		{"NR", 0x1},
+		{"NW", 0x2},
+		{"NX", 0x4},
+	}
+)
+
+// I转SDDL 把安全描述符格式化为SDDL字符串, 例如 "O:BAG:SYD:PAI(A;CI;KA;;;SY)"。
+func (sd *I安全描述符) I转SDDL() string {
+	var s strings.Builder
+	if sd.Owner != "" {
+		s.WriteString("O:" + sddl格式化SID(sd.Owner))
+	}
+	if sd.Group != "" {
+		s.WriteString("G:" + sddl格式化SID(sd.Group))
+	}
+	if sd.DACL != nil || sd.Control&SE_DACL_PRESENT != 0 {
+		s.WriteString("D:")
+		sddl格式化ACL(&s, sd.DACL, sd.Control&SE_DACL_PROTECTED != 0, sd.Control&SE_DACL_AUTO_INHERIT_REQ != 0, sd.Control&SE_DACL_AUTO_INHERITED != 0)
+	}
+	if sd.SACL != nil || sd.Control&SE_SACL_PRESENT != 0 {
+		s.WriteString("S:")
+		sddl格式化ACL(&s, sd.SACL, sd.Control&SE_SACL_PROTECTED != 0, sd.Control&SE_SACL_AUTO_INHERIT_REQ != 0, sd.Control&SE_SACL_AUTO_INHERITED != 0)
+	}
+	return s.String()
+}
+
+func (sd *I安全描述符) String() string {
+	return sd.I转SDDL()
+}
+
+func sddl格式化SID(sid string) string {
+	if 别名, ok := sddl别名反查[sid]; ok {
+		return 别名
+	}
+	return sid
+}
+
+func sddl格式化ACL(s *strings.Builder, acl *I访问控制列表, 保护, 继承请求, 已继承 bool) {
+	if 保护 {
+		s.WriteString("P")
+	}
+	if 继承请求 {
+		s.WriteString("AR")
+	}
+	if 已继承 {
+		s.WriteString("AI")
+	}
+	if acl == nil {
+		s.WriteString("NO_ACCESS_CONTROL")
+		return
+	}
+	for _, ace := range acl.Entries {
+		s.WriteString("(")
+		类型 := fmt.Sprintf("0x%x", ace.Type)
+		for _, c := range sddlACE类型 {
+			if c.值 == uint32(ace.Type) {
+				类型 = c.代码
+			}
+		}
+		s.WriteString(类型 + ";")
+		for _, c := range sddlACE标志 {
+			if uint32(ace.Flags)&c.值 != 0 {
+				s.WriteString(c.代码)
+			}
+		}
+		s.WriteString(";" + sddl格式化权限(ace.Mask, ace.Type == SYSTEM_MANDATORY_LABEL_ACE_TYPE))
+		s.WriteString(";;;" + sddl格式化SID(ace.SID) + ")")
+	}
+}
+
+func sddl格式化权限(掩码 uint32, 标签 bool) string {
+	if 标签 {
+		return sddl拼接权限(掩码, sddl标签权限)
+	}
+	for _, c := range sddl组合权限 {
+		if c.值 == 掩码 {
+			return c.代码
+		}
+	}
+	return sddl拼接权限(掩码, sddl权限)
+}
+
+// sddl拼接权限 用单个权限代码拼出掩码, 有代码无法表示的位时返回十六进制。
+func sddl拼接权限(掩码 uint32, 代码表 []sddl代码) string {
+	var s strings.Builder
+	剩余 := 掩码
+	for _, c := range 代码表 {
+		if 剩余&c.值 != 0 {
+			s.WriteString(c.代码)
+			剩余 &^= c.值
+		}
+	}
+	if 剩余 != 0 || 掩码 == 0 {
+		return fmt.Sprintf("0x%x", 掩码)
+	}
+	return s.String()
+}
+
+// I解析SDDL 解析SDDL字符串。不支持对象ACE、条件ACE和依赖域的SID别名。
+func I解析SDDL(sddl string) (*I安全描述符, error) {
+	sd := &I安全描述符{}
+	剩余 := strings.TrimSpace(sddl)
+	for 剩余 != "" {
+		if len(剩余) < 2 || 剩余[1] != ':' {
+			return nil, fmt.Errorf("SDDL %q 格式无效: %q", sddl, 剩余)
+		}
+		部分, 内容 := 剩余[0], 剩余[2:]
+		结束 := sddl下一部分(内容)
+		剩余 = 内容[结束:]
+		内容 = 内容[:结束]
+		var err error
+		switch 部分 {
+		case 'O':
+			sd.Owner, err = sddl解析SID(内容)
+		case 'G':
+			sd.Group, err = sddl解析SID(内容)
+		case 'D':
+			sd.DACL, err = sddl解析ACL(内容, &sd.Control, SE_DACL_PRESENT, SE_DACL_PROTECTED, SE_DACL_AUTO_INHERIT_REQ, SE_DACL_AUTO_INHERITED)
+		case 'S':
+			sd.SACL, err = sddl解析ACL(内容, &sd.Control, SE_SACL_PRESENT, SE_SACL_PROTECTED, SE_SACL_AUTO_INHERIT_REQ, SE_SACL_AUTO_INHERITED)
+		default:
+			err = fmt.Errorf("未知的SDDL部分 %q", 部分)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return sd, nil
+}
+
+// sddl下一部分 返回下一个 "O:"、"G:"、"D:" 或 "S:" 在s中的位置, ACE括号内的内容除外。
+func sddl下一部分(s string) int {
+	深度 := 0
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '(':
+			深度++
+		case c == ')':
+			深度--
+		case 深度 == 0 && i+1 < len(s) && s[i+1] == ':' && strings.IndexByte("OGDS", c) >= 0:
+			return i
+		}
+	}
+	return len(s)
+}
+
+func sddl解析SID(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	if sid, ok := sddl别名[strings.ToUpper(s)]; ok {
+		return sid, nil
+	}
+	b, err := I编码SID(s)
+	if err != nil {
+		return "", err
+	}
+	sid, _, err := 解析SID(b, 0)
+	return sid, err
+}
+
+func sddl解析ACL(s string, 控制 *uint16, 存在, 保护, 继承请求, 已继承 uint16) (*I访问控制列表, error) {
+	*控制 |= 存在
+	标志 := s
+	if i := strings.IndexByte(s, '('); i >= 0 {
+		标志, s = s[:i], s[i:]
+	} else {
+		s = ""
+	}
+	for 标志 = strings.TrimSpace(标志); 标志 != ""; {
+		switch {
+		case strings.HasPrefix(标志, "NO_ACCESS_CONTROL"):
+			if s != "" {
+				return nil, errors.New("NO_ACCESS_CONTROL 之后不能再有ACE")
+			}
+			标志 = 标志[len("NO_ACCESS_CONTROL"):]
+			return nil, sddl检查剩余标志(标志, 控制, 保护, 继承请求, 已继承)
+		case strings.HasPrefix(标志, "P"):
+			*控制 |= 保护
+			标志 = 标志[1:]
+		case strings.HasPrefix(标志, "AR"):
+			*控制 |= 继承请求
+			标志 = 标志[2:]
+		case strings.HasPrefix(标志, "AI"):
+			*控制 |= 已继承
+			标志 = 标志[2:]
+		default:
+			return nil, fmt.Errorf("未知的ACL标志 %q", 标志)
+		}
+	}
+	acl := &I访问控制列表{Entries: []I访问控制项{}}
+	for s = strings.TrimSpace(s); s != ""; s = strings.TrimSpace(s) {
+		结束 := strings.IndexByte(s, ')')
+		if s[0] != '(' || 结束 < 0 {
+			return nil, fmt.Errorf("无效的ACE %q", s)
+		}
+		ace, err := sddl解析ACE(s[1:结束])
+		if err != nil {
+			return nil, err
+		}
+		acl.Entries = append(acl.Entries, ace)
+		s = s[结束+1:]
+	}
+	return acl, nil
+}
+
+// sddl检查剩余标志 处理写在 NO_ACCESS_CONTROL 之后的 P、AR、AI 标志。
+func sddl检查剩余标志(标志 string, 控制 *uint16, 保护, 继承请求, 已继承 uint16) error {
+	for _, c := range []struct {
+		代码 string
+		位  uint16
+	}{{"P", 保护}, {"AR", 继承请求}, {"AI", 已继承}} {
+		if strings.HasPrefix(标志, c.代码) {
+			*控制 |= c.位
+			标志 = 标志[len(c.代码):]
+		}
+	}
+	if 标志 != "" {
+		return fmt.Errorf("未知的ACL标志 %q", 标志)
+	}
+	return nil
+}
+
+func sddl解析ACE(s string) (I访问控制项, error) {
+	var ace I访问控制项
+	字段 := strings.Split(s, ";")
+	if len(字段) != 6 {
+		return ace, fmt.Errorf("ACE %q 应有6个字段", s)
+	}
+	if 字段[3] != "" || 字段[4] != "" {
+		return ace, fmt.Errorf("ACE %q: 不支持对象ACE", s)
+	}
+	类型, ok := sddl查找代码(sddlACE类型, strings.TrimSpace(字段[0]))
+	if !ok {
+		return ace, fmt.Errorf("ACE %q: 不支持的类型 %q", s, 字段[0])
+	}
+	ace.Type = byte(类型)
+	for 标志 := strings.TrimSpace(字段[1]); 标志 != ""; 标志 = 标志[2:] {
+		v, ok := sddl查找代码(sddlACE标志, 标志[:min(2, len(标志))])
+		if !ok {
+			return ace, fmt.Errorf("ACE %q: 未知的标志 %q", s, 标志)
+		}
+		ace.Flags |= byte(v)
+	}
+	掩码, err := sddl解析权限(strings.TrimSpace(字段[2]), ace.Type == SYSTEM_MANDATORY_LABEL_ACE_TYPE)
+	if err != nil {
+		return ace, fmt.Errorf("ACE %q: %v", s, err)
+	}
+	ace.Mask = 掩码
+	if ace.SID, err = sddl解析SID(字段[5]); err != nil {
+		return ace, fmt.Errorf("ACE %q: %v", s, err)
+	}
+	return ace, nil
+}
+
+func sddl解析权限(s string, 标签 bool) (uint32, error) {
+	if s != "" && s[0] >= '0' && s[0] <= '9' {
+		v, err := strconv.ParseUint(s, 0, 32)
+		return uint32(v), err
+	}
+	var 掩码 uint32
+	for ; s != ""; s = s[2:] {
+		if len(s) < 2 {
+			return 0, fmt.Errorf("未知的权限 %q", s)
+		}
+		v, ok := sddl查找代码(sddl组合权限, s[:2])
+		if !ok {
+			if 标签 {
+				v, ok = sddl查找代码(sddl标签权限, s[:2])
+			} else {
+				v, ok = sddl查找代码(sddl权限, s[:2])
+			}
+		}
+		if !ok {
+			return 0, fmt.Errorf("未知的权限 %q", s[:2])
+		}
+		掩码 |= v
+	}
+	return 掩码, nil
+}
+
+func sddl查找代码(表 []sddl代码, 代码 string) (uint32, bool) {
+	for _, c := range 表 {
+		if c.代码 == 代码 {
+			return c.值, true
+		}
+	}
+	return 0, false
+}
+
+// I取安全描述符 解析表项的 SecurityDescriptor 字段。
+func (k *I离线表项) I取安全描述符() (*I安全描述符, error) {
+	if k == nil {
+		return nil, errors.New("离线表项为nil")
+	}
+	return I解析安全描述符(k.SecurityDescriptor)
+}
+
+// I设置安全描述符 把sd编码后保存到表项的 SecurityDescriptor 字段, 写入配置单元时成为sk单元。
+func (k *I离线表项) I设置安全描述符(sd *I安全描述符) error {
+	if k == nil {
+		return errors.New("离线表项为nil")
+	}
+	数据, err := sd.I生成()
+	if err != nil {
+		return err
+	}
+	k.SecurityDescriptor = 数据
+	return nil
+}
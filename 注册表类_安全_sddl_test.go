@@ -0,0 +1,185 @@
+package 注册表类_test
+
+import (
+	"bytes"
+	"testing"
+
+	注册表类 "e.coding.net/gogit/go/gosdk/core/win_registry_cn"
+)
+
+var sddlTests = []struct {
+	in, out string // out is empty when the formatted string equals in
+}{
+	{in: "O:BAG:SYD:(A;CI;KA;;;SY)(A;CI;KA;;;BA)(A;CI;KR;;;WD)"},
+	{in: "O:SYG:SYD:PAI(A;OICIIOID;GA;;;CO)(A;CIID;KR;;;BU)(D;;KW;;;S-1-5-21-1-2-3-1001)"},
+	{in: "D:NO_ACCESS_CONTROL"},
+	{in: "D:PNO_ACCESS_CONTROL"},
+	{in: "O:BAD:"},
+	{in: "O:BAD:AI(A;;CCDCRC;;;BU)"},
+	{in: "D:(A;;0x80000;;;BU)", out: "D:(A;;WO;;;BU)"},
+	{in: "D:(A;;0x3;;;BU)", out: "D:(A;;CCDC;;;BU)"},
+	{in: "D:(A;;0x400;;;BU)"},
+	{in: "D:(A;;KX;;;BU)", out: "D:(A;;KR;;;BU)"},
+	{in: "G:S-1-5-32-544", out: "G:BA"},
+	{in: "S:(AU;SAFA;KA;;;WD)"},
+	{in: "S:(ML;;NW;;;LW)"},
+	{in: "O:S-1-0x123456789ABC-1"},
+}
+
+func TestSDDLRoundTrip(t *testing.T) {
+	for _, test := range sddlTests {
+		sd, err := 注册表类.I解析SDDL(test.in)
+		if err != nil {
+			t.Errorf("I解析SDDL(%q): %v", test.in, err)
+			continue
+		}
+		want := test.out
+		if want == "" {
+			want = test.in
+		}
+		if got := sd.I转SDDL(); got != want {
+			t.Errorf("I解析SDDL(%q).I转SDDL() = %q, want %q", test.in, got, want)
+		}
+		data, err := sd.I生成()
+		if err != nil {
+			t.Errorf("%q: I生成: %v", test.in, err)
+			continue
+		}
+		sd2, err := 注册表类.I解析安全描述符(data)
+		if err != nil {
+			t.Errorf("%q: I解析安全描述符: %v", test.in, err)
+			continue
+		}
+		if got := sd2.I转SDDL(); got != want {
+			t.Errorf("%q: binary round trip gives %q, want %q", test.in, got, want)
+		}
+		data2, _ := sd2.I生成()
+		if !bytes.Equal(data, data2) {
+			t.Errorf("%q: binary form is not stable", test.in)
+		}
+	}
+}
+
+func TestSDDLBinaryLayout(t *testing.T) {
+	sd, err := 注册表类.I解析SDDL("O:BAG:SYD:(A;;KA;;;WD)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := sd.I生成()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []byte{
+		1, 0, 0x04, 0x80, // revision, control SE_SELF_RELATIVE|SE_DACL_PRESENT
+		0x30, 0, 0, 0, 0x40, 0, 0, 0, 0, 0, 0, 0, 0x14, 0, 0, 0, // owner, group, sacl, dacl
+		2, 0, 0x1c, 0, 1, 0, 0, 0, // ACL header
+		0, 0, 0x14, 0, 0x3f, 0, 0x0f, 0, 1, 1, 0, 0, 0, 0, 0, 1, 0, 0, 0, 0, // ACE for WD
+		1, 2, 0, 0, 0, 0, 0, 5, 32, 0, 0, 0, 0x20, 2, 0, 0, // BA
+		1, 1, 0, 0, 0, 0, 0, 5, 18, 0, 0, 0, // SY
+	}
+	if !bytes.Equal(data, want) {
+		t.Errorf("got\n% x\nwant\n% x", data, want)
+	}
+}
+
+func TestSDDLErrors(t *testing.T) {
+	for _, s := range []string{
+		"X:BA",
+		"O:XX",
+		"D:(A;;KA;;)",
+		"D:(Z;;KA;;;WD)",
+		"D:(A;QQ;KA;;;WD)",
+		"D:(A;;QQ;;;WD)",
+		"D:(OA;;KA;bf967aba-0de6-11d0-a285-00aa003049e2;;WD)",
+		"D:NO_ACCESS_CONTROL(A;;KA;;;WD)",
+		"D:(A;;KA;;;WD",
+	} {
+		if _, err := 注册表类.I解析SDDL(s); err == nil {
+			t.Errorf("I解析SDDL(%q) should fail", s)
+		}
+	}
+	if _, err := 注册表类.I解析安全描述符([]byte{1, 0, 0, 0}); err == nil {
+		t.Error("short security descriptor should fail")
+	}
+}
+
+func TestHiveSecurityDescriptor(t *testing.T) {
+	root := &注册表类.I离线表项{Name: "ROOT", SubKeys: []*注册表类.I离线表项{{Name: "Locked"}, {Name: "Open"}}}
+	locked, _ := 注册表类.I解析SDDL("O:BAG:SYD:P(A;CI;KA;;;SY)")
+	if err := root.I查找("Locked").I设置安全描述符(locked); err != nil {
+		t.Fatal(err)
+	}
+	data, err := 注册表类.I生成配置单元(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := 注册表类.I解析配置单元(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{
+		"Locked": "O:BAG:SYD:P(A;CI;KA;;;SY)",
+		"Open":   "O:BAG:SYD:(A;CI;KA;;;SY)(A;CI;KA;;;BA)(A;CI;KR;;;WD)",
+	} {
+		sd, err := got.I查找(name).I取安全描述符()
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if sd.I转SDDL() != want {
+			t.Errorf("%s: got %q, want %q", name, sd.I转SDDL(), want)
+		}
+	}
+	if r := 注册表类.I校验配置单元(data); r.SecurityDescriptors != 2 {
+		t.Errorf("got %d security descriptors, want 2", r.SecurityDescriptors)
+	}
+}
+
+func TestAccessCheck(t *testing.T) {
+	user := &注册表类.I访问令牌{User: "S-1-5-21-1-2-3-1001", Groups: []string{"WD", "BU"}}
+	admin := &注册表类.I访问令牌{User: "S-1-5-21-1-2-3-500", Groups: []string{"WD", "BA"}}
+	const (
+		read  = 0x20019 // KEY_READ
+		write = 0x20006 // KEY_WRITE
+		all   = 0xf003f // KEY_ALL_ACCESS
+	)
+	tests := []struct {
+		sddl    string
+		token   *注册表类.I访问令牌
+		desired uint32
+		granted uint32
+		ok      bool
+	}{
+		{"O:BAG:SYD:(A;;KA;;;BA)(A;;KR;;;WD)", user, read, read, true},
+		{"O:BAG:SYD:(A;;KA;;;BA)(A;;KR;;;WD)", user, write, 0, false},
+		{"O:BAG:SYD:(A;;KA;;;BA)(A;;KR;;;WD)", admin, all, all, true},
+		{"O:BAG:SYD:(A;;KA;;;BA)(A;;KR;;;WD)", user, 注册表类.GENERIC_READ | 0x100, read, true},
+		{"O:BAG:SYD:(A;;KA;;;BA)(A;;KR;;;WD)", user, 注册表类.MAXIMUM_ALLOWED, read, true},
+		{"O:BAG:SYD:(D;;KW;;;BU)(A;;KA;;;WD)", user, write, 0, false},
+		{"O:BAG:SYD:(A;;KA;;;WD)(D;;KW;;;BU)", user, write, write, true},
+		{"O:BAG:SYD:(A;IO;KA;;;WD)", user, read, 0, false},
+		{"O:BAG:SYD:", user, read, 0, false},
+		{"O:BAG:SYD:NO_ACCESS_CONTROL", user, all, all, true},
+		{"O:S-1-5-21-1-2-3-1001G:SYD:", user, 注册表类.WRITE_DAC, 注册表类.WRITE_DAC, true},
+		{"O:S-1-5-21-1-2-3-1001G:SYD:(A;;KR;;;OW)", user, 注册表类.WRITE_DAC, 0, false},
+	}
+	for _, test := range tests {
+		sd, err := 注册表类.I解析SDDL(test.sddl)
+		if err != nil {
+			t.Fatal(err)
+		}
+		granted, ok := 注册表类.I访问检查(sd, test.token, test.desired)
+		if granted != test.granted || ok != test.ok {
+			t.Errorf("%s for %s desired %#x: got %#x, %v, want %#x, %v",
+				test.sddl, test.token.User, test.desired, granted, ok, test.granted, test.ok)
+		}
+	}
+
+	key := &注册表类.I离线表项{Name: "Locked"}
+	sd, _ := 注册表类.I解析SDDL("O:BAG:SYD:P(A;;KA;;;SY)")
+	if err := key.I设置安全描述符(sd); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, err := key.I访问检查(admin, read); err != nil || ok {
+		t.Errorf("admin read of SYSTEM-only key: ok=%v err=%v", ok, err)
+	}
+}
@@ -0,0 +1,55 @@
+//go:build windows
+// +build windows
+
+package 注册表类
+
+import (
+	"errors"
+	"syscall"
+)
+
+// I取安全描述符 使用RegGetKeySecurity读取注册表对象k的安全描述符。
+// '信息'参数是 *_SECURITY_INFORMATION 标志的组合, 默认读取所有者、主组和DACL;
+// 读取SACL需要SeSecurityPrivilege特权。注册表对象需要以READ_CONTROL(包含在READ中)权限打开。
+func (k *Key结构) I取安全描述符(信息 ...uint32) (*I安全描述符, error) {
+	if k == nil {
+		return nil, errors.New("注册表类对象为nil")
+	}
+	var 信息参数 uint32 = OWNER_SECURITY_INFORMATION | GROUP_SECURITY_INFORMATION | DACL_SECURITY_INFORMATION
+	if len(信息) > 0 {
+		信息参数 = 信息[0]
+	}
+	缓冲区 := make([]byte, 256)
+	for {
+		n := uint32(len(缓冲区))
+		err := regGetKeySecurity(syscall.Handle(k.Key父类), 信息参数, &缓冲区[0], &n)
+		if err == nil {
+			return I解析安全描述符(缓冲区[:n])
+		}
+		if err != syscall.ERROR_INSUFFICIENT_BUFFER {
+			return nil, err
+		}
+		if n <= uint32(len(缓冲区)) {
+			n = uint32(2 * len(缓冲区))
+		}
+		缓冲区 = make([]byte, n)
+	}
+}
+
+// I设置安全描述符 使用RegSetKeySecurity设置注册表对象k的安全描述符。
+// 没有指定'信息'时, 根据sd中存在的部分决定设置所有者、主组、DACL和SACL中的哪些。
+// 修改DACL需要WRITE_DAC权限, 修改所有者需要WRITE_OWNER权限。
+func (k *Key结构) I设置安全描述符(sd *I安全描述符, 信息 ...uint32) error {
+	if k == nil {
+		return errors.New("注册表类对象为nil")
+	}
+	数据, err := sd.I生成()
+	if err != nil {
+		return err
+	}
+	信息参数 := sd.I取信息标志()
+	if len(信息) > 0 {
+		信息参数 = 信息[0]
+	}
+	return regSetKeySecurity(syscall.Handle(k.Key父类), 信息参数, &数据[0])
+}
@@ -0,0 +1,228 @@
+package 注册表类
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// 审计结果
+const (
+	I审计_成功 = "success"
+	I审计_失败 = "failure"
+)
+
+// ErrAuditTampered 当审计日志被修改、删除了记录或被截断时由 I校验审计日志 返回。
+var ErrAuditTampered = errors.New("audit log has been tampered with")
+
+// I审计记录 是审计日志中的一行。Old和New是修改前后的值, 不存在时为nil。
+// Prev是上一条记录的Hash(第一条为空), Hash是Prev与Hash为空时本记录的JSON的SHA-256,
+// 所以修改、插入或删除任何一条记录都会使后面的链接不再匹配。
+type I审计记录 struct {
+	Seq     uint64    `json:"seq"`
+	Time    time.Time `json:"time"`
+	Actor   string    `json:"actor,omitempty"`
+	Reason  string    `json:"reason,omitempty"`
+	Op      string    `json:"op"`
+	Path    string    `json:"path"`
+	Name    string    `json:"name,omitempty"`
+	Old     *I导出值     `json:"old,omitempty"`
+	New     *I导出值     `json:"new,omitempty"`
+	Existed bool      `json:"existed,omitempty"`
+	Outcome string    `json:"outcome"`
+	Error   string    `json:"error,omitempty"`
+	Prev    string    `json:"prev"`
+	Hash    string    `json:"hash"`
+}
+
+// 计算哈希 返回记录的哈希, 忽略记录中已有的Hash。
+func (r *I审计记录) 计算哈希() (string, error) {
+	副本 := *r
+	副本.Hash = ""
+	数据, err := json.Marshal(&副本)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	h.Write([]byte(r.Prev))
+	h.Write(数据)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// I审计日志 把注册表修改追加到哈希链接的JSON Lines文件中。可以被多个会话同时使用。
+// 写入失败后日志进入错误状态, 之后经过它的修改都会被拒绝, 以免出现没有审计记录的修改。
+type I审计日志 struct {
+	文件 *os.File
+	锁  sync.Mutex
+	序号 uint64
+	末尾 string
+	错误 error
+}
+
+// I打开审计日志 打开或创建审计日志文件。已有的记录会先被校验, 新记录接在链的末尾。
+func I打开审计日志(文件名 string) (*I审计日志, error) {
+	f, err := os.OpenFile(文件名, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	结果, err := I校验审计日志(f)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("审计日志 %s: %w", 文件名, err)
+	}
+	return &I审计日志{文件: f, 序号: 结果.Count, 末尾: 结果.LastHash}, nil
+}
+
+// I关闭 关闭审计日志文件。
+func (l *I审计日志) I关闭() error {
+	l.锁.Lock()
+	defer l.锁.Unlock()
+	if l.文件 == nil {
+		return nil
+	}
+	err := l.文件.Close()
+	l.文件 = nil
+	if l.错误 == nil {
+		l.错误 = os.ErrClosed
+	}
+	return err
+}
+
+// I末尾哈希 返回最后一条记录的哈希和记录数量。把它们保存在日志以外的地方,
+// 以后传给 I校验审计日志 就能发现末尾的记录被截断。
+func (l *I审计日志) I末尾哈希() (string, uint64) {
+	l.锁.Lock()
+	defer l.锁.Unlock()
+	return l.末尾, l.序号
+}
+
+// 写入 为记录分配序号并链接到末尾, 然后写入文件并同步到磁盘。
+func (l *I审计日志) 写入(r *I审计记录) error {
+	l.锁.Lock()
+	defer l.锁.Unlock()
+	if l.错误 != nil {
+		return l.错误
+	}
+	r.Seq, r.Prev = l.序号+1, l.末尾
+	var err error
+	if r.Hash, err = r.计算哈希(); err != nil {
+		return err
+	}
+	数据, err := json.Marshal(r)
+	if err == nil {
+		_, err = l.文件.Write(append(数据, '\n'))
+	}
+	if err == nil {
+		err = l.文件.Sync()
+	}
+	if err != nil {
+		l.错误 = fmt.Errorf("写入审计日志: %w", err)
+		return l.错误
+	}
+	l.序号, l.末尾 = r.Seq, r.Hash
+	return nil
+}
+
+// 可写 在修改前检查日志是否还能写入。
+func (l *I审计日志) 可写() error {
+	l.锁.Lock()
+	defer l.锁.Unlock()
+	return l.错误
+}
+
+// I审计拦截器 返回记录修改操作(设置值、删除值、创建表项、删除表项)的拦截器, 读取操作不记录。
+// 修改前的值通过'读取'会话获得, 一般是被包装的内部会话。操作者和原因写入每条记录。
+// 审计记录写入失败时, 修改的错误和写入的错误一起返回。审计 Key结构 上的修改使用 I审计表项。
+func (l *I审计日志) I审计拦截器(读取 I注册表会话, 操作者, 原因 string) I拦截器 {
+	return I拦截函数(func(调用 *I调用记录, 继续 func() error) error {
+		switch 调用.Op {
+		case I操作_设置值, I操作_删除值, I操作_创建表项, I操作_删除表项:
+		default:
+			return 继续()
+		}
+		if err := l.可写(); err != nil {
+			return err
+		}
+		r := &I审计记录{Actor: 操作者, Reason: 原因, Op: 调用.Op, Path: 规范路径(调用.Path), Name: 调用.Name}
+		if 调用.Op == I操作_设置值 || 调用.Op == I操作_删除值 {
+			if 旧, err := 读取.I取值(调用.Path, 调用.Name); err == nil && 旧 != nil {
+				r.Old = 导出值(旧, I导出选项{})
+			}
+		}
+		if 调用.Op == I操作_设置值 {
+			r.New = 导出值(调用.Value, I导出选项{})
+		}
+		err := 继续()
+		r.Time, r.Outcome, r.Existed = time.Now().UTC(), I审计_成功, 调用.Existed
+		if err != nil {
+			r.Outcome, r.Error = I审计_失败, err.Error()
+		}
+		if 写入错误 := l.写入(r); 写入错误 != nil {
+			return errors.Join(err, 写入错误)
+		}
+		return err
+	})
+}
+
+// I审计校验结果 是 I校验审计日志 的结果。
+type I审计校验结果 struct {
+	Count    uint64
+	LastHash string
+}
+
+// I校验审计日志 校验审计日志的每条记录及其链接, 返回记录数量和最后一条记录的哈希。
+// 记录被修改、插入、删除或重排, 或者最后一行不完整时返回包装 ErrAuditTampered 的错误。
+// 给出'期望末尾'(由 I审计日志.I末尾哈希 得到)时, 它必须出现在链中且之后的记录都完好,
+// 否则说明日志被截断。
+func I校验审计日志(r io.Reader, 期望末尾 ...string) (*I审计校验结果, error) {
+	结果 := &I审计校验结果{}
+	找到末尾 := len(期望末尾) == 0 || 期望末尾[0] == ""
+	br := bufio.NewReader(r)
+	for 行号 := 1; ; 行号++ {
+		行, err := br.ReadBytes('\n')
+		if err == io.EOF && len(行) == 0 {
+			break
+		}
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		if err == io.EOF {
+			return nil, fmt.Errorf("第 %d 行不完整: %w", 行号, ErrAuditTampered)
+		}
+		var 记录 I审计记录
+		d := json.NewDecoder(bytes.NewReader(行))
+		d.DisallowUnknownFields()
+		d.UseNumber() // 保持数字的原样, 大于2^53的QWORD也能得到相同的哈希
+		if err := d.Decode(&记录); err != nil {
+			return nil, fmt.Errorf("第 %d 行: %v: %w", 行号, err, ErrAuditTampered)
+		}
+		哈希, err := 记录.计算哈希()
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case 记录.Seq != 结果.Count+1:
+			return nil, fmt.Errorf("第 %d 行的序号是 %d, 应为 %d: %w", 行号, 记录.Seq, 结果.Count+1, ErrAuditTampered)
+		case 记录.Prev != 结果.LastHash:
+			return nil, fmt.Errorf("第 %d 行没有链接到上一条记录: %w", 行号, ErrAuditTampered)
+		case 记录.Hash != 哈希:
+			return nil, fmt.Errorf("第 %d 行的哈希不匹配: %w", 行号, ErrAuditTampered)
+		}
+		结果.Count, 结果.LastHash = 记录.Seq, 记录.Hash
+		if !找到末尾 && 记录.Hash == 期望末尾[0] {
+			找到末尾 = true
+		}
+	}
+	if !找到末尾 {
+		return nil, fmt.Errorf("没有找到期望的末尾记录 %s, 日志被截断: %w", 期望末尾[0], ErrAuditTampered)
+	}
+	return 结果, nil
+}
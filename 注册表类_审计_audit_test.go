@@ -0,0 +1,118 @@
+package 注册表类_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	注册表类 "e.coding.net/gogit/go/gosdk/core/win_registry_cn"
+)
+
+func writeAuditLog(t *testing.T, name string, actor string) string {
+	t.Helper()
+	l, err := 注册表类.I打开审计日志(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.I关闭()
+	inner := testSession(t)
+	s, err := 注册表类.I新建拦截会话(inner, l.I审计拦截器(inner, actor, "CHG-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.I取值(`Software\Contoso`, "Port") // 读取不记录
+	if err := s.I设置值(`Software\Contoso`, dword("Port", 9090)); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.I删除值(`Software\Contoso`, "Missing"); err == nil {
+		t.Fatal("deleting a missing value succeeded")
+	}
+	if _, err := s.I创建表项(`Software\Contoso\D`); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.I删除表项(`Software\Contoso\D`); err != nil {
+		t.Fatal(err)
+	}
+	big, _ := 注册表类.I编码值("Big", uint64(1<<64-1))
+	if err := s.I设置值(`Software\Contoso`, big); err != nil {
+		t.Fatal(err)
+	}
+	head, _ := l.I末尾哈希()
+	return head
+}
+
+func TestAuditLog(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "audit.jsonl")
+	writeAuditLog(t, name, "alice")
+	head := writeAuditLog(t, name, "bob") // 重新打开后接着原来的链
+
+	data, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := 注册表类.I校验审计日志(bytes.NewReader(data), head)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Count != 10 || res.LastHash != head {
+		t.Errorf("verify: got %+v, want 10 records ending in %s", res, head)
+	}
+	lines := strings.SplitAfter(string(data), "\n")
+	for _, want := range []string{
+		`"seq":1,`, `"actor":"alice","reason":"CHG-1","op":"SetValue","path":"Software\\Contoso","name":"Port",` +
+			`"old":{"type":"REG_DWORD","data":8080},"new":{"type":"REG_DWORD","data":9090},"outcome":"success","prev":""`,
+	} {
+		if !strings.Contains(lines[0], want) {
+			t.Errorf("first record %s does not contain %s", lines[0], want)
+		}
+	}
+	if !strings.Contains(lines[1], `"outcome":"failure","error":`) {
+		t.Errorf("failed delete not recorded: %s", lines[1])
+	}
+	if !strings.Contains(lines[5], `"actor":"bob"`) || !strings.Contains(lines[5], `"old":{"type":"REG_DWORD","data":8080}`) {
+		t.Errorf("second session record: %s", lines[5])
+	}
+
+	tampered := map[string]string{
+		"edited":         strings.Replace(string(data), `"data":9090`, `"data":9091`, 1),
+		"line removed":   lines[0] + strings.Join(lines[2:], ""),
+		"lines swapped":  lines[1] + lines[0] + strings.Join(lines[2:], ""),
+		"partial line":   string(data[:len(data)-10]),
+		"tail truncated": strings.Join(lines[:6], ""),
+	}
+	for name, log := range tampered {
+		if _, err := 注册表类.I校验审计日志(strings.NewReader(log), head); !errors.Is(err, 注册表类.ErrAuditTampered) {
+			t.Errorf("%s: got %v, want ErrAuditTampered", name, err)
+		}
+	}
+	// 没有保存末尾哈希时截断末尾的记录无法发现, 但其余记录仍然有效。
+	if res, err := 注册表类.I校验审计日志(strings.NewReader(strings.Join(lines[:6], ""))); err != nil || res.Count != 6 {
+		t.Errorf("truncated without head: got %+v, %v", res, err)
+	}
+
+	if err := os.WriteFile(name, []byte(tampered["edited"]), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := 注册表类.I打开审计日志(name); !errors.Is(err, 注册表类.ErrAuditTampered) {
+		t.Errorf("opening tampered log: got %v", err)
+	}
+}
+
+func TestAuditLogClosed(t *testing.T) {
+	l, err := 注册表类.I打开审计日志(filepath.Join(t.TempDir(), "audit.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	inner := testSession(t)
+	s, _ := 注册表类.I新建拦截会话(inner, l.I审计拦截器(inner, "alice", ""))
+	l.I关闭()
+	if err := s.I设置值(`Software\Contoso`, dword("Port", 1)); !errors.Is(err, os.ErrClosed) {
+		t.Errorf("set after close: got %v", err)
+	}
+	if port, _ := 注册表类.I会话取[uint32](inner, `Software\Contoso`, "Port"); port != 8080 {
+		t.Errorf("unaudited change was made: Port = %d", port)
+	}
+}
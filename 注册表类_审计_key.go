@@ -0,0 +1,15 @@
+//go:build windows
+// +build windows
+
+package 注册表类
+
+// I审计表项 返回注册表对象k的拦截副本, 通过它对 Key结构 的修改都按 I审计拦截器 的规则
+// 记录到审计日志中, 也包括 I应用计划、I写入离线表项、I删除表项树 等在它之上实现的函数。
+// 记录的路径相对于k, 修改前的值通过k上的 I表项会话 读取。返回的对象不拥有k的句柄。
+func (l *I审计日志) I审计表项(k *Key结构, 操作者, 原因 string) (*Key结构, error) {
+	读取, err := I新建表项会话(k)
+	if err != nil {
+		return nil, err
+	}
+	return I启用拦截(k, l.I审计拦截器(读取, 操作者, 原因))
+}
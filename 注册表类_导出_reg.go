@@ -0,0 +1,131 @@
+package 注册表类
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// I导出REG 把变更列表编码为regedit可以导入的.reg文件(Windows Registry Editor Version 5.00,
+// UTF-16LE编码并带BOM)。变更的路径接在'根路径'后面, 根路径应以HKEY_LOCAL_MACHINE等
+// 根项的全名开头。I差异_修改表项 没有对应的.reg语法, 会被忽略。
+func I导出REG(根路径 string, 变更 []I离线差异) []byte {
+	var b strings.Builder
+	b.WriteString("Windows Registry Editor Version 5.00\r\n")
+	当前 := ""
+	for _, d := range 变更 {
+		完整 := hive连接路径(规范路径(根路径), 规范路径(d.Path))
+		switch d.Kind {
+		case I差异_删除表项:
+			fmt.Fprintf(&b, "\r\n[-%s]\r\n", 完整)
+			当前 = ""
+			continue
+		case I差异_新增表项, I差异_新增值, I差异_修改值, I差异_删除值:
+		default:
+			continue
+		}
+		if 当前 != 完整 {
+			fmt.Fprintf(&b, "\r\n[%s]\r\n", 完整)
+			当前 = 完整
+		}
+		switch d.Kind {
+		case I差异_新增值, I差异_修改值:
+			b.WriteString(reg值(d.New))
+		case I差异_删除值:
+			b.WriteString(reg名称(d.Value) + "=-\r\n")
+		}
+	}
+	b.WriteString("\r\n")
+	结果 := []byte{0xff, 0xfe}
+	return append(结果, hive编码UTF16(b.String())...)
+}
+
+func reg名称(名称 string) string {
+	if 名称 == "" {
+		return "@"
+	}
+	return reg引号(名称)
+}
+
+func reg引号(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// reg值 返回一个值在.reg文件中的行。能用字符串或dword形式精确表示的值使用这两种形式,
+// 其他值使用hex形式, 每行不超过80个字符。
+func reg值(v *I离线值) string {
+	前缀 := reg名称(v.Name) + "="
+	switch v.Type {
+	case SZ:
+		if s := 规范数据(v); s != nil && !strings.ContainsAny(s.(string), "\x00\r\n") {
+			if 还原, _ := 编码文本(v.Name, s.(string)); 还原 != nil && bytes.Equal(还原.Data, v.Data) {
+				return 前缀 + reg引号(s.(string)) + "\r\n"
+			}
+		}
+	case DWORD:
+		if len(v.Data) == 4 {
+			return 前缀 + fmt.Sprintf("dword:%08x\r\n", binary.LittleEndian.Uint32(v.Data))
+		}
+	}
+	var b strings.Builder
+	b.WriteString(前缀)
+	if v.Type == BINARY {
+		b.WriteString("hex:")
+	} else {
+		fmt.Fprintf(&b, "hex(%x):", v.Type)
+	}
+	行长 := b.Len()
+	for i, c := range v.Data {
+		项 := fmt.Sprintf("%02x", c)
+		if i < len(v.Data)-1 {
+			项 += ","
+		}
+		if 行长+len(项) > 77 {
+			b.WriteString("\\\r\n  ")
+			行长 = 2
+		}
+		b.WriteString(项)
+		行长 += len(项)
+	}
+	b.WriteString("\r\n")
+	return b.String()
+}
+
+// I报告变更 是 I导出变更JSON 输出的一项变更。
+type I报告变更 struct {
+	Kind string  `json:"kind"`
+	Path string  `json:"path"`
+	Name *string `json:"name,omitempty"`
+	Old  *I导出值   `json:"old,omitempty"`
+	New  *I导出值   `json:"new,omitempty"`
+}
+
+// I导出变更JSON 把变更列表编码为JSON数组, 路径接在'根路径'后面, 值使用 I导出值 的表示。
+func I导出变更JSON(根路径 string, 变更 []I离线差异) ([]byte, error) {
+	报告 := make([]I报告变更, 0, len(变更))
+	for _, d := range 变更 {
+		r := I报告变更{Kind: d.Kind.String(), Path: hive连接路径(规范路径(根路径), 规范路径(d.Path))}
+		switch d.Kind {
+		case I差异_新增值, I差异_删除值, I差异_修改值:
+			名称 := d.Value
+			r.Name = &名称
+		}
+		if d.Old != nil {
+			r.Old = 导出值(d.Old, I导出选项{})
+		}
+		if d.New != nil {
+			r.New = 导出值(d.New, I导出选项{})
+		}
+		报告 = append(报告, r)
+	}
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(报告); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
@@ -0,0 +1,23 @@
+//go:build windows
+// +build windows
+
+package 注册表类
+
+// I导出子树 把注册表对象k下的子项路径及其所有子项导出为JSON或YAML文本, 见 I导出离线表项。
+func I导出子树(k *Key结构, 路径 string, 格式 I导出格式, 选项 ...I导出选项) ([]byte, error) {
+	树, err := I读取离线表项(k, 路径)
+	if err != nil {
+		return nil, err
+	}
+	return I导出离线表项(树, 格式, 选项...)
+}
+
+// I导入子树 把 I导出子树 导出的文本写入注册表对象k下的子项路径。
+// 与 I写入离线表项 一样, 已有的值被覆盖, 文本中没有的值和子项保持不变。
+func I导入子树(k *Key结构, 路径 string, 数据 []byte, 格式 I导出格式) error {
+	树, err := I导入离线表项(数据, 格式)
+	if err != nil {
+		return err
+	}
+	return I写入离线表项(k, 路径, 树)
+}
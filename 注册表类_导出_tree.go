@@ -0,0 +1,252 @@
+package 注册表类
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// I导出格式 是 I导出离线表项 和 I导入离线表项 使用的文本格式。
+type I导出格式 int
+
+const (
+	I格式_JSON I导出格式 = iota
+	I格式_YAML
+)
+
+// I导出选项 控制 I导出离线表项 的输出。
+type I导出选项 struct {
+	// Base64 为true时二进制数据以base64编码, 否则以十六进制编码。导入时两种都接受。
+	Base64 bool
+}
+
+// I导出表项 是表项树的规范文本表示: 值和子项分别是以名称为键的对象, 按名称排序输出。
+// 字段带有json和yaml标签, 也可以直接用其他JSON或YAML库编码。
+type I导出表项 struct {
+	Class  string            `json:"class,omitempty" yaml:"class,omitempty"`
+	Values map[string]*I导出值  `json:"values,omitempty" yaml:"values,omitempty"`
+	Keys   map[string]*I导出表项 `json:"keys,omitempty" yaml:"keys,omitempty"`
+}
+
+// I导出值 是一个值的规范文本表示。Type是 I值类型名称 返回的名称。
+// 数据能按类型的常规编码精确还原时放在Data中: 文本类型为字符串(保存时带结尾的NUL),
+// REG_MULTI_SZ为字符串数组, 整数类型为无符号整数; 否则(包括二进制类型和不规范的数据,
+// 例如没有结尾NUL的REG_SZ)原始字节放在Hex或Base64中。都没有时数据长度为0。
+type I导出值 struct {
+	Type   string `json:"type" yaml:"type"`
+	Data   any    `json:"data,omitempty" yaml:"data,omitempty"`
+	Hex    string `json:"hex,omitempty" yaml:"hex,omitempty"`
+	Base64 string `json:"base64,omitempty" yaml:"base64,omitempty"`
+}
+
+// I转导出表项 把离线表项树转换为规范文本表示。值的数据保证可以由 I转离线表项 逐字节还原。
+// 只保留名称、类名、值和子项, 不包括写入时间、标志和安全描述符。
+func I转导出表项(树 *I离线表项, 选项 ...I导出选项) *I导出表项 {
+	var 选 I导出选项
+	if len(选项) > 0 {
+		选 = 选项[0]
+	}
+	return 转导出表项(树, 选)
+}
+
+func 转导出表项(树 *I离线表项, 选 I导出选项) *I导出表项 {
+	e := &I导出表项{Class: 树.Class}
+	for _, v := range 树.Values {
+		if e.Values == nil {
+			e.Values = map[string]*I导出值{}
+		}
+		e.Values[v.Name] = 导出值(v, 选)
+	}
+	for _, 子 := range 树.SubKeys {
+		if e.Keys == nil {
+			e.Keys = map[string]*I导出表项{}
+		}
+		e.Keys[子.Name] = 转导出表项(子, 选)
+	}
+	return e
+}
+
+func 导出值(v *I离线值, 选 I导出选项) *I导出值 {
+	e := &I导出值{Type: I值类型名称(v.Type)}
+	if len(v.Data) == 0 {
+		return e
+	}
+	if 数据 := 规范数据(v); 数据 != nil {
+		// 只有能逐字节还原时才使用规范编码。
+		if 还原, err := 期望数据(v.Name, v.Type, 数据); err == nil && bytes.Equal(还原.Data, v.Data) {
+			e.Data = 数据
+			return e
+		}
+	}
+	if 选.Base64 {
+		e.Base64 = base64.StdEncoding.EncodeToString(v.Data)
+	} else {
+		e.Hex = hex.EncodeToString(v.Data)
+	}
+	return e
+}
+
+// 规范数据 返回值按类型的常规编码解释出的数据, 没有常规编码的类型返回nil。
+func 规范数据(v *I离线值) any {
+	switch v.Type {
+	case SZ, EXPAND_SZ, LINK:
+		if len(v.Data)%2 != 0 {
+			return nil
+		}
+		s := hive解码UTF16(v.Data)
+		return strings.TrimSuffix(s, "\x00")
+	case MULTI_SZ:
+		a, _ := 解码文本数组(v, 0)
+		return a
+	case DWORD, DWORD_BIG_ENDIAN, QWORD:
+		if n, _, err := 解码数字(v); err == nil {
+			return n
+		}
+	}
+	return nil
+}
+
+// I转离线表项 是 I转导出表项 的逆操作, 树根的名称为'名称'。
+func I转离线表项(名称 string, e *I导出表项) (*I离线表项, error) {
+	if e == nil {
+		return nil, errors.New("注册表类对象为nil")
+	}
+	return 转离线表项(名称, "", e)
+}
+
+func 转离线表项(名称, 路径 string, e *I导出表项) (*I离线表项, error) {
+	if e == nil {
+		return nil, fmt.Errorf("%s: 表项没有内容", 路径)
+	}
+	k := &I离线表项{Name: 名称, Class: e.Class}
+	for _, n := range 排序名称(e.Values) {
+		v, err := 导入值(n, e.Values[n])
+		if err == nil && k.I取值(n) != nil {
+			err = fmt.Errorf("值 %q 重复", n)
+		}
+		if err == nil {
+			err = k.I设置值(v)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", 路径, err)
+		}
+	}
+	for _, n := range 排序名称(e.Keys) {
+		err := hive检查名称(n, false)
+		if err == nil && k.I取子项(n) != nil {
+			err = fmt.Errorf("子项 %q 重复", n)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", 路径, err)
+		}
+		子, err := 转离线表项(n, hive连接路径(路径, n), e.Keys[n])
+		if err != nil {
+			return nil, err
+		}
+		k.SubKeys = append(k.SubKeys, 子)
+	}
+	return k, nil
+}
+
+func 排序名称[V any](m map[string]V) []string {
+	名称 := make([]string, 0, len(m))
+	for n := range m {
+		名称 = append(名称, n)
+	}
+	sort.Strings(名称)
+	return 名称
+}
+
+func 导入值(名称 string, e *I导出值) (*I离线值, error) {
+	if e == nil {
+		return nil, fmt.Errorf("值 %q 没有内容", 名称)
+	}
+	值类型, err := I解析值类型(e.Type)
+	if err != nil {
+		return nil, fmt.Errorf("值 %q: %w", 名称, err)
+	}
+	数量 := 0
+	for _, 有 := range []bool{e.Data != nil, e.Hex != "", e.Base64 != ""} {
+		if 有 {
+			数量++
+		}
+	}
+	var 数据 []byte
+	switch {
+	case 数量 > 1:
+		return nil, fmt.Errorf("值 %q 只能有data、hex和base64中的一个", 名称)
+	case e.Hex != "":
+		数据, err = 期望字节(e.Hex)
+	case e.Base64 != "":
+		数据, err = base64.StdEncoding.DecodeString(e.Base64)
+	case e.Data != nil:
+		var v *I离线值
+		if v, err = 期望数据(名称, 值类型, e.Data); err == nil {
+			数据 = v.Data
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("值 %q: %w", 名称, err)
+	}
+	return &I离线值{Name: 名称, Type: 值类型, Data: 数据}, nil
+}
+
+// I导出离线表项 把离线表项树编码为JSON或YAML文本, 键按名称排序, 相同的树总是得到相同的文本。
+func I导出离线表项(树 *I离线表项, 格式 I导出格式, 选项 ...I导出选项) ([]byte, error) {
+	if 树 == nil {
+		return nil, errors.New("注册表类对象为nil")
+	}
+	e := I转导出表项(树, 选项...)
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(e); err != nil {
+		return nil, err
+	}
+	switch 格式 {
+	case I格式_JSON:
+		return b.Bytes(), nil
+	case I格式_YAML:
+		var 通用 any
+		d := json.NewDecoder(&b)
+		d.UseNumber()
+		if err := d.Decode(&通用); err != nil {
+			return nil, err
+		}
+		return yaml编码(通用), nil
+	}
+	return nil, fmt.Errorf("未知的导出格式 %d", 格式)
+}
+
+// I导入离线表项 解析 I导出离线表项 生成的JSON或YAML文本, 返回名称为空的树根。
+// YAML只支持手工编辑时常用的子集: 块映射和块序列、各种引号的标量、只含标量的流序列和注释,
+// 不支持锚点、标签和多行标量。
+func I导入离线表项(数据 []byte, 格式 I导出格式) (*I离线表项, error) {
+	switch 格式 {
+	case I格式_JSON:
+	case I格式_YAML:
+		通用, err := yaml解码(数据)
+		if err != nil {
+			return nil, err
+		}
+		if 数据, err = json.Marshal(通用); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("未知的导出格式 %d", 格式)
+	}
+	d := json.NewDecoder(bytes.NewReader(数据))
+	d.UseNumber()
+	d.DisallowUnknownFields()
+	var e I导出表项
+	if err := d.Decode(&e); err != nil {
+		return nil, fmt.Errorf("解析导出数据: %w", err)
+	}
+	return I转离线表项("", &e)
+}
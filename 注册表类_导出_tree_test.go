@@ -0,0 +1,156 @@
+package 注册表类_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	注册表类 "e.coding.net/gogit/go/gosdk/core/win_registry_cn"
+)
+
+func testExportTree() *注册表类.I离线表项 {
+	return &注册表类.I离线表项{Name: "App", Class: "cls", Values: []*注册表类.I离线值{
+		{Name: "", Type: 注册表类.SZ},
+		sz("Text", "héllo \"quoted\" # not a comment"),
+		sz("yes", "true"),
+		{Name: "NoNul", Type: 注册表类.SZ, Data: []byte{'a', 0, 'b', 0}},
+		{Name: "Odd", Type: 注册表类.EXPAND_SZ, Data: []byte{'a', 0, 0}},
+		{Name: "Multi", Type: 注册表类.MULTI_SZ, Data: []byte("a\x00\x00\x00b\x00\x00\x00\x00\x00")},
+		{Name: "EmptyMulti", Type: 注册表类.MULTI_SZ, Data: []byte{0, 0}},
+		dword("Level", 0xffffffff),
+		{Name: "BE", Type: 注册表类.DWORD_BIG_ENDIAN, Data: []byte{1, 2, 3, 4}},
+		{Name: "Q", Type: 注册表类.QWORD, Data: []byte{1, 2, 3, 4, 5, 6, 7, 0xff}},
+		{Name: "ShortDword", Type: 注册表类.DWORD, Data: []byte{1, 2}},
+		{Name: "Blob", Type: 注册表类.BINARY, Data: []byte{0xde, 0xad, 0xbe, 0xef}},
+		{Name: "None", Type: 注册表类.NONE},
+		{Name: "Weird", Type: 0x1234, Data: []byte{9}},
+	}, SubKeys: []*注册表类.I离线表项{
+		{Name: "Child: 1", SubKeys: []*注册表类.I离线表项{{Name: "Empty"}}},
+	}}
+}
+
+func sameTree(t *testing.T, want, got *注册表类.I离线表项) {
+	t.Helper()
+	for _, w := range want.Values {
+		g := got.I取值(w.Name)
+		if g == nil || g.Name != w.Name || g.Type != w.Type || !bytes.Equal(g.Data, w.Data) || len(g.Data) != len(w.Data) {
+			t.Errorf("value %q: got %+v, want %+v", w.Name, g, w)
+		}
+	}
+	if len(got.Values) != len(want.Values) || len(got.SubKeys) != len(want.SubKeys) || got.Class != want.Class {
+		t.Errorf("key %q: got %d values, %d subkeys, class %q", want.Name, len(got.Values), len(got.SubKeys), got.Class)
+	}
+	for _, w := range want.SubKeys {
+		if g := got.I取子项(w.Name); g == nil {
+			t.Errorf("missing subkey %q", w.Name)
+		} else {
+			sameTree(t, w, g)
+		}
+	}
+}
+
+func TestExportRoundTrip(t *testing.T) {
+	tree := testExportTree()
+	for _, tt := range []struct {
+		name   string
+		format 注册表类.I导出格式
+		opts   注册表类.I导出选项
+	}{
+		{"json", 注册表类.I格式_JSON, 注册表类.I导出选项{}},
+		{"json-base64", 注册表类.I格式_JSON, 注册表类.I导出选项{Base64: true}},
+		{"yaml", 注册表类.I格式_YAML, 注册表类.I导出选项{}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := 注册表类.I导出离线表项(tree, tt.format, tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := 注册表类.I导入离线表项(data, tt.format)
+			if err != nil {
+				t.Fatalf("%v\n%s", err, data)
+			}
+			got.Name = tree.Name
+			sameTree(t, tree, got)
+			again, _ := 注册表类.I导出离线表项(got, tt.format, tt.opts)
+			if !bytes.Equal(data, again) {
+				t.Errorf("export is not canonical:\n%s\n---\n%s", data, again)
+			}
+		})
+	}
+
+	data, _ := 注册表类.I导出离线表项(tree, 注册表类.I格式_JSON)
+	for _, s := range []string{
+		`"": {`, `"type": "REG_SZ"`, `"data": 4294967295`, `"hex": "deadbeef"`, `"type": "REG_0x1234"`,
+		`"data": [`, `"type": "REG_DWORD_BIG_ENDIAN"`, `"data": 16909060`, `"hex": "61006200"`, `"hex": "610000"`,
+	} {
+		if !bytes.Contains(data, []byte(s)) {
+			t.Errorf("JSON export missing %s:\n%s", s, data)
+		}
+	}
+}
+
+func TestImportHandWrittenYAML(t *testing.T) {
+	doc := `---
+# reviewed settings
+class: 'it''s'
+values:
+  "":
+    type: REG_SZ
+    data: ""
+  Level:
+    type: dword
+    data: 0x10  # hex is fine
+  Hosts:
+    type: REG_MULTI_SZ
+    data: ["a", 'b', c]
+  Paths:
+    type: REG_MULTI_SZ
+    data:
+    - "C:\\Windows"
+    - D:\Data
+  Blob: {type: REG_BINARY}
+keys:
+  Child:
+    keys:
+      Empty: {}
+`
+	if _, err := 注册表类.I导入离线表项([]byte(doc), 注册表类.I格式_YAML); err == nil || !strings.Contains(err.Error(), "不支持") {
+		t.Errorf("flow mapping: got %v, want unsupported error", err)
+	}
+	doc = strings.Replace(doc, "  Blob: {type: REG_BINARY}\n", "  Blob:\n    type: REG_BINARY\n", 1)
+	k, err := 注册表类.I导入离线表项([]byte(doc), 注册表类.I格式_YAML)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if k.Class != "it's" || text(k.I取值("")) != "" || len(k.I取值("").Data) != 2 {
+		t.Errorf("got class %q, default %+v", k.Class, k.I取值(""))
+	}
+	if n, err := 注册表类.I解码值[uint32](k.I取值("Level")); err != nil || n != 16 {
+		t.Errorf("Level: got %d, %v", n, err)
+	}
+	for name, want := range map[string][]string{"Hosts": {"a", "b", "c"}, "Paths": {`C:\Windows`, `D:\Data`}} {
+		got, err := 注册表类.I解码值[[]string](k.I取值(name))
+		if err != nil || strings.Join(got, "|") != strings.Join(want, "|") {
+			t.Errorf("%s: got %q, %v", name, got, err)
+		}
+	}
+	if v := k.I取值("Blob"); v == nil || len(v.Data) != 0 {
+		t.Errorf("Blob: got %+v", v)
+	}
+	if k.I查找(`Child\Empty`) == nil {
+		t.Error(`missing Child\Empty`)
+	}
+
+	for _, bad := range []string{
+		"values:\n  a:\n    type: REG_SZ\n   data: x\n",
+		"values:\n  a:\n    type: REG_SZ\n    data: |\n      x\n",
+		"values:\n  a:\n    type: REG_SZ\n    hex: 00\n    data: x\n",
+		"values:\n  a:\n    type: REG_SZ\n    unknown: 1\n",
+		"keys:\n  a: {}\n  a: {}\n",
+		"keys:\n  a: {}\n  A: {}\n",
+	} {
+		if _, err := 注册表类.I导入离线表项([]byte(bad), 注册表类.I格式_YAML); err == nil {
+			t.Errorf("%q: want error", bad)
+		}
+	}
+}
@@ -0,0 +1,397 @@
+package 注册表类
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// yaml编码 把由JSON解码出的通用值(map[string]any、[]any、string、json.Number、bool和nil)
+// 编码为块格式的YAML。字符串总是加双引号, 避免被解释为数字或布尔值。
+func yaml编码(v any) []byte {
+	var b bytes.Buffer
+	switch x := v.(type) {
+	case map[string]any:
+		if len(x) == 0 {
+			b.WriteString("{}\n")
+		} else {
+			yaml编码映射(&b, x, 0)
+		}
+	case []any:
+		if len(x) == 0 {
+			b.WriteString("[]\n")
+		} else {
+			yaml编码序列(&b, x, 0)
+		}
+	default:
+		b.WriteString(yaml标量(v))
+		b.WriteByte('\n')
+	}
+	return b.Bytes()
+}
+
+func yaml编码映射(b *bytes.Buffer, m map[string]any, 缩进 int) {
+	键 := make([]string, 0, len(m))
+	for k := range m {
+		键 = append(键, k)
+	}
+	sort.Strings(键)
+	for _, k := range 键 {
+		b.WriteString(strings.Repeat(" ", 缩进))
+		b.WriteString(yaml键(k))
+		b.WriteByte(':')
+		yaml编码子值(b, m[k], 缩进)
+	}
+}
+
+func yaml编码序列(b *bytes.Buffer, a []any, 缩进 int) {
+	for _, v := range a {
+		b.WriteString(strings.Repeat(" ", 缩进))
+		b.WriteByte('-')
+		yaml编码子值(b, v, 缩进)
+	}
+}
+
+func yaml编码子值(b *bytes.Buffer, v any, 缩进 int) {
+	switch x := v.(type) {
+	case map[string]any:
+		if len(x) > 0 {
+			b.WriteByte('\n')
+			yaml编码映射(b, x, 缩进+2)
+			return
+		}
+		b.WriteString(" {}\n")
+	case []any:
+		if len(x) > 0 {
+			b.WriteByte('\n')
+			yaml编码序列(b, x, 缩进+2)
+			return
+		}
+		b.WriteString(" []\n")
+	default:
+		b.WriteByte(' ')
+		b.WriteString(yaml标量(v))
+		b.WriteByte('\n')
+	}
+}
+
+var yaml普通键 = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]*$`)
+
+// yaml键 返回映射的键, 不会被误解的名称不加引号。
+func yaml键(k string) string {
+	switch strings.ToLower(k) {
+	case "y", "n", "yes", "no", "on", "off":
+		return yaml引号(k) // YAML 1.1 中的布尔值
+	}
+	if yaml普通键.MatchString(k) && yaml普通标量(k) == k {
+		return k
+	}
+	return yaml引号(k)
+}
+
+func yaml标量(v any) string {
+	switch x := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return strconv.FormatBool(x)
+	case json.Number:
+		return x.String()
+	case string:
+		return yaml引号(x)
+	}
+	return yaml引号(fmt.Sprint(v))
+}
+
+// yaml引号 返回双引号字符串。JSON字符串的转义也是YAML双引号字符串的合法转义。
+func yaml引号(s string) string {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// yaml行 是去掉注释后的一个非空行。
+type yaml行 struct {
+	号  int
+	缩进 int
+	文本 string
+}
+
+// yaml解码 解析YAML的一个常用子集, 返回与JSON解码相同形式的通用值:
+// 以空格缩进的块映射和块序列(元素可以是紧凑的 - 键: 值 形式), 单引号、双引号和普通标量, 只含标量的流序列 [a, b],
+// 空的流映射 {}, 注释和文档开始标记 ---。不支持锚点、标签、多行标量和多个文档。
+// 普通标量中的整数和浮点数解码为 json.Number, true/false 为bool, null 和 ~ 为nil。
+func yaml解码(数据 []byte) (any, error) {
+	var 行 []yaml行
+	for i, s := range strings.Split(strings.ReplaceAll(string(数据), "\r\n", "\n"), "\n") {
+		s = strings.TrimRight(yaml去注释(s), " ")
+		去缩进 := strings.TrimLeft(s, " ")
+		if 去缩进 == "" || i == 0 && 去缩进 == "---" {
+			continue
+		}
+		if strings.HasPrefix(去缩进, "\t") {
+			return nil, fmt.Errorf("yaml: 第%d行: 不能用制表符缩进", i+1)
+		}
+		if 去缩进 == "---" || 去缩进 == "..." {
+			return nil, fmt.Errorf("yaml: 第%d行: 不支持多个文档", i+1)
+		}
+		行 = append(行, yaml行{号: i + 1, 缩进: len(s) - len(去缩进), 文本: 去缩进})
+	}
+	if len(行) == 0 {
+		return nil, nil
+	}
+	p := &yaml解析器{行: 行}
+	v, err := p.块(行[0].缩进)
+	if err != nil {
+		return nil, err
+	}
+	if p.位置 < len(p.行) {
+		return nil, p.错误("缩进不正确")
+	}
+	return v, nil
+}
+
+// yaml去注释 去掉引号外以#开始的注释。
+func yaml去注释(s string) string {
+	var 引号 byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 引号 == '"' && c == '\\':
+			i++
+		case 引号 != 0:
+			if c == 引号 {
+				引号 = 0
+			}
+		case c == '"' || c == '\'':
+			引号 = c
+		case c == '#' && (i == 0 || s[i-1] == ' '):
+			return s[:i]
+		}
+	}
+	return s
+}
+
+type yaml解析器 struct {
+	行  []yaml行
+	位置 int
+}
+
+func (p *yaml解析器) 错误(格式 string, 参数 ...any) error {
+	号 := 0
+	if p.位置 < len(p.行) {
+		号 = p.行[p.位置].号
+	} else if len(p.行) > 0 {
+		号 = p.行[len(p.行)-1].号
+	}
+	return fmt.Errorf("yaml: 第%d行: %s", 号, fmt.Sprintf(格式, 参数...))
+}
+
+// 块 解析从当前行开始、缩进为'缩进'的块映射、块序列或单独的标量。
+func (p *yaml解析器) 块(缩进 int) (any, error) {
+	l := p.行[p.位置]
+	if l.缩进 != 缩进 {
+		return nil, p.错误("缩进不正确")
+	}
+	if l.文本 == "-" || strings.HasPrefix(l.文本, "- ") {
+		return p.序列(缩进)
+	}
+	if _, _, ok := yaml拆分键(l.文本); ok {
+		return p.映射(缩进)
+	}
+	p.位置++
+	return yaml解析值(l.文本)
+}
+
+func (p *yaml解析器) 映射(缩进 int) (any, error) {
+	m := map[string]any{}
+	for p.位置 < len(p.行) && p.行[p.位置].缩进 == 缩进 {
+		键, 值, ok := yaml拆分键(p.行[p.位置].文本)
+		if !ok {
+			return nil, p.错误("应该是 键: 值")
+		}
+		if _, 重复 := m[键]; 重复 {
+			return nil, p.错误("键 %q 重复", 键)
+		}
+		p.位置++
+		v, err := p.子值(缩进, 值, true)
+		if err != nil {
+			return nil, err
+		}
+		m[键] = v
+	}
+	return m, nil
+}
+
+func (p *yaml解析器) 序列(缩进 int) (any, error) {
+	a := []any{}
+	for p.位置 < len(p.行) && p.行[p.位置].缩进 == 缩进 {
+		l := p.行[p.位置]
+		if l.文本 != "-" && !strings.HasPrefix(l.文本, "- ") {
+			break
+		}
+		// 紧凑的 - 键: 值 元素是从键的位置开始的块映射。
+		内容 := strings.TrimLeft(l.文本[1:], " ")
+		if _, _, ok := yaml拆分键(内容); ok && !strings.HasPrefix(内容, "{") && !strings.HasPrefix(内容, "[") {
+			键缩进 := 缩进 + len(l.文本) - len(内容)
+			p.行[p.位置] = yaml行{号: l.号, 缩进: 键缩进, 文本: 内容}
+			v, err := p.映射(键缩进)
+			if err != nil {
+				return nil, err
+			}
+			a = append(a, v)
+			continue
+		}
+		p.位置++
+		v, err := p.子值(缩进, strings.TrimSpace(l.文本[1:]), false)
+		if err != nil {
+			return nil, err
+		}
+		a = append(a, v)
+	}
+	return a, nil
+}
+
+// 子值 解析映射的值或序列的元素: 同一行有内容时是标量或流集合, 否则是下面缩进更多的块。
+// 映射的值也可以是与键缩进相同的块序列。
+func (p *yaml解析器) 子值(缩进 int, 文本 string, 是映射 bool) (any, error) {
+	if 文本 != "" {
+		return yaml解析值(文本)
+	}
+	if p.位置 < len(p.行) {
+		l := p.行[p.位置]
+		if l.缩进 > 缩进 || 是映射 && l.缩进 == 缩进 && (l.文本 == "-" || strings.HasPrefix(l.文本, "- ")) {
+			return p.块(l.缩进)
+		}
+	}
+	return nil, nil
+}
+
+// yaml拆分键 把 键: 值 拆分为键和值, 键可以加引号。
+func yaml拆分键(s string) (string, string, bool) {
+	if s != "" && (s[0] == '"' || s[0] == '\'') {
+		n := yaml引号长度(s)
+		if n < 0 || n == len(s) || s[n] != ':' || n+1 < len(s) && s[n+1] != ' ' {
+			return "", "", false
+		}
+		k, err := yaml解析引号(s[:n])
+		if err != nil {
+			return "", "", false
+		}
+		return k, strings.TrimSpace(s[n+1:]), true
+	}
+	if i := strings.Index(s, ": "); i > 0 {
+		return s[:i], strings.TrimSpace(s[i+2:]), true
+	}
+	if strings.HasSuffix(s, ":") && len(s) > 1 {
+		return s[:len(s)-1], "", true
+	}
+	return "", "", false
+}
+
+// yaml引号长度 返回s开头的引号字符串的长度, 没有结束引号时返回-1。
+func yaml引号长度(s string) int {
+	q := s[0]
+	for i := 1; i < len(s); i++ {
+		switch {
+		case q == '"' && s[i] == '\\':
+			i++
+		case s[i] == q && q == '\'' && i+1 < len(s) && s[i+1] == '\'':
+			i++
+		case s[i] == q:
+			return i + 1
+		}
+	}
+	return -1
+}
+
+func yaml解析引号(s string) (string, error) {
+	if s[0] == '\'' {
+		return strings.ReplaceAll(s[1:len(s)-1], "''", "'"), nil
+	}
+	var v string
+	if err := json.Unmarshal([]byte(s), &v); err == nil {
+		return v, nil
+	}
+	return strconv.Unquote(s)
+}
+
+// yaml解析值 解析同一行中的标量或流集合。
+func yaml解析值(s string) (any, error) {
+	switch {
+	case s == "{}":
+		return map[string]any{}, nil
+	case strings.HasPrefix(s, "["):
+		if !strings.HasSuffix(s, "]") {
+			return nil, fmt.Errorf("yaml: 流序列 %s 没有结束", s)
+		}
+		a := []any{}
+		内容 := strings.TrimSpace(s[1 : len(s)-1])
+		for 内容 != "" {
+			n := strings.IndexByte(内容, ',')
+			if 内容[0] == '"' || 内容[0] == '\'' {
+				if n = yaml引号长度(内容); n < 0 {
+					return nil, fmt.Errorf("yaml: %s 中的引号没有结束", s)
+				}
+			} else if n < 0 {
+				n = len(内容)
+			}
+			v, err := yaml解析值(strings.TrimSpace(内容[:n]))
+			if err != nil {
+				return nil, err
+			}
+			a = append(a, v)
+			内容 = strings.TrimSpace(内容[n:])
+			if 内容 != "" {
+				if 内容[0] != ',' {
+					return nil, fmt.Errorf("yaml: %s 中缺少逗号", s)
+				}
+				内容 = strings.TrimSpace(内容[1:])
+			}
+		}
+		return a, nil
+	case strings.HasPrefix(s, "{"), strings.HasPrefix(s, "&"), strings.HasPrefix(s, "*"),
+		strings.HasPrefix(s, "!"), strings.HasPrefix(s, "|"), strings.HasPrefix(s, ">"):
+		return nil, fmt.Errorf("yaml: 不支持 %s", s)
+	case s[0] == '"' || s[0] == '\'':
+		if yaml引号长度(s) != len(s) {
+			return nil, fmt.Errorf("yaml: 引号字符串 %s 不正确", s)
+		}
+		return yaml解析引号(s)
+	}
+	return yaml解释普通标量(s), nil
+}
+
+var yaml数字 = regexp.MustCompile(`^[-+]?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][-+]?[0-9]+)?$`)
+
+func yaml解释普通标量(s string) any {
+	switch s {
+	case "null", "Null", "NULL", "~":
+		return nil
+	case "true", "True", "TRUE":
+		return true
+	case "false", "False", "FALSE":
+		return false
+	}
+	if yaml数字.MatchString(s) {
+		return json.Number(strings.TrimPrefix(s, "+"))
+	}
+	if n, err := strconv.ParseUint(s, 0, 64); err == nil && (strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0o")) {
+		return json.Number(strconv.FormatUint(n, 10))
+	}
+	return s
+}
+
+// yaml普通标量 返回s作为普通标量时被解释出的字符串, 会被解释为其他类型时返回空字符串。
+func yaml普通标量(s string) string {
+	if v, ok := yaml解释普通标量(s).(string); ok {
+		return v
+	}
+	return ""
+}
@@ -0,0 +1,383 @@
+package 注册表类
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"runtime"
+	"slices"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// I注册表会话 是以路径访问一个根项下的表项的接口, 路径相对于根项。
+// Windows上由 I录制器 实现, 它访问真实的注册表并把每次调用记录到录像带中;
+// 任何平台上都可以用 I回放器 按录像带回放这些调用。需要在Linux上测试的代码应该
+// 通过这个接口访问注册表, 并用 errors.Is(err, fs.ErrNotExist) 之类的方式判断错误,
+// 这样录制和回放时的行为一致。
+//
+// I打开表项 以'访问权限'打开表项并立即关闭, 用于检查表项存在并且可以以该权限访问;
+// I创建表项 的'访问权限'是调用方打算使用的权限, 没有给出时使用CREATE_SUB_KEY。
+type I注册表会话 interface {
+	I打开表项(路径 string, 访问权限 uint32) error
+	I取值(路径, 名称 string) (*I离线值, error)
+	I列出值(路径 string) ([]*I离线值, error)
+	I列出子项(路径 string) ([]string, error)
+	I设置值(路径 string, 值 *I离线值) error
+	I删除值(路径, 名称 string) error
+	I创建表项(路径 string, 访问权限 ...uint32) (是否已存在 bool, err error)
+	I删除表项(路径 string) error
+	I取信息(路径 string) (*I表项统计, error)
+}
+
+// I表项统计 是 I注册表会话.I取信息 返回的表项信息。Link为true时路径最后一段是符号链接表项,
+// 其他字段描述的是链接表项本身而不是目标; 删除表项树时只删除链接, 不进入目标。
+type I表项统计 struct {
+	Class       string    `json:"class,omitempty"`
+	SubKeyCount int       `json:"subKeys"`
+	ValueCount  int       `json:"values"`
+	ModTime     time.Time `json:"modTime"`
+	Link        bool      `json:"link,omitempty"`
+}
+
+// I会话取 读取会话中路径指向的表项的值, 并按 I解码值 的规则转换为类型T。
+func I会话取[T any](s I注册表会话, 路径, 名称 string, 转换 ...I转换) (T, error) {
+	var 零值 T
+	if s == nil {
+		return 零值, errors.New("注册表类对象为nil")
+	}
+	v, err := s.I取值(路径, 名称)
+	if err != nil {
+		return 零值, err
+	}
+	return I解码值[T](v, 转换...)
+}
+
+// 会话操作的名称, 也是录像带中记录的Op。
+const (
+	I操作_打开表项 = "OpenKey"
+	I操作_取值   = "GetValue"
+	I操作_列出值  = "EnumValues"
+	I操作_列出子项 = "EnumKeys"
+	I操作_设置值  = "SetValue"
+	I操作_删除值  = "DeleteValue"
+	I操作_创建表项 = "CreateKey"
+	I操作_删除表项 = "DeleteKey"
+	I操作_取信息  = "Stat"
+)
+
+// 可选权限 返回可选的'访问权限'参数, 没有给出时返回0。
+func 可选权限(访问权限 []uint32) uint32 {
+	if len(访问权限) > 0 {
+		return 访问权限[0]
+	}
+	return 0
+}
+
+// ErrReplayMismatch 当回放时的调用与录像带不符时返回。
+var ErrReplayMismatch = errors.New("registry call does not match cassette")
+
+// I录像带 是录制的注册表调用序列, 以JSON格式保存。
+type I录像带 struct {
+	Version int     `json:"version"`
+	Calls   []I调用记录 `json:"calls"`
+}
+
+// I调用记录 是一次会话调用的参数和结果。Value是 I设置值 的参数;
+// Result、Values、Names、Existed和Stat分别是 I取值、I列出值、I列出子项、I创建表项
+// 和 I取信息 的结果。Access是传给 I打开表项 和 I创建表项 的访问权限, 其他调用为0。Type是设置或读取到的值的类型, 读取时在内部调用返回后才填写。
+type I调用记录 struct {
+	Op      string   `json:"op"`
+	Path    string   `json:"path"`
+	Access  uint32   `json:"access,omitempty"`
+	Name    string   `json:"name,omitempty"`
+	Type    uint32   `json:"type,omitempty"`
+	Value   *I离线值    `json:"value,omitempty"`
+	Result  *I离线值    `json:"result,omitempty"`
+	Values  []*I离线值  `json:"values,omitempty"`
+	Names   []string `json:"names,omitempty"`
+	Existed bool     `json:"existed,omitempty"`
+	Stat    *I表项统计   `json:"stat,omitempty"`
+	Err     *I录制的错误  `json:"error,omitempty"`
+}
+
+// I录制的错误 是录制时调用返回的错误。Code是Windows错误码, 没有时为0。
+// 回放和其他非Windows的会话实现返回该类型的错误, 它与 fs.ErrNotExist、fs.ErrPermission
+// 以及Windows上错误码相同的 syscall.Errno(例如 ErrNotExist、ErrShortBuffer)匹配。
+type I录制的错误 struct {
+	Code    uint32 `json:"code,omitempty"`
+	Message string `json:"message"`
+	原因      error
+}
+
+func (e *I录制的错误) Error() string {
+	return e.Message
+}
+
+// Unwrap 返回录制时的原始错误, 回放时为nil。
+func (e *I录制的错误) Unwrap() error {
+	return e.原因
+}
+
+// Is 实现 errors.Is。
+func (e *I录制的错误) Is(目标 error) bool {
+	switch 目标 {
+	case fs.ErrNotExist:
+		return e.Code == 2 || e.Code == 3 // ERROR_FILE_NOT_FOUND, ERROR_PATH_NOT_FOUND
+	case fs.ErrPermission:
+		return e.Code == 5 // ERROR_ACCESS_DENIED
+	}
+	if t, ok := 目标.(*I录制的错误); ok {
+		return e.Code != 0 && t.Code == e.Code
+	}
+	if n, ok := 目标.(syscall.Errno); ok {
+		return runtime.GOOS == "windows" && e.Code != 0 && uint32(n) == e.Code
+	}
+	return false
+}
+
+// 会话实现可以返回的常见Windows错误, 判断时使用 errors.Is。
+var (
+	ErrSessionNotExist         error = &I录制的错误{Code: 2, Message: "The system cannot find the file specified."}
+	ErrSessionAccessDenied     error = &I录制的错误{Code: 5, Message: "Access is denied."}
+	ErrSessionSharingViolation error = &I录制的错误{Code: 32, Message: "The process cannot access the file because it is being used by another process."}
+	ErrSessionMoreData         error = &I录制的错误{Code: 234, Message: "More data is available."}
+	ErrSessionKeyDeleted       error = &I录制的错误{Code: 1018, Message: "Illegal operation attempted on a registry key that has been marked for deletion."}
+)
+
+// 录制错误 把调用返回的错误转换为可以保存的形式。
+func 录制错误(err error) *I录制的错误 {
+	if err == nil {
+		return nil
+	}
+	if e, ok := err.(*I录制的错误); ok {
+		return e
+	}
+	e := &I录制的错误{Message: err.Error(), 原因: err}
+	var n syscall.Errno
+	if runtime.GOOS == "windows" && errors.As(err, &n) {
+		e.Code = uint32(n)
+	}
+	return e
+}
+
+// 回放错误 返回记录中的错误, 没有时返回nil。
+func (r *I调用记录) 回放错误() error {
+	if r.Err == nil {
+		return nil
+	}
+	return r.Err
+}
+
+// I读取录像带 从文件读取录像带。
+func I读取录像带(文件名 string) (*I录像带, error) {
+	数据, err := os.ReadFile(文件名)
+	if err != nil {
+		return nil, err
+	}
+	var c I录像带
+	d := json.NewDecoder(bytes.NewReader(数据))
+	d.DisallowUnknownFields()
+	if err := d.Decode(&c); err != nil {
+		return nil, fmt.Errorf("读取录像带 %s: %w", 文件名, err)
+	}
+	return &c, nil
+}
+
+// I保存 把录像带以缩进的JSON格式写入文件。
+func (c *I录像带) I保存(文件名 string) error {
+	if c == nil {
+		return errors.New("注册表类对象为nil")
+	}
+	c.Version = 1
+	数据, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(文件名, append(数据, '\n'), 0o644)
+}
+
+// I测试 是 I回放器 报告错误所用的接口, *testing.T 和 *testing.B 都实现了它。
+type I测试 interface {
+	Helper()
+	Errorf(格式 string, 参数 ...any)
+	Fatalf(格式 string, 参数 ...any)
+	Cleanup(func())
+}
+
+// I回放器 按录像带中的顺序回放会话调用, 实现 I注册表会话。
+// 调用的操作或参数(包括访问权限)与下一条记录不符, 或者录像带已经用完时, 用t.Fatalf使测试失败;
+// 测试结束时还有没回放的记录也会使测试失败。返回的值、名称和统计信息是录像带中记录的副本。
+type I回放器 struct {
+	带  *I录像带
+	t  I测试
+	锁  sync.Mutex
+	位置 int
+}
+
+// I新建回放器 返回回放录像带的会话。
+func I新建回放器(t I测试, 带 *I录像带) *I回放器 {
+	r := &I回放器{带: 带, t: t}
+	t.Cleanup(func() {
+		if 剩余 := r.I剩余调用(); len(剩余) > 0 {
+			t.Errorf("录像带中还有 %d 次调用没有回放, 下一次是 %s", len(剩余), 描述记录(&剩余[0]))
+		}
+	})
+	return r
+}
+
+// I剩余调用 返回还没有回放的记录。
+func (r *I回放器) I剩余调用() []I调用记录 {
+	r.锁.Lock()
+	defer r.锁.Unlock()
+	return r.带.Calls[r.位置:]
+}
+
+func 描述记录(r *I调用记录) string {
+	s := fmt.Sprintf("%s(%q", r.Op, r.Path)
+	if r.Name != "" || r.Op == I操作_取值 || r.Op == I操作_删除值 {
+		s += fmt.Sprintf(", %q", r.Name)
+	}
+	if r.Value != nil {
+		s += ", " + 格式化值数据(r.Value)
+	}
+	if r.Access != 0 {
+		s += fmt.Sprintf(", %#x", r.Access)
+	}
+	return s + ")"
+}
+
+// 下一个 返回与调用匹配的下一条记录, 不匹配时使测试失败并返回 ErrReplayMismatch。
+func (r *I回放器) 下一个(调用 *I调用记录) (*I调用记录, error) {
+	r.t.Helper()
+	r.锁.Lock()
+	defer r.锁.Unlock()
+	if r.位置 >= len(r.带.Calls) {
+		r.t.Fatalf("意外的注册表调用 %s: 录像带已经用完", 描述记录(调用))
+		return nil, ErrReplayMismatch
+	}
+	记录 := &r.带.Calls[r.位置]
+	switch {
+	case 记录.Op != 调用.Op:
+		r.t.Fatalf("意外的注册表调用 %s, 录像带中下一次调用是 %s", 描述记录(调用), 描述记录(记录))
+		return nil, ErrReplayMismatch
+	case hive比较名称(规范路径(记录.Path), 规范路径(调用.Path)) != 0 || hive比较名称(记录.Name, 调用.Name) != 0 ||
+		记录.Access != 调用.Access ||
+		(记录.Value == nil) != (调用.Value == nil) ||
+		记录.Value != nil && (hive比较名称(记录.Value.Name, 调用.Value.Name) != 0 || !值相同(记录.Value, 调用.Value)):
+		r.t.Fatalf("注册表调用的参数不符: 调用 %s, 录像带中是 %s", 描述记录(调用), 描述记录(记录))
+		return nil, ErrReplayMismatch
+	}
+	r.位置++
+	return 记录, nil
+}
+
+// I打开表项 实现 I注册表会话。
+func (r *I回放器) I打开表项(路径 string, 访问权限 uint32) error {
+	r.t.Helper()
+	记录, err := r.下一个(&I调用记录{Op: I操作_打开表项, Path: 路径, Access: 访问权限})
+	if err != nil {
+		return err
+	}
+	return 记录.回放错误()
+}
+
+// I取值 实现 I注册表会话。
+func (r *I回放器) I取值(路径, 名称 string) (*I离线值, error) {
+	r.t.Helper()
+	记录, err := r.下一个(&I调用记录{Op: I操作_取值, Path: 路径, Name: 名称})
+	if err != nil {
+		return nil, err
+	}
+	var 结果 *I离线值
+	if 记录.Result != nil {
+		结果 = 复制离线值(记录.Result)
+	}
+	return 结果, 记录.回放错误()
+}
+
+// I列出值 实现 I注册表会话。
+func (r *I回放器) I列出值(路径 string) ([]*I离线值, error) {
+	r.t.Helper()
+	记录, err := r.下一个(&I调用记录{Op: I操作_列出值, Path: 路径})
+	if err != nil {
+		return nil, err
+	}
+	var 结果 []*I离线值
+	for _, v := range 记录.Values {
+		结果 = append(结果, 复制离线值(v))
+	}
+	return 结果, 记录.回放错误()
+}
+
+// I列出子项 实现 I注册表会话。
+func (r *I回放器) I列出子项(路径 string) ([]string, error) {
+	r.t.Helper()
+	记录, err := r.下一个(&I调用记录{Op: I操作_列出子项, Path: 路径})
+	if err != nil {
+		return nil, err
+	}
+	return slices.Clone(记录.Names), 记录.回放错误()
+}
+
+// I设置值 实现 I注册表会话。
+func (r *I回放器) I设置值(路径 string, 值 *I离线值) error {
+	r.t.Helper()
+	if 值 == nil {
+		return errors.New("注册表类对象为nil")
+	}
+	记录, err := r.下一个(&I调用记录{Op: I操作_设置值, Path: 路径, Name: 值.Name, Value: 值})
+	if err != nil {
+		return err
+	}
+	return 记录.回放错误()
+}
+
+// I删除值 实现 I注册表会话。
+func (r *I回放器) I删除值(路径, 名称 string) error {
+	r.t.Helper()
+	记录, err := r.下一个(&I调用记录{Op: I操作_删除值, Path: 路径, Name: 名称})
+	if err != nil {
+		return err
+	}
+	return 记录.回放错误()
+}
+
+// I创建表项 实现 I注册表会话。
+func (r *I回放器) I创建表项(路径 string, 访问权限 ...uint32) (bool, error) {
+	r.t.Helper()
+	记录, err := r.下一个(&I调用记录{Op: I操作_创建表项, Path: 路径, Access: 可选权限(访问权限)})
+	if err != nil {
+		return false, err
+	}
+	return 记录.Existed, 记录.回放错误()
+}
+
+// I删除表项 实现 I注册表会话。
+func (r *I回放器) I删除表项(路径 string) error {
+	r.t.Helper()
+	记录, err := r.下一个(&I调用记录{Op: I操作_删除表项, Path: 路径})
+	if err != nil {
+		return err
+	}
+	return 记录.回放错误()
+}
+
+// I取信息 实现 I注册表会话。
+func (r *I回放器) I取信息(路径 string) (*I表项统计, error) {
+	r.t.Helper()
+	记录, err := r.下一个(&I调用记录{Op: I操作_取信息, Path: 路径})
+	if err != nil {
+		return nil, err
+	}
+	var 结果 *I表项统计
+	if 记录.Stat != nil {
+		统计 := *记录.Stat
+		结果 = &统计
+	}
+	return 结果, 记录.回放错误()
+}
@@ -0,0 +1,178 @@
+package 注册表类_test
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	注册表类 "e.coding.net/gogit/go/gosdk/core/win_registry_cn"
+)
+
+const testCassette = `{"version": 1, "calls": [
+	{"op": "GetValue", "path": "Software\\Contoso", "name": "Theme",
+	 "result": {"Name": "Theme", "Type": 1, "Data": "ZABhAHIAawAAAA=="}},
+	{"op": "GetValue", "path": "Software\\Contoso", "name": "Missing",
+	 "error": {"code": 2, "message": "The system cannot find the file specified."}},
+	{"op": "SetValue", "path": "Software\\Contoso", "name": "Level",
+	 "value": {"Name": "Level", "Type": 4, "Data": "AwAAAA=="}},
+	{"op": "EnumKeys", "path": "Software\\Contoso", "names": ["A", "B"]},
+	{"op": "CreateKey", "path": "Software\\Contoso\\New"},
+	{"op": "DeleteKey", "path": "Software\\Contoso\\Old",
+	 "error": {"code": 5, "message": "Access is denied."}}]}`
+
+// fakeT 记录回放器报告的失败, 而不结束测试。
+type fakeT struct {
+	failures []string
+	cleanups []func()
+}
+
+func (f *fakeT) Helper() {}
+func (f *fakeT) Errorf(format string, a ...any) {
+	f.failures = append(f.failures, fmt.Sprintf(format, a...))
+}
+func (f *fakeT) Fatalf(format string, a ...any) {
+	f.failures = append(f.failures, fmt.Sprintf(format, a...))
+}
+func (f *fakeT) Cleanup(fn func()) { f.cleanups = append(f.cleanups, fn) }
+
+func (f *fakeT) finish() {
+	for i := len(f.cleanups) - 1; i >= 0; i-- {
+		f.cleanups[i]()
+	}
+}
+
+func loadTestCassette(t *testing.T) *注册表类.I录像带 {
+	t.Helper()
+	name := filepath.Join(t.TempDir(), "cassette.json")
+	if err := os.WriteFile(name, []byte(testCassette), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	c, err := 注册表类.I读取录像带(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 保存后重新读取应该得到相同的调用。
+	if err := c.I保存(name); err != nil {
+		t.Fatal(err)
+	}
+	again, err := 注册表类.I读取录像带(name)
+	if err != nil || len(again.Calls) != len(c.Calls) {
+		t.Fatalf("reload: got %v, %v", again, err)
+	}
+	return again
+}
+
+func TestReplayCassette(t *testing.T) {
+	cassette := loadTestCassette(t)
+	var s 注册表类.I注册表会话 = 注册表类.I新建回放器(t, cassette)
+
+	v, err := s.I取值(`software\contoso\`, "theme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := v.I取文本(); got != "dark" {
+		t.Errorf("Theme: got %q", got)
+	}
+	// 修改返回的值不影响录像带。
+	v.Data[0] = 'D'
+	if got, _ := cassette.Calls[0].Result.I取文本(); got != "dark" {
+		t.Errorf("cassette Theme after modifying the result: got %q", got)
+	}
+	if _, err := s.I取值(`Software\Contoso`, "Missing"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Missing: got %v, want fs.ErrNotExist", err)
+	}
+	if err := s.I设置值(`Software\Contoso`, dword("Level", 3)); err != nil {
+		t.Error(err)
+	}
+	if names, err := s.I列出子项(`Software\Contoso`); err != nil || strings.Join(names, ",") != "A,B" {
+		t.Errorf("EnumKeys: got %q, %v", names, err)
+	}
+	if existed, err := s.I创建表项(`Software\Contoso\New`); err != nil || existed {
+		t.Errorf("CreateKey: got %v, %v", existed, err)
+	}
+	if err := s.I删除表项(`Software\Contoso\Old`); !errors.Is(err, fs.ErrPermission) || err.Error() != "Access is denied." {
+		t.Errorf("DeleteKey: got %v, want fs.ErrPermission", err)
+	}
+}
+
+func TestReplayMismatch(t *testing.T) {
+	tests := []struct {
+		name string
+		call func(s 注册表类.I注册表会话) error
+		want string
+	}{
+		{"unexpected call", func(s 注册表类.I注册表会话) error {
+			return s.I删除值(`Software\Contoso`, "Theme")
+		}, "意外的注册表调用 DeleteValue"},
+		{"wrong name", func(s 注册表类.I注册表会话) error {
+			_, err := s.I取值(`Software\Contoso`, "Other")
+			return err
+		}, "参数不符"},
+		{"wrong path", func(s 注册表类.I注册表会话) error {
+			_, err := s.I取值(`Software\Fabrikam`, "Theme")
+			return err
+		}, "参数不符"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ft := &fakeT{}
+			r := 注册表类.I新建回放器(ft, loadTestCassette(t))
+			if err := tt.call(r); !errors.Is(err, 注册表类.ErrReplayMismatch) {
+				t.Errorf("got %v, want ErrReplayMismatch", err)
+			}
+			if len(ft.failures) == 0 || !strings.Contains(ft.failures[0], tt.want) {
+				t.Errorf("failures: %q, want %q", ft.failures, tt.want)
+			}
+		})
+	}
+
+	t.Run("wrong value", func(t *testing.T) {
+		ft := &fakeT{}
+		r := 注册表类.I新建回放器(ft, loadTestCassette(t))
+		r.I取值(`Software\Contoso`, "Theme")
+		r.I取值(`Software\Contoso`, "Missing")
+		if err := r.I设置值(`Software\Contoso`, dword("Level", 4)); !errors.Is(err, 注册表类.ErrReplayMismatch) {
+			t.Errorf("got %v, want ErrReplayMismatch", err)
+		}
+	})
+
+	t.Run("wrong access", func(t *testing.T) {
+		ft := &fakeT{}
+		r := 注册表类.I新建回放器(ft, loadTestCassette(t))
+		r.I取值(`Software\Contoso`, "Theme")
+		r.I取值(`Software\Contoso`, "Missing")
+		r.I设置值(`Software\Contoso`, dword("Level", 3))
+		r.I列出子项(`Software\Contoso`)
+		if _, err := r.I创建表项(`Software\Contoso\New`, 0x20019); !errors.Is(err, 注册表类.ErrReplayMismatch) {
+			t.Errorf("got %v, want ErrReplayMismatch", err)
+		}
+		if len(ft.failures) != 1 || !strings.Contains(ft.failures[0], "0x20019") {
+			t.Errorf("failures: %q", ft.failures)
+		}
+	})
+
+	t.Run("unused calls", func(t *testing.T) {
+		ft := &fakeT{}
+		r := 注册表类.I新建回放器(ft, loadTestCassette(t))
+		r.I取值(`Software\Contoso`, "Theme")
+		ft.finish()
+		if len(ft.failures) != 1 || !strings.Contains(ft.failures[0], "还有 5 次调用没有回放") {
+			t.Errorf("failures: %q", ft.failures)
+		}
+	})
+
+	t.Run("exhausted", func(t *testing.T) {
+		ft := &fakeT{}
+		r := 注册表类.I新建回放器(ft, &注册表类.I录像带{})
+		if _, err := r.I列出值(`Software`); !errors.Is(err, 注册表类.ErrReplayMismatch) {
+			t.Errorf("got %v, want ErrReplayMismatch", err)
+		}
+		if len(ft.failures) != 1 || !strings.Contains(ft.failures[0], "录像带已经用完") {
+			t.Errorf("failures: %q", ft.failures)
+		}
+	})
+}
@@ -0,0 +1,57 @@
+//go:build windows
+// +build windows
+
+package 注册表类
+
+import (
+	"sync"
+)
+
+// I录制器 在注册表对象的根项下执行会话调用, 并把每次调用的参数、访问权限、结果和错误
+// 追加到录像带中, 实现 I注册表会话。调用的执行和返回的错误与 I表项会话 相同。
+// 记录的访问权限是调用方传给 I打开表项 和 I创建表项 的权限, 回放时必须一致。
+// 录制结束后用 I录像带.I保存 保存录像带, 在其他平台上用 I回放器 回放。
+type I录制器 struct {
+	*I拦截会话
+	根 *Key结构
+	带 *I录像带
+	锁 sync.Mutex
+}
+
+// I新建录制器 返回在根项下执行调用并记录到录像带'带'的会话, 带为nil时新建一个。
+func I新建录制器(根 *Key结构, 带 *I录像带) (*I录制器, error) {
+	会话, err := I新建表项会话(根)
+	if err != nil {
+		return nil, err
+	}
+	if 带 == nil {
+		带 = &I录像带{}
+	}
+	r := &I录制器{根: 根, 带: 带}
+	if r.I拦截会话, err = I新建拦截会话(会话, I拦截函数(r.记录)); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// I取录像带 返回录制到的录像带。
+func (r *I录制器) I取录像带() *I录像带 {
+	return r.带
+}
+
+// I录制表项 返回根项的拦截副本, 通过它对 Key结构 的调用也记录到同一个录像带中,
+// 路径相对于根项。返回的对象不拥有句柄, 用完后根项仍需由调用方关闭。
+func (r *I录制器) I录制表项() (*Key结构, error) {
+	return I启用拦截(r.根, I拦截函数(r.记录))
+}
+
+// 记录 执行调用, 然后把调用连同错误追加到录像带中。
+func (r *I录制器) 记录(调用 *I调用记录, 继续 func() error) error {
+	err := 继续()
+	记录 := *调用
+	记录.Err = 录制错误(err)
+	r.锁.Lock()
+	r.带.Calls = append(r.带.Calls, 记录)
+	r.锁.Unlock()
+	return err
+}
@@ -0,0 +1,382 @@
+//go:build windows
+// +build windows
+
+package 注册表类
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"syscall"
+	"unicode/utf16"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+)
+
+// I启用拦截 返回与注册表对象k共享句柄的注册表对象, 它和从它打开或创建的所有子项上的调用
+// 都依次交给拦截器, 拦截器与 I拦截会话 的相同: 调用的Path是相对k的路径,
+// 打开和创建子项时Access是请求的'访问权限'(I打开表项Ex 等降级时每次尝试都是一次调用)。
+// 拦截器修改调用的参数不影响实际执行的操作, 但可以修改结果或返回错误;
+// 不调用 继续 时以拦截器填写的结果为准, 此时打开或创建的子项没有有效的句柄,
+// 只能继续用于同样不调用 继续 的拦截器(例如回放)。
+//
+// 被拦截的有打开、创建和删除子项(包括符号链接表项)、读取、设置和删除值、枚举子项和值以及
+// I取对象信息, 也就包括在它们之上实现的函数, 例如 I取、I删除表项树、I应用计划 和 I写入离线表项。
+// I取文本值P、I取安全描述符 等其他操作直接使用句柄。
+// 返回的注册表对象的 I关闭 不关闭k的句柄; k已经启用拦截时返回错误。
+func I启用拦截(k *Key结构, 拦截器 ...I拦截器) (*Key结构, error) {
+	if k == nil {
+		return nil, errors.New("注册表类对象为nil")
+	}
+	if k.拦截 != nil {
+		return nil, errors.New("注册表对象已经启用拦截")
+	}
+	if err := 检查拦截器(拦截器); err != nil {
+		return nil, err
+	}
+	return &Key结构{Key父类: k.Key父类, 拦截: append(拦截链{}, 拦截器...), 借用: true}, nil
+}
+
+// 子路径 返回k下的子项路径相对于启用拦截的注册表对象的路径。
+func (k *Key结构) 子路径(路径 string) string {
+	if 路径 = 规范路径(路径); 路径 == "" {
+		return k.路径
+	}
+	return hive连接路径(k.路径, 路径)
+}
+
+// 拦截调用 在k启用了拦截时把调用交给拦截链, 最后执行'内部'; 否则直接执行'内部'。
+func (k *Key结构) 拦截调用(调用 *I调用记录, 内部 func() error) error {
+	if k.拦截 == nil {
+		return 内部()
+	}
+	return k.拦截.执行(调用, 内部)
+}
+
+// 原始 返回与k共享句柄但没有拦截的注册表对象, 用于执行拦截链最内层的操作。
+func (k *Key结构) 原始() *Key结构 {
+	return &Key结构{Key父类: k.Key父类}
+}
+
+// 新建子项 返回句柄为h的子项, 它继承k的拦截链。
+func (k *Key结构) 新建子项(h registry.Key, 路径 string) *Key结构 {
+	if k.拦截 == nil {
+		return &Key结构{Key父类: h}
+	}
+	return &Key结构{Key父类: h, 拦截: k.拦截, 路径: k.子路径(路径)}
+}
+
+// 打开子项 以RegOpenKeyEx的'选项'(例如 OPTION_OPEN_LINK)和'访问权限'打开k下的子项路径。
+func (k *Key结构) 打开子项(路径 string, 选项, 访问权限 uint32) (*Key结构, error) {
+	if k == nil {
+		return nil, errors.New("注册表类对象为nil")
+	}
+	var h windows.Handle
+	err := k.拦截调用(&I调用记录{Op: I操作_打开表项, Path: k.子路径(路径), Access: 访问权限}, func() error {
+		p, err := syscall.UTF16PtrFromString(路径)
+		if err != nil {
+			return err
+		}
+		return windows.RegOpenKeyEx(windows.Handle(k.Key父类), p, 选项, 访问权限, &h)
+	})
+	if err != nil {
+		if h != 0 {
+			windows.RegCloseKey(h)
+		}
+		return nil, err
+	}
+	return k.新建子项(registry.Key(h), 路径), nil
+}
+
+// 创建子项 以RegCreateKeyEx的'选项'、'类名'和'访问权限'创建或打开k下的子项路径,
+// 返回子项和它是否已经存在。
+func (k *Key结构) 创建子项(路径 string, 选项 uint32, 类名 *uint16, 访问权限 uint32) (*Key结构, bool, error) {
+	if k == nil {
+		return nil, false, errors.New("注册表类对象为nil")
+	}
+	var h syscall.Handle
+	调用 := &I调用记录{Op: I操作_创建表项, Path: k.子路径(路径), Access: 访问权限}
+	err := k.拦截调用(调用, func() error {
+		p, err := syscall.UTF16PtrFromString(路径)
+		if err != nil {
+			return err
+		}
+		var 结果 uint32
+		err = regCreateKeyEx(syscall.Handle(k.Key父类), p, 0, 类名, 选项, 访问权限, nil, &h, &结果)
+		调用.Existed = 结果 == _REG_OPENED_EXISTING_KEY
+		return err
+	})
+	if err != nil {
+		if h != 0 {
+			syscall.RegCloseKey(h)
+		}
+		return nil, 调用.Existed, err
+	}
+	return k.新建子项(registry.Key(h), 路径), 调用.Existed, nil
+}
+
+// 取值重试次数 是拦截的读取遇到 ErrShortBuffer 时最多重试的次数。未拦截的读取在值
+// 于两次调用之间变大时重新分配缓冲区重试, 拦截器返回 ErrShortBuffer 时同样重试。
+const 取值重试次数 = 4
+
+func (k *Key结构) 拦截取原始值(名称 string) (*I离线值, error) {
+	var 调用 *I调用记录
+	var err error
+	for i := 0; i <= 取值重试次数; i++ {
+		调用 = &I调用记录{Op: I操作_取值, Path: k.路径, Name: 名称}
+		err = k.拦截.执行(调用, func() (err error) {
+			if 调用.Result, err = k.原始().取原始值(名称); 调用.Result != nil {
+				调用.Type = 调用.Result.Type
+			}
+			return err
+		})
+		if !errors.Is(err, ErrShortBuffer) {
+			break
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if 调用.Result == nil {
+		return nil, ErrNotExist
+	}
+	return 调用.Result, nil
+}
+
+// 拦截取值 按 I取值 的约定把拦截后读取的值复制到缓冲区。
+func (k *Key结构) 拦截取值(名称 string, 缓冲区 []byte) (int, uint32, error) {
+	v, err := k.拦截取原始值(名称)
+	if err != nil {
+		return 0, 0, err
+	}
+	n := len(v.Data)
+	if 缓冲区 == nil {
+		return n, v.Type, nil
+	}
+	if n > len(缓冲区) {
+		return n, v.Type, ErrShortBuffer
+	}
+	copy(缓冲区, v.Data)
+	return n, v.Type, nil
+}
+
+// 取类型值 读取值并检查它的类型是'类型'之一, 不是时同时返回值和ErrUnexpectedType。
+func (k *Key结构) 取类型值(名称 string, 类型 ...uint32) (*I离线值, error) {
+	v, err := k.拦截取原始值(名称)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range 类型 {
+		if v.Type == t {
+			return v, nil
+		}
+	}
+	return v, ErrUnexpectedType
+}
+
+func (k *Key结构) 拦截取文本值(名称 string) (string, uint32, error) {
+	v, err := k.取类型值(名称, SZ, EXPAND_SZ)
+	if err != nil {
+		return "", 值类型(v), err
+	}
+	文本, _, _ := strings.Cut(hive解码UTF16(v.Data), "\x00")
+	return 文本, v.Type, nil
+}
+
+// 拦截取文本值_数组 与 registry.Key.GetStringsValue 一样, 去掉结尾的空字符后按空字符分隔,
+// 最后一个没有以空字符结束的字符串被忽略。
+func (k *Key结构) 拦截取文本值_数组(名称 string) ([]string, uint32, error) {
+	v, err := k.取类型值(名称, MULTI_SZ)
+	if err != nil {
+		return nil, 值类型(v), err
+	}
+	文本 := hive解码UTF16(v.Data)
+	if 文本 == "" {
+		return nil, v.Type, nil
+	}
+	段 := strings.Split(strings.TrimSuffix(文本, "\x00"), "\x00")
+	return append(make([]string, 0, len(段)-1), 段[:len(段)-1]...), v.Type, nil
+}
+
+func (k *Key结构) 拦截取整数值(名称 string) (uint64, uint32, error) {
+	v, err := k.取类型值(名称, DWORD, QWORD)
+	if err != nil {
+		return 0, 值类型(v), err
+	}
+	if v.Type == DWORD {
+		if len(v.Data) != 4 {
+			return 0, v.Type, errors.New("DWORD value is not 4 bytes long")
+		}
+		return uint64(le.Uint32(v.Data)), v.Type, nil
+	}
+	if len(v.Data) != 8 {
+		return 0, v.Type, errors.New("QWORD value is not 8 bytes long")
+	}
+	return le.Uint64(v.Data), v.Type, nil
+}
+
+func (k *Key结构) 拦截取字节集值(名称 string) ([]byte, uint32, error) {
+	v, err := k.取类型值(名称, BINARY)
+	if err != nil {
+		return nil, 值类型(v), err
+	}
+	return v.Data, v.Type, nil
+}
+
+// 值类型 返回值的类型, 值为nil时返回0。
+func 值类型(v *I离线值) uint32 {
+	if v == nil {
+		return 0
+	}
+	return v.Type
+}
+
+func (k *Key结构) 拦截设置值(名称 string, 值类型 uint32, data []byte) error {
+	值 := &I离线值{Name: 名称, Type: 值类型, Data: data}
+	return k.拦截.执行(&I调用记录{Op: I操作_设置值, Path: k.路径, Name: 名称, Type: 值类型, Value: 值}, func() error {
+		return k.原始().setValue(名称, 值类型, data)
+	})
+}
+
+// 拦截设置文本值 与 registry.Key.SetStringValue 一样, 值中有空字符时返回EINVAL。
+func (k *Key结构) 拦截设置文本值(名称 string, 值类型 uint32, 值 string) error {
+	u, err := syscall.UTF16FromString(值)
+	if err != nil {
+		return err
+	}
+	return k.setValue(名称, 值类型, utf16转字节(u))
+}
+
+func (k *Key结构) 拦截设置文本值_数组(名称 string, 值 []string) error {
+	var u []uint16
+	for _, s := range 值 {
+		if strings.IndexByte(s, 0) >= 0 {
+			return errors.New("string cannot have 0 inside")
+		}
+		u = append(append(u, utf16.Encode([]rune(s))...), 0)
+	}
+	return k.setValue(名称, MULTI_SZ, utf16转字节(append(u, 0)))
+}
+
+// 编码整数值 返回n的小端序编码的前'长度'个字节。
+func 编码整数值(n uint64, 长度 int) []byte {
+	b := make([]byte, 8)
+	le.PutUint64(b, n)
+	return b[:长度]
+}
+
+func (k *Key结构) 拦截取子项名称(n int) ([]string, error) {
+	调用 := &I调用记录{Op: I操作_列出子项, Path: k.路径}
+	err := k.拦截.执行(调用, func() (err error) {
+		调用.Names, err = k.Key父类.ReadSubKeyNames(-1)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return 截取名称(调用.Names, n), nil
+}
+
+func (k *Key结构) 拦截列出值() ([]*I离线值, error) {
+	调用 := &I调用记录{Op: I操作_列出值, Path: k.路径}
+	err := k.拦截.执行(调用, func() (err error) {
+		调用.Values, err = k.原始().I列出原始值()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return 调用.Values, nil
+}
+
+func (k *Key结构) 拦截取值名称(n int) ([]string, error) {
+	值, err := k.拦截列出值()
+	if err != nil {
+		return nil, err
+	}
+	名称 := make([]string, len(值))
+	for i, v := range 值 {
+		名称[i] = v.Name
+	}
+	return 截取名称(名称, n), nil
+}
+
+// 拦截遍历值 一次列出所有值后逐个交给yield。
+func (k *Key结构) 拦截遍历值(ctx context.Context, yield func(*I离线值, error) bool) {
+	值, err := k.拦截列出值()
+	if err != nil {
+		yield(nil, err)
+		return
+	}
+	for _, v := range 值 {
+		if err := ctx.Err(); err != nil {
+			yield(nil, err)
+			return
+		}
+		if !yield(v, nil) {
+			return
+		}
+	}
+}
+
+// 拦截遍历子项 一次列出所有子项名称后逐个打开子项读取统计信息。
+func (k *Key结构) 拦截遍历子项(ctx context.Context, yield func(I子项条目, error) bool) {
+	名称, err := k.拦截取子项名称(-1)
+	if err != nil {
+		yield(I子项条目{}, err)
+		return
+	}
+	for _, n := range 名称 {
+		if err := ctx.Err(); err != nil {
+			yield(I子项条目{}, err)
+			return
+		}
+		条目 := I子项条目{Name: n}
+		子k, err := I打开表项(k, n, QUERY_VALUE)
+		if err == nil {
+			条目.Info, err = 子k.I取对象信息()
+			子k.I关闭()
+		}
+		if !yield(条目, err) {
+			return
+		}
+	}
+}
+
+// 对象信息转统计 返回会话和拦截器使用的表项统计信息。
+func 对象信息转统计(信息 *I对象信息) *I表项统计 {
+	return &I表项统计{
+		Class:       信息.Class,
+		SubKeyCount: int(信息.SubKeyCount),
+		ValueCount:  int(信息.ValueCount),
+		ModTime:     信息.I取写入时间().UTC(),
+	}
+}
+
+// 拦截取对象信息 以拦截后的统计信息为准, 只有统计信息中没有的字段来自句柄。
+func (k *Key结构) 拦截取对象信息() (*I对象信息, error) {
+	var 信息 *I对象信息
+	调用 := &I调用记录{Op: I操作_取信息, Path: k.路径}
+	err := k.拦截.执行(调用, func() (err error) {
+		if 信息, err = k.原始().I取对象信息(); err == nil {
+			调用.Stat = 对象信息转统计(信息)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if 信息 == nil {
+		信息 = &I对象信息{}
+	}
+	if s := 调用.Stat; s != nil {
+		信息.Class = s.Class
+		信息.SubKeyCount = uint32(s.SubKeyCount)
+		信息.ValueCount = uint32(s.ValueCount)
+		if !s.ModTime.Equal(信息.I取写入时间()) {
+			信息.修改时间 = s.ModTime
+		}
+	}
+	return 信息, nil
+}
@@ -0,0 +1,83 @@
+//go:build windows
+// +build windows
+
+package 注册表类
+
+import (
+	"errors"
+	"fmt"
+)
+
+// I计划表项 比较期望状态与注册表对象k下的当前状态, 期望状态中的路径相对于k。见 I计划期望状态。
+func I计划表项(k *Key结构, 期望 *I期望状态) ([]I离线差异, error) {
+	if k == nil {
+		return nil, errors.New("注册表类对象为nil")
+	}
+	return I计划期望状态(期望, func(路径 string) (*I离线表项, error) {
+		子k, err := I打开表项(k, 路径, QUERY_VALUE)
+		if errors.Is(err, ErrNotExist) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		defer 子k.I关闭()
+		值, err := 子k.I列出原始值()
+		if err != nil {
+			return nil, err
+		}
+		return &I离线表项{Values: 值}, nil
+	})
+}
+
+// I应用计划 在注册表对象k下执行 I计划表项 返回的变更, 缺少的上级表项会被创建。
+// 已经处于期望状态的部分(例如要删除的值已经不存在)被跳过, 所以重复应用同一个计划是安全的。
+// 出错时停止并返回错误, 之前的变更不会回滚; 重新计划并应用即可继续。
+func I应用计划(k *Key结构, 计划 []I离线差异) error {
+	if k == nil {
+		return errors.New("注册表类对象为nil")
+	}
+	for _, d := range 计划 {
+		if err := 应用变更(k, d); err != nil {
+			return fmt.Errorf("%v: %w", d, err)
+		}
+	}
+	return nil
+}
+
+func 应用变更(k *Key结构, d I离线差异) error {
+	switch d.Kind {
+	case I差异_新增表项:
+		子k, _, err := I创建表项(k, d.Path, CREATE_SUB_KEY)
+		if err != nil {
+			return err
+		}
+		return 子k.I关闭()
+	case I差异_删除表项:
+		if err := I删除表项树(k, d.Path); err != nil && !errors.Is(err, ErrNotExist) {
+			return err
+		}
+		return nil
+	case I差异_新增值, I差异_修改值:
+		子k, _, err := I创建表项(k, d.Path, SET_VALUE)
+		if err != nil {
+			return err
+		}
+		defer 子k.I关闭()
+		return 子k.I写入原始值(d.New)
+	case I差异_删除值:
+		子k, err := I打开表项(k, d.Path, SET_VALUE)
+		if errors.Is(err, ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		defer 子k.I关闭()
+		if err := 子k.I删除值(d.Value); err != nil && !errors.Is(err, ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+	return errors.New("不能应用的变更")
+}
@@ -0,0 +1,462 @@
+package 注册表类
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	// I退出码_无漂移 等是 I漂移退出码 返回的退出码, 与terraform plan -detailed-exitcode 一致。
+	I退出码_无漂移 = 0
+	I退出码_错误  = 1
+	I退出码_有漂移 = 2
+)
+
+// I期望状态 声明一组表项和值应有的状态, 可以用 I解析期望状态 从JSON或YAML解析,
+// 字段也带有yaml标签, 也可以用任意YAML库解码后传给 I计划期望状态。
+//
+//	{"keys": [
+//	  {"path": `Software\Contoso`, "values": [
+//	    {"name": "Level", "type": "dword", "data": 3},
+//	    {"name": "Hosts", "type": "multi_sz", "data": ["a", "b"]},
+//	    {"name": "Legacy", "absent": true}]},
+//	  {"path": `Software\Contoso\Old`, "absent": true}]}
+type I期望状态 struct {
+	Keys []I期望表项 `json:"keys" yaml:"keys"`
+}
+
+// I期望表项 声明一个表项。Path相对于应用时的根项。
+// Absent为true时表项及其所有子项必须不存在; Purge为true时删除没有声明的值。
+type I期望表项 struct {
+	Path   string `json:"path" yaml:"path"`
+	Absent bool   `json:"absent,omitempty" yaml:"absent,omitempty"`
+	Purge  bool   `json:"purge,omitempty" yaml:"purge,omitempty"`
+	Values []I期望值 `json:"values,omitempty" yaml:"values,omitempty"`
+}
+
+// I期望值 声明一个值。Type是 I解析值类型 接受的类型名称, 默认为REG_SZ。Data的格式取决于类型:
+// 文本类型为字符串; REG_MULTI_SZ为字符串数组; 整数类型为数字或 strconv.ParseInt 接受的字符串,
+// 负数按补码保存; 其他类型为十六进制字符串(可以用空格、逗号或冒号分隔)或字节数组。
+// Absent为true时值必须不存在, 忽略Type和Data。
+type I期望值 struct {
+	Name   string `json:"name" yaml:"name"`
+	Type   string `json:"type,omitempty" yaml:"type,omitempty"`
+	Data   any    `json:"data,omitempty" yaml:"data,omitempty"`
+	Absent bool   `json:"absent,omitempty" yaml:"absent,omitempty"`
+}
+
+// I解析期望状态 解析期望状态文档并检查它, 见 I检查期望状态。
+// '格式'默认为 I格式_JSON; I格式_YAML 支持的子集与 I导入离线表项 相同。
+func I解析期望状态(数据 []byte, 格式 ...I导出格式) (*I期望状态, error) {
+	if len(格式) > 0 {
+		switch 格式[0] {
+		case I格式_JSON:
+		case I格式_YAML:
+			通用, err := yaml解码(数据)
+			if err != nil {
+				return nil, fmt.Errorf("解析期望状态: %w", err)
+			}
+			if 数据, err = json.Marshal(通用); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("未知的导出格式 %d", 格式[0])
+		}
+	}
+	d := json.NewDecoder(bytes.NewReader(数据))
+	d.UseNumber()
+	d.DisallowUnknownFields()
+	var s I期望状态
+	if err := d.Decode(&s); err != nil {
+		return nil, fmt.Errorf("解析期望状态: %w", err)
+	}
+	if err := I检查期望状态(&s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// I检查期望状态 检查期望状态中的路径是否重复或互相矛盾, 值的类型和数据是否有效。
+func I检查期望状态(s *I期望状态) error {
+	if s == nil {
+		return errors.New("注册表类对象为nil")
+	}
+	for i, k := range s.Keys {
+		if len(拆分路径(k.Path)) == 0 && k.Absent {
+			return fmt.Errorf("第%d个表项: 不能删除根项", i+1)
+		}
+		for _, 前 := range s.Keys[:i] {
+			switch {
+			case hive比较名称(规范路径(前.Path), 规范路径(k.Path)) == 0:
+				return fmt.Errorf("表项 %q 重复声明", k.Path)
+			case 前.Absent && !k.Absent && 是子路径(前.Path, k.Path), k.Absent && !前.Absent && 是子路径(k.Path, 前.Path):
+				return fmt.Errorf("表项 %q 和 %q 互相矛盾", 前.Path, k.Path)
+			}
+		}
+		for j, v := range k.Values {
+			for _, 前 := range k.Values[:j] {
+				if hive比较名称(前.Name, v.Name) == 0 {
+					return fmt.Errorf("表项 %q 的值 %q 重复声明", k.Path, v.Name)
+				}
+			}
+			if _, err := v.I取值(); err != nil {
+				return fmt.Errorf("表项 %q: %w", k.Path, err)
+			}
+		}
+	}
+	return nil
+}
+
+func 规范路径(路径 string) string {
+	return strings.Join(拆分路径(路径), `\`)
+}
+
+// 是子路径 报告子是否是父或父下面的表项。
+func 是子路径(父, 子 string) bool {
+	p, c := 拆分路径(父), 拆分路径(子)
+	if len(c) < len(p) {
+		return false
+	}
+	for i := range p {
+		if hive比较名称(p[i], c[i]) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// I取值 返回声明的值。Absent为true时返回nil。
+func (v I期望值) I取值() (*I离线值, error) {
+	if v.Absent {
+		return nil, nil
+	}
+	值类型 := uint32(SZ)
+	if v.Type != "" {
+		var err error
+		if 值类型, err = I解析值类型(v.Type); err != nil {
+			return nil, fmt.Errorf("值 %q: %w", v.Name, err)
+		}
+	}
+	结果, err := 期望数据(v.Name, 值类型, v.Data)
+	if err != nil {
+		return nil, fmt.Errorf("值 %q: %w", v.Name, err)
+	}
+	return 结果, nil
+}
+
+func 期望数据(名称 string, 值类型 uint32, 数据 any) (*I离线值, error) {
+	switch 值类型 {
+	case SZ, EXPAND_SZ, LINK:
+		s, ok := 数据.(string)
+		if !ok && 数据 != nil {
+			return nil, fmt.Errorf("%s 的数据必须是字符串", I值类型名称(值类型))
+		}
+		v, err := 编码文本(名称, s)
+		if err != nil {
+			return nil, err
+		}
+		v.Type = 值类型
+		return v, nil
+	case MULTI_SZ:
+		var a []string
+		switch x := 数据.(type) {
+		case nil:
+		case []string:
+			a = x
+		case []any:
+			for _, e := range x {
+				s, ok := e.(string)
+				if !ok {
+					return nil, errors.New("REG_MULTI_SZ 的数据必须是字符串数组")
+				}
+				a = append(a, s)
+			}
+		default:
+			return nil, errors.New("REG_MULTI_SZ 的数据必须是字符串数组")
+		}
+		return I编码值(名称, a)
+	case DWORD, DWORD_BIG_ENDIAN, QWORD:
+		位数 := 32
+		if 值类型 == QWORD {
+			位数 = 64
+		}
+		n, err := 期望整数(数据, 位数)
+		if err != nil {
+			return nil, err
+		}
+		if 值类型 == DWORD_BIG_ENDIAN {
+			return &I离线值{Name: 名称, Type: 值类型, Data: []byte{byte(n >> 24), byte(n >> 16), byte(n >> 8), byte(n)}}, nil
+		}
+		return 数字值(名称, 值类型, n), nil
+	}
+	b, err := 期望字节(数据)
+	if err != nil {
+		return nil, err
+	}
+	return &I离线值{Name: 名称, Type: 值类型, Data: b}, nil
+}
+
+// 期望整数 把JSON或YAML解码出的数字转换为位数宽的整数, 负数按补码保存。
+func 期望整数(数据 any, 位数 int) (uint64, error) {
+	var s string
+	switch x := 数据.(type) {
+	case nil:
+		return 0, nil
+	case json.Number:
+		s = x.String()
+	case string:
+		s = strings.TrimSpace(x)
+	case float64:
+		if x != math.Trunc(x) || math.Abs(x) > 1<<53 {
+			return 0, fmt.Errorf("%v 不是可以精确表示的整数", x)
+		}
+		s = strconv.FormatFloat(x, 'f', -1, 64)
+	case int:
+		s = strconv.Itoa(x)
+	case int64:
+		s = strconv.FormatInt(x, 10)
+	case uint64:
+		s = strconv.FormatUint(x, 10)
+	default:
+		return 0, fmt.Errorf("整数数据的类型不能是 %T", 数据)
+	}
+	if strings.HasPrefix(s, "-") {
+		n, err := strconv.ParseInt(s, 0, 位数)
+		if err != nil {
+			return 0, 整数错误(err)
+		}
+		return uint64(n) & (1<<位数 - 1), nil
+	}
+	n, err := strconv.ParseUint(s, 0, 位数)
+	if err != nil {
+		return 0, 整数错误(err)
+	}
+	return n, nil
+}
+
+func 整数错误(err error) error {
+	if errors.Is(err, strconv.ErrRange) {
+		return fmt.Errorf("%w: %v", ErrOverflow, err)
+	}
+	return err
+}
+
+func 期望字节(数据 any) ([]byte, error) {
+	switch x := 数据.(type) {
+	case nil:
+		return nil, nil
+	case []byte:
+		return x, nil
+	case string:
+		s := strings.NewReplacer(" ", "", ",", "", ":", "", "\n", "", "\t", "").Replace(x)
+		b, err := hex.DecodeString(s)
+		if err != nil {
+			return nil, fmt.Errorf("二进制数据必须是十六进制字符串: %w", err)
+		}
+		return b, nil
+	case []any:
+		b := make([]byte, len(x))
+		for i, e := range x {
+			n, err := 期望整数(e, 8)
+			if err != nil {
+				return nil, fmt.Errorf("第%d个字节: %w", i, err)
+			}
+			b[i] = byte(n)
+		}
+		return b, nil
+	}
+	return nil, fmt.Errorf("二进制数据的类型不能是 %T", 数据)
+}
+
+// 值相同 比较值的逻辑内容: 文本类型忽略结尾的NUL, REG_MULTI_SZ比较各个文本。
+func 值相同(a, b *I离线值) bool {
+	if a.Type != b.Type {
+		return false
+	}
+	switch a.Type {
+	case SZ, EXPAND_SZ:
+		x, _ := a.I取文本()
+		y, _ := b.I取文本()
+		return x == y
+	case MULTI_SZ:
+		x, _ := 解码文本数组(a, 0)
+		y, _ := 解码文本数组(b, 0)
+		return slices.Equal(x, y)
+	}
+	return bytes.Equal(a.Data, b.Data)
+}
+
+// I计划期望状态 比较期望状态与当前状态, 返回使当前状态符合期望需要的变更, 没有漂移时返回空。
+// '读取'返回相对路径对应的表项及其值(不需要子项), 表项不存在时返回nil和nil。
+// 变更按文档中的顺序排列; 不存在的表项只报告一次 I差异_新增表项 和它声明的所有值。
+func I计划期望状态(期望 *I期望状态, 读取 func(路径 string) (*I离线表项, error)) ([]I离线差异, error) {
+	if err := I检查期望状态(期望); err != nil {
+		return nil, err
+	}
+	var 计划 []I离线差异
+	for _, 项 := range 期望.Keys {
+		路径 := 规范路径(项.Path)
+		当前, err := 读取(路径)
+		if err != nil {
+			return nil, fmt.Errorf("读取 %q: %w", 路径, err)
+		}
+		if 项.Absent {
+			if 当前 != nil {
+				计划 = append(计划, I离线差异{Kind: I差异_删除表项, Path: 路径})
+			}
+			continue
+		}
+		if 当前 == nil {
+			计划 = append(计划, I离线差异{Kind: I差异_新增表项, Path: 路径})
+			当前 = &I离线表项{}
+		}
+		for _, 声明 := range 项.Values {
+			新, _ := 声明.I取值()
+			旧 := 当前.I取值(声明.Name)
+			switch {
+			case 新 == nil && 旧 != nil:
+				计划 = append(计划, I离线差异{Kind: I差异_删除值, Path: 路径, Value: 旧.Name, Old: 旧})
+			case 新 != nil && 旧 == nil:
+				计划 = append(计划, I离线差异{Kind: I差异_新增值, Path: 路径, Value: 新.Name, New: 新})
+			case 新 != nil && !值相同(旧, 新):
+				计划 = append(计划, I离线差异{Kind: I差异_修改值, Path: 路径, Value: 新.Name, Old: 旧, New: 新})
+			}
+		}
+		if 项.Purge {
+			for _, 旧 := range 当前.Values {
+				if !slices.ContainsFunc(项.Values, func(v I期望值) bool { return hive比较名称(v.Name, 旧.Name) == 0 }) {
+					计划 = append(计划, I离线差异{Kind: I差异_删除值, Path: 路径, Value: 旧.Name, Old: 旧})
+				}
+			}
+		}
+	}
+	return 计划, nil
+}
+
+// I计划离线表项 对以根为根项的离线表项树执行 I计划期望状态。
+func I计划离线表项(根 *I离线表项, 期望 *I期望状态) ([]I离线差异, error) {
+	if 根 == nil {
+		return nil, errors.New("注册表类对象为nil")
+	}
+	return I计划期望状态(期望, func(路径 string) (*I离线表项, error) { return 根.I查找(路径), nil })
+}
+
+// I应用到离线表项 在离线表项树上执行 I计划离线表项 返回的变更, 缺少的上级表项会被创建。
+// 计划是幂等的: 应用后再次计划应该没有变更。
+func I应用到离线表项(根 *I离线表项, 计划 []I离线差异) error {
+	if 根 == nil {
+		return errors.New("注册表类对象为nil")
+	}
+	for _, d := range 计划 {
+		var err error
+		switch d.Kind {
+		case I差异_新增表项:
+			_, err = 创建离线路径(根, d.Path)
+		case I差异_删除表项:
+			段 := 拆分路径(d.Path)
+			if 父 := 根.I查找(strings.Join(段[:len(段)-1], `\`)); 父 != nil {
+				父.SubKeys = slices.DeleteFunc(父.SubKeys, func(子 *I离线表项) bool { return hive比较名称(子.Name, 段[len(段)-1]) == 0 })
+			}
+		case I差异_新增值, I差异_修改值:
+			var k *I离线表项
+			if k, err = 创建离线路径(根, d.Path); err == nil {
+				err = k.I设置值(d.New)
+			}
+		case I差异_删除值:
+			if k := 根.I查找(d.Path); k != nil {
+				k.I删除值(d.Value)
+			}
+		default:
+			err = fmt.Errorf("不能应用的变更 %v", d)
+		}
+		if err != nil {
+			return fmt.Errorf("%v: %w", d, err)
+		}
+	}
+	return nil
+}
+
+func 创建离线路径(根 *I离线表项, 路径 string) (*I离线表项, error) {
+	k := 根
+	for _, 段 := range 拆分路径(路径) {
+		var err error
+		if k, _, err = k.I创建子项(段); err != nil {
+			return nil, err
+		}
+	}
+	return k, nil
+}
+
+// I格式化计划 以类似terraform plan的格式描述计划, 每项变更一行, 最后一行是变更数量的汇总。
+func I格式化计划(计划 []I离线差异) string {
+	var b strings.Builder
+	var 新增, 修改, 删除 int
+	for _, d := range 计划 {
+		switch d.Kind {
+		case I差异_新增表项, I差异_新增值:
+			新增++
+			b.WriteString("+ ")
+		case I差异_删除表项, I差异_删除值:
+			删除++
+			b.WriteString("- ")
+		default:
+			修改++
+			b.WriteString("~ ")
+		}
+		b.WriteString(d.String())
+		switch {
+		case d.Old != nil && d.New != nil:
+			fmt.Fprintf(&b, ": %s -> %s", 格式化值数据(d.Old), 格式化值数据(d.New))
+		case d.New != nil:
+			fmt.Fprintf(&b, " = %s", 格式化值数据(d.New))
+		}
+		b.WriteByte('\n')
+	}
+	if len(计划) == 0 {
+		b.WriteString("No changes. The registry matches the desired state.\n")
+	} else {
+		fmt.Fprintf(&b, "Plan: %d to add, %d to change, %d to destroy.\n", 新增, 修改, 删除)
+	}
+	return b.String()
+}
+
+// 格式化值数据 返回值的类型和便于阅读的数据。
+func 格式化值数据(v *I离线值) string {
+	var 数据 string
+	switch v.Type {
+	case SZ, EXPAND_SZ, LINK:
+		s, _ := v.I取文本()
+		数据 = strconv.Quote(s)
+	case MULTI_SZ:
+		a, _ := 解码文本数组(v, 0)
+		数据 = fmt.Sprintf("%q", a)
+	case DWORD, DWORD_BIG_ENDIAN, QWORD:
+		if n, _, err := 解码数字(v); err == nil {
+			数据 = fmt.Sprintf("%d (0x%x)", n, n)
+			break
+		}
+		fallthrough
+	default:
+		数据 = hex.EncodeToString(v.Data)
+	}
+	return I值类型名称(v.Type) + " " + 数据
+}
+
+// I漂移退出码 返回检查模式下的退出码: 计划或检查出错时为 I退出码_错误,
+// 存在漂移时为 I退出码_有漂移, 否则为 I退出码_无漂移。
+func I漂移退出码(计划 []I离线差异, err error) int {
+	switch {
+	case err != nil:
+		return I退出码_错误
+	case len(计划) > 0:
+		return I退出码_有漂移
+	}
+	return I退出码_无漂移
+}
@@ -0,0 +1,174 @@
+package 注册表类_test
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	注册表类 "e.coding.net/gogit/go/gosdk/core/win_registry_cn"
+)
+
+const testDesiredState = `{"keys": [
+	{"path": "Software\\Contoso\\App", "purge": true, "values": [
+		{"name": "Theme", "data": "dark"},
+		{"name": "Level", "type": "dword", "data": 3},
+		{"name": "Offset", "type": "REG_DWORD", "data": -1},
+		{"name": "Big", "type": "qword", "data": "0xffffffffffff"},
+		{"name": "Hosts", "type": "multi_sz", "data": ["a", "b"]},
+		{"name": "Blob", "type": "binary", "data": "de ad be ef"},
+		{"name": "Legacy", "absent": true}]},
+	{"path": "Software\\Contoso\\Old", "absent": true}]}`
+
+func TestDesiredStatePlanApply(t *testing.T) {
+	desired, err := 注册表类.I解析期望状态([]byte(testDesiredState))
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := &注册表类.I离线表项{Name: "ROOT", SubKeys: []*注册表类.I离线表项{
+		{Name: "SOFTWARE", SubKeys: []*注册表类.I离线表项{
+			{Name: "Contoso", SubKeys: []*注册表类.I离线表项{
+				{Name: "App", Values: []*注册表类.I离线值{
+					sz("Theme", "light"), dword("Level", 3), sz("Legacy", "x"), sz("Extra", "y"),
+					{Name: "Hosts", Type: 注册表类.MULTI_SZ, Data: []byte("a\x00\x00\x00b\x00\x00\x00\x00\x00")},
+				}},
+				{Name: "Old", SubKeys: []*注册表类.I离线表项{{Name: "Child"}}},
+			}},
+		}},
+	}}
+
+	plan, err := 注册表类.I计划离线表项(root, desired)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, d := range plan {
+		got = append(got, d.String())
+	}
+	want := []string{
+		`value changed Software\Contoso\App ["Theme"]`,
+		`value added Software\Contoso\App ["Offset"]`,
+		`value added Software\Contoso\App ["Big"]`,
+		`value added Software\Contoso\App ["Blob"]`,
+		`value deleted Software\Contoso\App ["Legacy"]`,
+		`value deleted Software\Contoso\App ["Extra"]`,
+		`key deleted Software\Contoso\Old`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("plan:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if code := 注册表类.I漂移退出码(plan, nil); code != 注册表类.I退出码_有漂移 {
+		t.Errorf("exit code with drift: got %d", code)
+	}
+	text := 注册表类.I格式化计划(plan)
+	for _, s := range []string{
+		`~ value changed Software\Contoso\App ["Theme"]: REG_SZ "light" -> REG_SZ "dark"`,
+		`+ value added Software\Contoso\App ["Offset"] = REG_DWORD 4294967295 (0xffffffff)`,
+		"Plan: 3 to add, 1 to change, 3 to destroy.",
+	} {
+		if !strings.Contains(text, s) {
+			t.Errorf("formatted plan missing %q:\n%s", s, text)
+		}
+	}
+
+	if err := 注册表类.I应用到离线表项(root, plan); err != nil {
+		t.Fatal(err)
+	}
+	plan, err = 注册表类.I计划离线表项(root, desired)
+	if err != nil || len(plan) != 0 {
+		t.Errorf("plan after apply: got %v, %v", plan, err)
+	}
+	if code := 注册表类.I漂移退出码(plan, err); code != 注册表类.I退出码_无漂移 {
+		t.Errorf("exit code without drift: got %d", code)
+	}
+	if v := root.I查找(`Software\Contoso\App`).I取值("Blob"); v == nil || string(v.Data) != "\xde\xad\xbe\xef" {
+		t.Errorf("Blob: got %v", v)
+	}
+
+	// A missing key is planned once, with all its values.
+	plan, _ = 注册表类.I计划离线表项(&注册表类.I离线表项{}, desired)
+	if len(plan) != 7 || plan[0].Kind != 注册表类.I差异_新增表项 {
+		t.Errorf("plan on empty root: got %v", plan)
+	}
+}
+
+func TestDesiredStateValidation(t *testing.T) {
+	for _, doc := range []string{
+		`{"keys": [{"path": "A"}, {"path": "a"}]}`,
+		`{"keys": [{"path": "A", "absent": true}, {"path": "A\\B"}]}`,
+		`{"keys": [{"path": "", "absent": true}]}`,
+		`{"keys": [{"path": "A", "values": [{"name": "x"}, {"name": "X"}]}]}`,
+		`{"keys": [{"path": "A", "values": [{"name": "x", "type": "dword", "data": 4294967296}]}]}`,
+		`{"keys": [{"path": "A", "values": [{"name": "x", "type": "float"}]}]}`,
+		`{"keys": [{"path": "A", "values": [{"name": "x", "type": "binary", "data": "xyz"}]}]}`,
+		`{"keys": [{"path": "A", "unknown": 1}]}`,
+	} {
+		if _, err := 注册表类.I解析期望状态([]byte(doc)); err == nil {
+			t.Errorf("%s: want error", doc)
+		}
+	}
+	_, err := 注册表类.I计划离线表项(&注册表类.I离线表项{}, &注册表类.I期望状态{Keys: []注册表类.I期望表项{
+		{Path: "A", Values: []注册表类.I期望值{{Name: "x", Type: "dword", Data: 1.5}}},
+	}})
+	if err == nil {
+		t.Error("non-integer DWORD: want error")
+	}
+	if code := 注册表类.I漂移退出码(nil, errors.New("x")); code != 注册表类.I退出码_错误 {
+		t.Errorf("exit code on error: got %d", code)
+	}
+}
+
+func TestDesiredStateYAML(t *testing.T) {
+	const doc = `keys:
+  - path: 'Software\Contoso\App'
+    purge: true
+    values:
+      - name: Theme
+        data: dark
+      - {name: Level, type: dword, data: 3}
+`
+	if _, err := 注册表类.I解析期望状态([]byte(doc), 注册表类.I格式_YAML); err == nil {
+		t.Error("flow mappings are not supported: want error")
+	}
+	yamlDoc := `# same document as testDesiredState
+keys:
+  - path: 'Software\Contoso\App'
+    purge: true
+    values:
+      - name: Theme
+        data: dark
+      - name: Level
+        type: dword
+        data: 3
+      - name: Offset
+        type: REG_DWORD
+        data: -1
+      - name: Big
+        type: qword
+        data: "0xffffffffffff"
+      - name: Hosts
+        type: multi_sz
+        data: [a, b]
+      - name: Blob
+        type: binary
+        data: de ad be ef
+      - name: Legacy
+        absent: true
+  - path: Software\Contoso\Old
+    absent: true
+`
+	fromYAML, err := 注册表类.I解析期望状态([]byte(yamlDoc), 注册表类.I格式_YAML)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromJSON, err := 注册表类.I解析期望状态([]byte(testDesiredState), 注册表类.I格式_JSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(fromYAML, fromJSON) {
+		t.Errorf("YAML document:\n%+v\nJSON document:\n%+v", fromYAML, fromJSON)
+	}
+	if _, err := 注册表类.I解析期望状态([]byte("keys:\n  - path: A\n    unknown: 1\n"), 注册表类.I格式_YAML); err == nil {
+		t.Error("unknown field in YAML: want error")
+	}
+}
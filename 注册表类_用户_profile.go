@@ -0,0 +1,91 @@
+package 注册表类
+
+import (
+	"fmt"
+	"strings"
+)
+
+// I用户配置列表路径 是ProfileList相对于HKEY_LOCAL_MACHINE的路径。
+const I用户配置列表路径 = `SOFTWARE\Microsoft\Windows NT\CurrentVersion\ProfileList`
+
+// I用户配置 描述ProfileList中的一个用户配置文件。
+type I用户配置 struct {
+	SID         string
+	ProfilePath string // ProfileImagePath, 在线读取时已展开环境变量
+	Account     string // 账户名, 只在在线读取且能解析SID时填充
+	Domain      string
+	Loaded      bool // 该用户的配置单元已加载到HKEY_USERS
+}
+
+// 是用户SID 报告HKEY_USERS下的子项名称是否是用户的SID, 排除 .DEFAULT 和 <SID>_Classes。
+func 是用户SID(名称 string) bool {
+	if !strings.HasPrefix(strings.ToUpper(名称), "S-") || strings.HasSuffix(strings.ToUpper(名称), "_CLASSES") {
+		return false
+	}
+	_, err := I编码SID(名称)
+	return err == nil
+}
+
+// I解析用户配置列表 从离线SOFTWARE配置单元的根项软件中读取ProfileList。
+// '已加载'是HKEY_USERS下的子项名称, 用于设置 Loaded, 可以为nil。
+// 离线时无法查询账户名, ProfilePath也不展开环境变量。
+func I解析用户配置列表(软件 *I离线表项, 已加载 []string) []I用户配置 {
+	列表 := 软件.I查找(strings.TrimPrefix(I用户配置列表路径, `SOFTWARE\`))
+	if 列表 == nil {
+		return nil
+	}
+	var 结果 []I用户配置
+	for _, 子 := range 列表.SubKeys {
+		if !是用户SID(子.Name) {
+			continue
+		}
+		配置 := I用户配置{SID: 子.Name, Loaded: 包含名称(已加载, 子.Name)}
+		配置.ProfilePath, _ = 子.I取值("ProfileImagePath").I取文本()
+		结果 = append(结果, 配置)
+	}
+	return 结果
+}
+
+// 包含名称 报告名称列表中是否有不区分大小写等于'名称'的项。
+func 包含名称(列表 []string, 名称 string) bool {
+	for _, s := range 列表 {
+		if hive比较名称(s, 名称) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// I取已加载用户SID 返回命名空间 HKEY_USERS 下已挂载配置单元的用户SID,
+// 不包括 .DEFAULT 和 <SID>_Classes。与在线的 I取已加载用户SID 对应。
+func (n *I离线命名空间) I取已加载用户SID() []string {
+	var 结果 []string
+	for _, 子 := range n.用户.SubKeys {
+		if 是用户SID(子.Name) {
+			结果 = append(结果, 子.Name)
+		}
+	}
+	return 结果
+}
+
+// I取用户配置列表 从挂载在 HKLM\SOFTWARE 的配置单元读取ProfileList,
+// 按 HKEY_USERS 下已挂载的配置单元设置 Loaded。没有挂载SOFTWARE时返回nil。
+func (n *I离线命名空间) I取用户配置列表() []I用户配置 {
+	软件 := n.本机.I取子项("SOFTWARE")
+	if 软件 == nil {
+		return nil
+	}
+	return I解析用户配置列表(软件, n.I取已加载用户SID())
+}
+
+// I打开用户表项 返回 HKEY_USERS\<SID>, 即该用户的 HKEY_CURRENT_USER,
+// 与 I设置当前用户(SID) 之后通过 HKCU 看到的是同一个表项。配置单元没有挂载时返回 ErrOfflineNotExist。
+func (n *I离线命名空间) I打开用户表项(SID string) (*I离线表项, error) {
+	if !是用户SID(SID) {
+		return nil, fmt.Errorf("%q 不是用户SID", SID)
+	}
+	if k := n.用户.I取子项(SID); k != nil {
+		return k, nil
+	}
+	return nil, ErrOfflineNotExist
+}
@@ -0,0 +1,96 @@
+package 注册表类_test
+
+import (
+	"reflect"
+	"testing"
+
+	注册表类 "e.coding.net/gogit/go/gosdk/core/win_registry_cn"
+)
+
+func TestOfflineProfileList(t *testing.T) {
+	software := &注册表类.I离线表项{Name: "SOFTWARE"}
+	list, _, _ := software.I查找("").I创建子项("Microsoft")
+	for _, name := range []string{"Windows NT", "CurrentVersion", "ProfileList"} {
+		list, _, _ = list.I创建子项(name)
+	}
+	for sid, path := range map[string]string{
+		"S-1-5-18":                `%systemroot%\system32\config\systemprofile`,
+		"S-1-5-21-1-2-3-1001":     `C:\Users\alice`,
+		"S-1-5-21-1-2-3-1002.bak": `C:\Users\old`,
+	} {
+		k, _, _ := list.I创建子项(sid)
+		k.I设置值(&注册表类.I离线值{Name: "ProfileImagePath", Type: 注册表类.EXPAND_SZ, Data: 注册表类.I新建文本值("", path).Data})
+	}
+
+	got := 注册表类.I解析用户配置列表(software, []string{".DEFAULT", "s-1-5-21-1-2-3-1001", "S-1-5-21-1-2-3-1001_Classes"})
+	want := []注册表类.I用户配置{
+		{SID: "S-1-5-18", ProfilePath: `%systemroot%\system32\config\systemprofile`},
+		{SID: "S-1-5-21-1-2-3-1001", ProfilePath: `C:\Users\alice`, Loaded: true},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+	for _, w := range want {
+		found := false
+		for _, g := range got {
+			if reflect.DeepEqual(g, w) {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("missing %+v in %+v", w, got)
+		}
+	}
+	if 注册表类.I解析用户配置列表(&注册表类.I离线表项{Name: "SOFTWARE"}, nil) != nil {
+		t.Error("hive without ProfileList should give no profiles")
+	}
+}
+
+func TestOfflineNamespaceUsers(t *testing.T) {
+	const sid = "S-1-5-21-1-2-3-1001"
+	software := &注册表类.I离线表项{Name: "ROOT"}
+	list := software
+	for _, name := range []string{"Microsoft", "Windows NT", "CurrentVersion", "ProfileList", sid} {
+		list, _, _ = list.I创建子项(name)
+	}
+	list.I设置值(sz("ProfileImagePath", `C:\Users\alice`))
+	ntuser := &注册表类.I离线表项{Name: "ROOT", SubKeys: []*注册表类.I离线表项{{Name: "Environment"}}}
+
+	ns := 注册表类.I新建离线命名空间()
+	for path, root := range map[string]*注册表类.I离线表项{
+		`HKLM\SOFTWARE`:           software,
+		`HKU\.DEFAULT`:            {Name: "ROOT"},
+		`HKU\` + sid:              ntuser,
+		`HKU\` + sid + "_Classes": {Name: "ROOT"},
+	} {
+		if err := ns.I加载(path, root); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if sids := ns.I取已加载用户SID(); !reflect.DeepEqual(sids, []string{sid}) {
+		t.Errorf("loaded SIDs: got %q", sids)
+	}
+	want := []注册表类.I用户配置{{SID: sid, ProfilePath: `C:\Users\alice`, Loaded: true}}
+	if got := ns.I取用户配置列表(); !reflect.DeepEqual(got, want) {
+		t.Errorf("profiles: got %+v, want %+v", got, want)
+	}
+
+	// 用户的表项与设置当前用户后的 HKCU 是同一份数据。
+	user, err := ns.I打开用户表项(sid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ns.I设置当前用户(sid); err != nil {
+		t.Fatal(err)
+	}
+	user.I查找("Environment").I设置值(sz("TEMP", `C:\Temp`))
+	if v, _ := ns.I取值(`HKCU\Environment`, "TEMP"); text(v) != `C:\Temp` {
+		t.Errorf("write under HKU\\<SID> is not visible under HKCU: got %q", text(v))
+	}
+	if _, err := ns.I打开用户表项("S-1-5-21-1-2-3-1002"); err != 注册表类.ErrOfflineNotExist {
+		t.Errorf("user without a loaded hive: got %v", err)
+	}
+	if _, err := ns.I打开用户表项(".DEFAULT"); err == nil {
+		t.Error(".DEFAULT is not a user SID")
+	}
+}
@@ -0,0 +1,88 @@
+//go:build windows
+// +build windows
+
+package 注册表类
+
+import (
+	"errors"
+
+	"golang.org/x/sys/windows"
+)
+
+// I取已加载用户SID 返回HKEY_USERS下已加载配置单元的用户SID,
+// 不包括 .DEFAULT 和 <SID>_Classes。
+func I取已加载用户SID() ([]string, error) {
+	名称, err := USERS.I取所有子项名称(-1)
+	if err != nil {
+		return nil, err
+	}
+	var 结果 []string
+	for _, s := range 名称 {
+		if 是用户SID(s) {
+			结果 = append(结果, s)
+		}
+	}
+	return 结果, nil
+}
+
+// I取用户配置列表 读取本机ProfileList中的用户配置文件,
+// 展开配置文件路径中的环境变量, 并用LookupAccountSid解析账户名。
+// 无法解析的SID(例如已删除的账户)Account和Domain为空。
+func I取用户配置列表() ([]I用户配置, error) {
+	已加载, err := I取已加载用户SID()
+	if err != nil {
+		return nil, err
+	}
+	列表, _, err := I打开表项Ex(LOCAL_MACHINE, I用户配置列表路径, I打开选项{Access: READ})
+	if err != nil {
+		return nil, err
+	}
+	defer 列表.I关闭()
+	名称, err := 列表.I取所有子项名称(-1)
+	if err != nil {
+		return nil, err
+	}
+	var 结果 []I用户配置
+	for _, s := range 名称 {
+		if !是用户SID(s) {
+			continue
+		}
+		配置 := I用户配置{SID: s, Loaded: 包含名称(已加载, s)}
+		if k, err := I打开表项(列表, s, QUERY_VALUE); err == nil {
+			if 路径, _, err := k.I取文本值("ProfileImagePath"); err == nil {
+				if 展开, err := I解析环境变量(路径); err == nil {
+					路径 = 展开
+				}
+				配置.ProfilePath = 路径
+			}
+			k.I关闭()
+		}
+		if sid, err := windows.StringToSid(s); err == nil {
+			配置.Account, 配置.Domain, _, _ = sid.LookupAccount("")
+		}
+		结果 = append(结果, 配置)
+	}
+	return 结果, nil
+}
+
+// I打开用户表项 打开HKEY_USERS下SID对应的用户配置单元, 相当于该用户的CURRENT_USER。
+// SID也可以是SDDL别名, 例如 "SY"。该用户的配置单元必须已经加载。
+func I打开用户表项(SID string, 访问权限 ...uint32) (*Key结构, error) {
+	s, err := sddl解析SID(SID)
+	if err != nil {
+		return nil, err
+	}
+	if !是用户SID(s) {
+		return nil, errors.New("不是用户SID: " + SID)
+	}
+	return I打开表项(USERS, s, 访问权限...)
+}
+
+// I打开用户类表项 打开HKEY_USERS下的<SID>_Classes, 即该用户的HKCU\Software\Classes。
+func I打开用户类表项(SID string, 访问权限 ...uint32) (*Key结构, error) {
+	s, err := sddl解析SID(SID)
+	if err != nil {
+		return nil, err
+	}
+	return I打开表项(USERS, s+"_Classes", 访问权限...)
+}
@@ -0,0 +1,666 @@
+package 注册表类
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// I问题级别 表示配置单元校验问题的严重程度。
+type I问题级别 int
+
+const (
+	I问题级别_警告 I问题级别 = iota + 1 // 结构可用, 但与Windows写出的配置单元不一致, 例如存在未引用的单元
+	I问题级别_错误                  // 结构损坏, Windows会拒绝加载或读到错误的数据
+)
+
+func (l I问题级别) String() string {
+	switch l {
+	case I问题级别_警告:
+		return "warning"
+	case I问题级别_错误:
+		return "error"
+	}
+	return fmt.Sprintf("I问题级别(%d)", int(l))
+}
+
+// I配置单元问题 描述校验配置单元时发现的一个问题。
+type I配置单元问题 struct {
+	Severity I问题级别
+	Code     string // 问题类别, 例如 "base.checksum"、"index.order"、"security.refcount"
+	Offset   int64  // 相对文件起始的字节偏移, -1表示不适用
+	Path     string // 相关表项相对根项的路径, 未知时为空
+	Message  string
+}
+
+func (p I配置单元问题) String() string {
+	s := fmt.Sprintf("%s %s", p.Severity, p.Code)
+	if p.Offset >= 0 {
+		s += fmt.Sprintf(" @%#x", p.Offset)
+	}
+	if p.Path != "" {
+		s += fmt.Sprintf(" [%s]", p.Path)
+	}
+	return s + ": " + p.Message
+}
+
+// I配置单元报告 是 I校验配置单元 返回的结构化校验报告。
+type I配置单元报告 struct {
+	Issues []I配置单元问题
+
+	Hbins               int   // hbin数量
+	AllocatedCells      int   // 已分配单元数量
+	AllocatedBytes      int64 // 已分配单元的总字节数(含单元头)
+	FreeCells           int   // 空闲单元数量
+	FreeBytes           int64 // 空闲单元的总字节数(含单元头)
+	Keys                int   // 可以到达的表项数量
+	Values              int   // 可以到达的值数量
+	SecurityDescriptors int   // 被引用的安全描述符数量
+}
+
+// I是否有效 报告配置单元是否没有错误级别的问题。
+func (r *I配置单元报告) I是否有效() bool {
+	return len(r.I取错误()) == 0
+}
+
+// I取错误 返回报告中所有错误级别的问题。
+func (r *I配置单元报告) I取错误() []I配置单元问题 {
+	var 结果 []I配置单元问题
+	for _, p := range r.Issues {
+		if p.Severity == I问题级别_错误 {
+			结果 = append(结果, p)
+		}
+	}
+	return 结果
+}
+
+// I校验配置单元 检查regf格式配置单元的完整性并返回结构化报告。
+// 检查内容包括基块校验和与版本、hbin头、单元大小与分配情况、
+// 子项索引的顺序与计数是否与nk记录一致、值列表单元,
+// 以及安全描述符(sk)的引用计数和链表。
+func I校验配置单元(数据 []byte) *I配置单元报告 {
+	w := hive新遍历器(数据)
+	w.遍历()
+	return w.报告
+}
+
+// I修复配置单元 从损坏的配置单元中抢救所有能够完整读取的表项和值,
+// 重新生成一个干净的配置单元。返回的报告描述原文件中的问题。
+// 根表项本身无法读取时返回 ErrCorruptHive。
+func I修复配置单元(数据 []byte) ([]byte, *I配置单元报告, error) {
+	w := hive新遍历器(数据)
+	根 := w.遍历()
+	if 根 == nil {
+		return nil, w.报告, fmt.Errorf("%w: 无法读取根表项", ErrCorruptHive)
+	}
+	新数据, err := I生成配置单元(根)
+	if err != nil {
+		return nil, w.报告, err
+	}
+	return 新数据, w.报告, nil
+}
+
+type hive单元 struct {
+	大小  uint32
+	已分配 bool
+}
+
+// hive遍历器 容错地读取配置单元: 记录遇到的问题, 跳过无法读取的表项和值,
+// 同时构建能够抢救的表项树。解析、校验和修复都使用它。
+type hive遍历器 struct {
+	数据   []byte
+	报告   *I配置单元报告
+	单元   map[uint32]hive单元 // 扫描hbin得到的全部单元, 键为单元偏移
+	引用   map[uint32]int    // 除sk以外的单元被引用的次数
+	安全计数 map[uint32]uint32 // sk单元被nk引用的次数
+	已访问  map[uint32]bool
+}
+
+func hive新遍历器(数据 []byte) *hive遍历器 {
+	return &hive遍历器{
+		数据:   数据,
+		报告:   &I配置单元报告{},
+		单元:   map[uint32]hive单元{},
+		引用:   map[uint32]int{},
+		安全计数: map[uint32]uint32{},
+		已访问:  map[uint32]bool{},
+	}
+}
+
+func (w *hive遍历器) 问题(级别 I问题级别, 代码 string, 偏移 int64, 路径 string, 格式 string, 参数 ...interface{}) {
+	w.报告.Issues = append(w.报告.Issues, I配置单元问题{
+		Severity: 级别,
+		Code:     代码,
+		Offset:   偏移,
+		Path:     路径,
+		Message:  fmt.Sprintf(格式, 参数...),
+	})
+}
+
+// 文件偏移 把单元偏移换算为相对文件起始的偏移。
+func 文件偏移(单元偏移 uint32) int64 {
+	return int64(单元偏移) + hive基块大小
+}
+
+// 遍历 执行全部检查并返回能够抢救的根表项。
+func (w *hive遍历器) 遍历() *I离线表项 {
+	根偏移, 数据大小, ok := w.检查基块()
+	if !ok {
+		return nil
+	}
+	w.扫描hbin(数据大小)
+	根 := w.遍历表项(根偏移, 0, "", true)
+	w.检查安全单元()
+	w.检查未引用单元()
+	w.报告.SecurityDescriptors = len(w.安全计数)
+	return 根
+}
+
+func (w *hive遍历器) 检查基块() (根偏移 uint32, 数据大小 uint32, ok bool) {
+	if len(w.数据) < hive基块大小 {
+		w.问题(I问题级别_错误, "base.truncated", 0, "", "文件只有 %d 字节, 不足一个基块", len(w.数据))
+		return 0, 0, false
+	}
+	b := w.数据[:hive基块大小]
+	if string(b[:4]) != "regf" {
+		w.问题(I问题级别_错误, "base.signature", 0, "", "基块签名为 %q, 应为 \"regf\"", b[:4])
+		return 0, 0, false
+	}
+	if 主, 次 := le.Uint32(b[0x04:]), le.Uint32(b[0x08:]); 主 != 次 {
+		w.问题(I问题级别_警告, "base.sequence", 0x04, "", "主序号 %d 与次序号 %d 不同, 配置单元未正常关闭, 可能需要重放日志", 主, 次)
+	}
+	if 记录, 实际 := le.Uint32(b[0x1fc:]), hive基块校验和(b); 记录 != 实际 {
+		w.问题(I问题级别_错误, "base.checksum", 0x1fc, "", "基块校验和为 %#x, 计算结果为 %#x", 记录, 实际)
+	}
+	if 主版本, 次版本 := le.Uint32(b[0x14:]), le.Uint32(b[0x18:]); 主版本 != 1 || 次版本 < 2 || 次版本 > 6 {
+		w.问题(I问题级别_错误, "base.version", 0x14, "", "不支持的格式版本 %d.%d", 主版本, 次版本)
+	}
+	if t := le.Uint32(b[0x1c:]); t != 0 {
+		w.问题(I问题级别_警告, "base.type", 0x1c, "", "文件类型为 %d, 不是主配置单元文件", t)
+	}
+	if f := le.Uint32(b[0x20:]); f != 1 {
+		w.问题(I问题级别_警告, "base.format", 0x20, "", "文件格式为 %d, 应为 1", f)
+	}
+
+	可用 := uint32((len(w.数据) - hive基块大小) &^ (hbin对齐 - 1))
+	数据大小 = le.Uint32(b[0x28:])
+	switch {
+	case 数据大小 == 0 || 数据大小%hbin对齐 != 0:
+		w.问题(I问题级别_错误, "base.size", 0x28, "", "hbin数据大小 %#x 不是 %#x 的正整数倍", 数据大小, hbin对齐)
+		数据大小 = 可用
+	case uint64(数据大小) > uint64(len(w.数据)-hive基块大小):
+		w.问题(I问题级别_错误, "base.size", 0x28, "", "hbin数据大小 %#x 超出文件末尾, 文件被截断", 数据大小)
+		数据大小 = 可用
+	case uint64(数据大小) < uint64(len(w.数据)-hive基块大小):
+		w.问题(I问题级别_警告, "base.trailing", 文件偏移(数据大小), "", "hbin数据之后还有 %d 字节", uint64(len(w.数据)-hive基块大小)-uint64(数据大小))
+	}
+	return le.Uint32(b[0x24:]), 数据大小, true
+}
+
+func (w *hive遍历器) 扫描hbin(数据大小 uint32) {
+	结束 := hive基块大小 + int(数据大小)
+	for 位置 := hive基块大小; 位置+hbin头大小 <= 结束; {
+		h := w.数据[位置:]
+		if string(h[:4]) != "hbin" {
+			w.问题(I问题级别_错误, "hbin.signature", int64(位置), "", "hbin签名为 %q", h[:4])
+			位置 += hbin对齐
+			continue
+		}
+		if 偏移 := le.Uint32(h[4:]); 偏移 != uint32(位置-hive基块大小) {
+			w.问题(I问题级别_错误, "hbin.offset", int64(位置)+4, "", "hbin偏移字段为 %#x, 实际位置为 %#x", 偏移, 位置-hive基块大小)
+		}
+		大小 := int(le.Uint32(h[8:]))
+		if 大小 < hbin对齐 || 大小%hbin对齐 != 0 || 位置+大小 > 结束 {
+			w.问题(I问题级别_错误, "hbin.size", int64(位置)+8, "", "hbin大小 %#x 无效", 大小)
+			大小 = hbin对齐
+		}
+		w.报告.Hbins++
+		w.扫描单元(位置, 大小)
+		位置 += 大小
+	}
+}
+
+func (w *hive遍历器) 扫描单元(hbin起始, hbin大小 int) {
+	结束 := hbin起始 + hbin大小
+	for 位置 := hbin起始 + hbin头大小; 位置 < 结束; {
+		原始 := int32(le.Uint32(w.数据[位置:]))
+		大小, 已分配 := int64(原始), 原始 < 0
+		if 已分配 {
+			大小 = -大小
+		}
+		if 大小 < 8 || 大小%8 != 0 || int64(位置)+大小 > int64(结束) {
+			w.问题(I问题级别_错误, "cell.size", int64(位置), "", "单元大小 %d 无效, 跳过hbin中余下的 %d 字节", 原始, 结束-位置)
+			return
+		}
+		w.单元[uint32(位置-hive基块大小)] = hive单元{uint32(大小), 已分配}
+		if 已分配 {
+			w.报告.AllocatedCells++
+			w.报告.AllocatedBytes += 大小
+		} else {
+			w.报告.FreeCells++
+			w.报告.FreeBytes += 大小
+		}
+		位置 += int(大小)
+	}
+}
+
+// 取单元 返回已分配单元的内容部分, 偏移不是已分配单元的起始位置时返回false。
+func (w *hive遍历器) 取单元(偏移 uint32) ([]byte, bool) {
+	c, ok := w.单元[偏移]
+	if !ok || !c.已分配 {
+		return nil, false
+	}
+	位置 := hive基块大小 + int(偏移)
+	return w.数据[位置+4 : 位置+int(c.大小)], true
+}
+
+// 描述单元 说明偏移为什么不是可用的单元, 用于问题消息。
+func (w *hive遍历器) 描述单元(偏移 uint32) string {
+	if 偏移 == hive无效偏移 {
+		return "偏移为空"
+	}
+	c, ok := w.单元[偏移]
+	switch {
+	case !ok:
+		return fmt.Sprintf("偏移 %#x 不是单元的起始位置", 偏移)
+	case !c.已分配:
+		return fmt.Sprintf("偏移 %#x 指向空闲单元", 偏移)
+	}
+	return fmt.Sprintf("偏移 %#x 的单元内容无效", 偏移)
+}
+
+// 读取表项名称 读取nk单元中的名称, 不记录引用。
+func (w *hive遍历器) 读取表项名称(偏移 uint32) (string, bool) {
+	c, ok := w.取单元(偏移)
+	if !ok || len(c) < nk固定大小 || string(c[:2]) != "nk" {
+		return "", false
+	}
+	n := int(le.Uint16(c[0x48:]))
+	if nk固定大小+n > len(c) {
+		return "", false
+	}
+	return hive解码名称(c[nk固定大小:nk固定大小+n], le.Uint16(c[0x02:])&hive标志_压缩名称 != 0), true
+}
+
+func (w *hive遍历器) 遍历表项(偏移, 父偏移 uint32, 路径 string, 是根 bool) *I离线表项 {
+	if w.已访问[偏移] {
+		w.问题(I问题级别_错误, "key.cycle", 文件偏移(偏移), 路径, "表项单元 %#x 被重复引用, 子项索引形成环或共用表项", 偏移)
+		return nil
+	}
+	c, ok := w.取单元(偏移)
+	if !ok {
+		w.问题(I问题级别_错误, "key.cell", -1, 路径, "表项单元无效: %s", w.描述单元(偏移))
+		return nil
+	}
+	w.已访问[偏移] = true
+	w.引用[偏移]++
+	if len(c) < nk固定大小 || string(c[:2]) != "nk" {
+		w.问题(I问题级别_错误, "key.signature", 文件偏移(偏移), 路径, "单元不是nk记录")
+		return nil
+	}
+	标志 := le.Uint16(c[0x02:])
+	名称, ok := w.读取表项名称(偏移)
+	if !ok {
+		w.问题(I问题级别_错误, "key.name", 文件偏移(偏移), 路径, "名称长度 %d 超出单元大小", le.Uint16(c[0x48:]))
+		return nil
+	}
+	if !是根 {
+		路径 = hive连接路径(路径, 名称)
+	}
+	项 := &I离线表项{
+		Name:    名称,
+		ModTime: hive解析filetime(le.Uint64(c[0x04:])),
+		Flags:   标志 &^ hive标志_压缩名称,
+	}
+	w.报告.Keys++
+	if 是根 && 标志&hive标志_根项 == 0 {
+		w.问题(I问题级别_警告, "key.root", 文件偏移(偏移), 路径, "根表项没有设置KEY_HIVE_ENTRY标志")
+	}
+	if !是根 && le.Uint32(c[0x10:]) != 父偏移 {
+		w.问题(I问题级别_错误, "key.parent", 文件偏移(偏移)+0x14, 路径, "父项偏移为 %#x, 应为 %#x", le.Uint32(c[0x10:]), 父偏移)
+	}
+
+	if n := int(le.Uint16(c[0x4a:])); n > 0 {
+		类名偏移 := le.Uint32(c[0x30:])
+		if cc, ok := w.取单元(类名偏移); !ok || len(cc) < n {
+			w.问题(I问题级别_错误, "key.class", 文件偏移(偏移)+0x34, 路径, "类名单元无效: %s", w.描述单元(类名偏移))
+		} else {
+			w.引用[类名偏移]++
+			项.Class = hive解码UTF16(cc[:n])
+		}
+	}
+
+	项.SecurityDescriptor = w.引用安全单元(le.Uint32(c[0x2c:]), 偏移, 路径)
+
+	if n := le.Uint32(c[0x24:]); n > 0 {
+		项.Values = w.遍历值列表(le.Uint32(c[0x28:]), n, 路径)
+	}
+
+	子偏移 := w.遍历子项索引(le.Uint32(c[0x1c:]), le.Uint32(c[0x14:]), 偏移, 路径)
+	已有 := map[string]bool{}
+	for _, o := range 子偏移 {
+		子 := w.遍历表项(o, 偏移, 路径, false)
+		if 子 == nil {
+			continue
+		}
+		if err := hive检查名称(子.Name, false); err != nil {
+			w.问题(I问题级别_错误, "key.name", 文件偏移(o), 路径, "子项名称无效: %v", err)
+			continue
+		}
+		if 键 := string(utf16转字节(hive大写(子.Name))); 已有[键] {
+			w.问题(I问题级别_错误, "key.duplicate", 文件偏移(o), hive连接路径(路径, 子.Name), "子项名称重复")
+			continue
+		} else {
+			已有[键] = true
+		}
+		项.SubKeys = append(项.SubKeys, 子)
+	}
+	return 项
+}
+
+func (w *hive遍历器) 遍历子项索引(偏移, 期望 uint32, 父偏移 uint32, 路径 string) []uint32 {
+	if 期望 == 0 {
+		return nil
+	}
+	var 结果 []uint32
+	w.遍历索引单元(偏移, 路径, &结果, false)
+	if uint32(len(结果)) != 期望 {
+		w.问题(I问题级别_错误, "index.count", 文件偏移(父偏移)+0x18, 路径, "nk记录的子项数为 %d, 索引中有 %d 项", 期望, len(结果))
+	}
+	var 前一个 string
+	for i, o := range 结果 {
+		名称, ok := w.读取表项名称(o)
+		if !ok {
+			continue
+		}
+		if i > 0 && hive比较名称(前一个, 名称) >= 0 {
+			w.问题(I问题级别_错误, "index.order", 文件偏移(偏移), 路径, "子项索引未排序: %q 出现在 %q 之后", 名称, 前一个)
+		}
+		前一个 = 名称
+	}
+	return 结果
+}
+
+func (w *hive遍历器) 遍历索引单元(偏移 uint32, 路径 string, 结果 *[]uint32, 嵌套 bool) {
+	c, ok := w.取单元(偏移)
+	if !ok || len(c) < 4 {
+		w.问题(I问题级别_错误, "index.cell", -1, 路径, "子项索引单元无效: %s", w.描述单元(偏移))
+		return
+	}
+	w.引用[偏移]++
+	签名, n := string(c[:2]), int(le.Uint16(c[2:]))
+	条目大小 := 8
+	if 签名 == "li" || 签名 == "ri" {
+		条目大小 = 4
+	}
+	if 4+条目大小*n > len(c) {
+		w.问题(I问题级别_错误, "index.size", 文件偏移(偏移), 路径, "%s 索引的 %d 个条目超出单元大小", 签名, n)
+		n = (len(c) - 4) / 条目大小
+	}
+	switch 签名 {
+	case "li":
+		for i := 0; i < n; i++ {
+			*结果 = append(*结果, le.Uint32(c[4+4*i:]))
+		}
+	case "lf", "lh":
+		for i := 0; i < n; i++ {
+			o, 提示 := le.Uint32(c[4+8*i:]), le.Uint32(c[8+8*i:])
+			*结果 = append(*结果, o)
+			名称, ok := w.读取表项名称(o)
+			if !ok {
+				continue
+			}
+			if 签名 == "lh" && 提示 != hive名称哈希(名称) {
+				w.问题(I问题级别_错误, "index.hash", 文件偏移(偏移)+int64(8+8*i), 路径, "子项 %q 的哈希为 %#x, 应为 %#x", 名称, 提示, hive名称哈希(名称))
+			}
+			if 签名 == "lf" && 提示 != hive名称提示(名称) {
+				w.问题(I问题级别_警告, "index.hint", 文件偏移(偏移)+int64(8+8*i), 路径, "子项 %q 的名称提示与名称不符", 名称)
+			}
+		}
+	case "ri":
+		if 嵌套 {
+			w.问题(I问题级别_错误, "index.nested", 文件偏移(偏移), 路径, "ri索引不能嵌套")
+			return
+		}
+		for i := 0; i < n; i++ {
+			w.遍历索引单元(le.Uint32(c[4+4*i:]), 路径, 结果, true)
+		}
+	default:
+		w.问题(I问题级别_错误, "index.signature", 文件偏移(偏移), 路径, "未知的子项索引签名 %q", 签名)
+	}
+}
+
+// hive名称提示 计算lf索引中保存的名称前4个字符。
+func hive名称提示(名称 string) uint32 {
+	var b [4]byte
+	for i, c := range []rune(名称) {
+		if i == 4 {
+			break
+		}
+		b[i] = byte(c)
+	}
+	return le.Uint32(b[:])
+}
+
+func (w *hive遍历器) 遍历值列表(偏移, 数量 uint32, 路径 string) []*I离线值 {
+	c, ok := w.取单元(偏移)
+	if !ok {
+		w.问题(I问题级别_错误, "values.cell", -1, 路径, "值列表单元无效: %s", w.描述单元(偏移))
+		return nil
+	}
+	w.引用[偏移]++
+	if uint64(数量)*4 > uint64(len(c)) {
+		w.问题(I问题级别_错误, "values.count", 文件偏移(偏移), 路径, "nk记录的值数为 %d, 值列表单元只能容纳 %d 项", 数量, len(c)/4)
+		数量 = uint32(len(c) / 4)
+	}
+	var 结果 []*I离线值
+	已有 := map[string]bool{}
+	for i := uint32(0); i < 数量; i++ {
+		v := w.遍历值(le.Uint32(c[4*i:]), 路径)
+		if v == nil {
+			continue
+		}
+		if err := hive检查名称(v.Name, true); err != nil {
+			w.问题(I问题级别_错误, "value.name", 文件偏移(le.Uint32(c[4*i:])), 路径, "值名称无效: %v", err)
+			continue
+		}
+		if 键 := string(utf16转字节(hive大写(v.Name))); 已有[键] {
+			w.问题(I问题级别_错误, "value.duplicate", 文件偏移(le.Uint32(c[4*i:])), 路径, "值名称 %q 重复", v.Name)
+			continue
+		} else {
+			已有[键] = true
+		}
+		结果 = append(结果, v)
+	}
+	return 结果
+}
+
+func (w *hive遍历器) 遍历值(偏移 uint32, 路径 string) *I离线值 {
+	c, ok := w.取单元(偏移)
+	if !ok {
+		w.问题(I问题级别_错误, "value.cell", -1, 路径, "值单元无效: %s", w.描述单元(偏移))
+		return nil
+	}
+	w.引用[偏移]++
+	if len(c) < vk固定大小 || string(c[:2]) != "vk" {
+		w.问题(I问题级别_错误, "value.signature", 文件偏移(偏移), 路径, "单元不是vk记录")
+		return nil
+	}
+	n := int(le.Uint16(c[0x02:]))
+	if vk固定大小+n > len(c) {
+		w.问题(I问题级别_错误, "value.name", 文件偏移(偏移), 路径, "值名称长度 %d 超出单元大小", n)
+		return nil
+	}
+	v := &I离线值{
+		Name: hive解码名称(c[vk固定大小:vk固定大小+n], le.Uint16(c[0x10:])&hive值标志_压缩名称 != 0),
+		Type: le.Uint32(c[0x0c:]),
+		Data: []byte{},
+	}
+	w.报告.Values++
+	大小, 数据偏移 := le.Uint32(c[0x04:]), le.Uint32(c[0x08:])
+	switch {
+	case 大小&hive内联数据标志 != 0:
+		if 大小&hive数据大小掩码 > 4 {
+			w.问题(I问题级别_错误, "value.data", 文件偏移(偏移)+4, hive连接路径(路径, v.Name), "内联数据长度 %d 超过4字节", 大小&hive数据大小掩码)
+			return nil
+		}
+		v.Data = append(v.Data, c[0x08:0x08+大小&hive数据大小掩码]...)
+	case 大小 > 0:
+		数据, ok := w.读取值数据(数据偏移, 大小, hive连接路径(路径, v.Name))
+		if !ok {
+			return nil
+		}
+		v.Data = 数据
+	}
+	return v
+}
+
+func (w *hive遍历器) 读取值数据(偏移, 大小 uint32, 路径 string) ([]byte, bool) {
+	c, ok := w.取单元(偏移)
+	if !ok {
+		w.问题(I问题级别_错误, "value.data", -1, 路径, "值数据单元无效: %s", w.描述单元(偏移))
+		return nil, false
+	}
+	w.引用[偏移]++
+	if int64(大小) <= int64(len(c)) {
+		return append([]byte(nil), c[:大小]...), true
+	}
+	if len(c) < 8 || string(c[:2]) != "db" {
+		w.问题(I问题级别_错误, "value.data", 文件偏移(偏移), 路径, "值数据长度 %d 超出单元大小 %d", 大小, len(c))
+		return nil, false
+	}
+	段数, 列表偏移 := int(le.Uint16(c[2:])), le.Uint32(c[4:])
+	列表, ok := w.取单元(列表偏移)
+	if !ok || len(列表) < 4*段数 {
+		w.问题(I问题级别_错误, "value.data", 文件偏移(偏移), 路径, "大数据段列表无效: %s", w.描述单元(列表偏移))
+		return nil, false
+	}
+	w.引用[列表偏移]++
+	// 大小来自文件, 先与段数和文件大小比较, 以免为损坏的长度分配巨大的缓冲区。
+	if int64(大小) > int64(段数)*hive大数据段大小 || int64(大小) > int64(len(w.数据)) {
+		w.问题(I问题级别_错误, "value.data", 文件偏移(偏移), 路径, "值数据长度 %d 超过 %d 个大数据段的容量", 大小, 段数)
+		return nil, false
+	}
+	数据 := make([]byte, 0, 大小)
+	for i := 0; i < 段数 && uint32(len(数据)) < 大小; i++ {
+		段偏移 := le.Uint32(列表[4*i:])
+		段, ok := w.取单元(段偏移)
+		if !ok {
+			w.问题(I问题级别_错误, "value.data", 文件偏移(列表偏移), 路径, "大数据段 %d 无效: %s", i, w.描述单元(段偏移))
+			return nil, false
+		}
+		w.引用[段偏移]++
+		n := min(len(段), hive大数据段大小, int(大小)-len(数据))
+		数据 = append(数据, 段[:n]...)
+	}
+	if uint32(len(数据)) != 大小 {
+		w.问题(I问题级别_错误, "value.data", 文件偏移(偏移), 路径, "大数据段合计 %d 字节, 应为 %d 字节", len(数据), 大小)
+		return nil, false
+	}
+	return 数据, true
+}
+
+// 引用安全单元 记录表项对sk单元的一次引用并返回其中的安全描述符。
+func (w *hive遍历器) 引用安全单元(偏移, 表项偏移 uint32, 路径 string) []byte {
+	c, ok := w.取单元(偏移)
+	if !ok || len(c) < sk固定大小 || string(c[:2]) != "sk" {
+		w.问题(I问题级别_错误, "security.cell", 文件偏移(表项偏移)+0x30, 路径, "安全描述符单元无效: %s", w.描述单元(偏移))
+		return nil
+	}
+	w.安全计数[偏移]++
+	n := le.Uint32(c[0x10:])
+	if uint64(sk固定大小)+uint64(n) > uint64(len(c)) {
+		w.问题(I问题级别_错误, "security.size", 文件偏移(偏移)+0x14, 路径, "安全描述符长度 %d 超出单元大小", n)
+		return nil
+	}
+	sd := c[sk固定大小 : sk固定大小+n]
+	if err := hive检查安全描述符(sd); err != nil {
+		if w.安全计数[偏移] == 1 {
+			w.问题(I问题级别_错误, "security.descriptor", 文件偏移(偏移)+sk固定大小, 路径, "%v", err)
+		}
+		return nil
+	}
+	return append([]byte(nil), sd...)
+}
+
+// 检查安全单元 检查sk单元的引用计数以及flink/blink链表。
+func (w *hive遍历器) 检查安全单元() {
+	偏移表 := make([]uint32, 0, len(w.安全计数))
+	for o := range w.安全计数 {
+		偏移表 = append(偏移表, o)
+	}
+	sort.Slice(偏移表, func(i, j int) bool { return 偏移表[i] < 偏移表[j] })
+
+	已检查 := map[uint32]bool{}
+	for len(偏移表) > 0 {
+		o := 偏移表[0]
+		偏移表 = 偏移表[1:]
+		if 已检查[o] {
+			continue
+		}
+		已检查[o] = true
+		c, _ := w.取单元(o)
+		if 记录, 实际 := le.Uint32(c[0x0c:]), w.安全计数[o]; 记录 != 实际 {
+			w.问题(I问题级别_错误, "security.refcount", 文件偏移(o)+0x10, "", "安全描述符的引用计数为 %d, 实际被 %d 个表项引用", 记录, 实际)
+		}
+		前向, 后向 := le.Uint32(c[0x04:]), le.Uint32(c[0x08:])
+		前向单元, ok := w.取单元(前向)
+		if !ok || len(前向单元) < sk固定大小 || string(前向单元[:2]) != "sk" || le.Uint32(前向单元[0x08:]) != o {
+			w.问题(I问题级别_错误, "security.list", 文件偏移(o)+0x08, "", "sk链表的前向链接 %#x 无效", 前向)
+		} else if !已检查[前向] {
+			// 链表中没有被任何表项引用的sk单元也要检查引用计数
+			偏移表 = append(偏移表, 前向)
+		}
+		if 后向单元, ok := w.取单元(后向); !ok || len(后向单元) < sk固定大小 || string(后向单元[:2]) != "sk" {
+			w.问题(I问题级别_错误, "security.list", 文件偏移(o)+0x0c, "", "sk链表的后向链接 %#x 无效", 后向)
+		}
+	}
+	for o := range 已检查 {
+		w.引用[o]++
+	}
+}
+
+// 检查未引用单元 报告没有被任何结构引用的已分配单元, 以及被多处引用的单元。
+func (w *hive遍历器) 检查未引用单元() {
+	偏移表 := make([]uint32, 0, len(w.单元))
+	for o, c := range w.单元 {
+		if c.已分配 {
+			偏移表 = append(偏移表, o)
+		}
+	}
+	sort.Slice(偏移表, func(i, j int) bool { return 偏移表[i] < 偏移表[j] })
+	for _, o := range 偏移表 {
+		switch n := w.引用[o]; {
+		case n == 0:
+			w.问题(I问题级别_警告, "cell.unreferenced", 文件偏移(o), "", "已分配的单元 (%d 字节) 没有被引用", w.单元[o].大小)
+		case n > 1:
+			w.问题(I问题级别_错误, "cell.shared", 文件偏移(o), "", "单元被引用了 %d 次", n)
+		}
+	}
+}
+
+// hive检查安全描述符 对自相对格式的安全描述符做基本的结构检查。
+func hive检查安全描述符(sd []byte) error {
+	if len(sd) < 20 {
+		return errors.New("安全描述符长度不足20字节")
+	}
+	if sd[0] != 1 {
+		return fmt.Errorf("安全描述符版本为 %d", sd[0])
+	}
+	if le.Uint16(sd[2:])&0x8000 == 0 {
+		return errors.New("安全描述符不是自相对格式")
+	}
+	for _, 位置 := range []int{4, 8, 12, 16} {
+		if o := le.Uint32(sd[位置:]); o != 0 && (o < 20 || uint64(o) >= uint64(len(sd))) {
+			return fmt.Errorf("安全描述符中的偏移 %#x 超出范围", o)
+		}
+	}
+	return nil
+}
+
+func hive连接路径(父, 名称 string) string {
+	if 父 == "" {
+		return 名称
+	}
+	return 父 + `\` + 名称
+}
@@ -0,0 +1,142 @@
+package 注册表类
+
+import (
+	"errors"
+	"sort"
+	"strings"
+)
+
+// I合并类根 按Windows合并HKEY_CLASSES_ROOT的规则组合两个离线表项, 见
+// https://learn.microsoft.com/en-us/windows/win32/sysinfo/merged-view-of-hkey-classes-root
+//
+// Machine 对应 HKLM\Software\Classes, User 对应 HKCU\Software\Classes(UsrClass.dat的根项),
+// 两者都可以为nil。读取时同一路径在User中存在就使用User中的表项, 否则使用Machine中的表项;
+// 子项列表是两者的并集。写入值时写入读取所用的表项; 新建的表项放在其父项所在的一侧,
+// 顶层表项放在Machine中。
+type I合并类根 struct {
+	Machine *I离线表项
+	User    *I离线表项
+}
+
+// I查找 返回合并视图中路径对应的表项, 优先返回User中的表项。不存在时返回nil。
+// 返回的表项只包含它所在一侧的子项, 完整的子项列表使用 I取子项名称。
+func (c *I合并类根) I查找(路径 string) *I离线表项 {
+	if c == nil {
+		return nil
+	}
+	if k := c.User.I查找(路径); k != nil {
+		return k
+	}
+	return c.Machine.I查找(路径)
+}
+
+// I取子项名称 返回合并视图中路径下所有子项的名称, 按不区分大小写的顺序排列。
+// 同名子项只出现一次, 名称取自User中的子项。路径不存在时返回 ErrOfflineNotExist。
+func (c *I合并类根) I取子项名称(路径 string) ([]string, error) {
+	if c == nil {
+		return nil, errors.New("注册表类对象为nil")
+	}
+	var 名称 []string
+	已有 := map[string]bool{}
+	存在 := false
+	for _, 根 := range []*I离线表项{c.User, c.Machine} {
+		k := 根.I查找(路径)
+		if k == nil {
+			continue
+		}
+		存在 = true
+		for _, 子 := range k.SubKeys {
+			键 := string(utf16转字节(hive大写(子.Name)))
+			if !已有[键] {
+				已有[键] = true
+				名称 = append(名称, 子.Name)
+			}
+		}
+	}
+	if !存在 {
+		return nil, ErrOfflineNotExist
+	}
+	sort.Slice(名称, func(i, j int) bool { return hive比较名称(名称[i], 名称[j]) < 0 })
+	return 名称, nil
+}
+
+// I取值 返回合并视图中路径对应表项的值。与Windows一样, 值不逐个合并:
+// User中存在该表项时只在User的表项中查找。不存在时返回 ErrOfflineNotExist。
+func (c *I合并类根) I取值(路径, 名称 string) (*I离线值, error) {
+	k := c.I查找(路径)
+	if k == nil {
+		return nil, ErrOfflineNotExist
+	}
+	if v := k.I取值(名称); v != nil {
+		return v, nil
+	}
+	return nil, ErrOfflineNotExist
+}
+
+// I设置值 把值写入合并视图中路径对应的表项, 即User中存在时写入User, 否则写入Machine。
+// 表项不存在时返回 ErrOfflineNotExist。
+func (c *I合并类根) I设置值(路径 string, 值 *I离线值) error {
+	k := c.I查找(路径)
+	if k == nil {
+		return ErrOfflineNotExist
+	}
+	return k.I设置值(值)
+}
+
+// I删除值 从合并视图中路径对应的表项删除值。
+func (c *I合并类根) I删除值(路径, 名称 string) error {
+	k := c.I查找(路径)
+	if k == nil {
+		return ErrOfflineNotExist
+	}
+	return k.I删除值(名称)
+}
+
+// I创建表项 在合并视图中创建路径对应的表项及缺少的父项, 返回表项和它是否已存在。
+// 新表项创建在父项所在的一侧; 顶层表项创建在Machine中, Machine为nil时创建在User中。
+func (c *I合并类根) I创建表项(路径 string) (*I离线表项, bool, error) {
+	if c == nil {
+		return nil, false, errors.New("注册表类对象为nil")
+	}
+	if k := c.I查找(路径); k != nil {
+		return k, true, nil
+	}
+	段 := 拆分路径(路径)
+	if len(段) == 0 {
+		return nil, false, errors.New("表项名称不能为空")
+	}
+	父 := c.Machine
+	if 父 == nil {
+		父 = c.User
+	}
+	if len(段) > 1 {
+		var err error
+		if 父, _, err = c.I创建表项(strings.Join(段[:len(段)-1], `\`)); err != nil {
+			return nil, false, err
+		}
+	}
+	if 父 == nil {
+		return nil, false, errors.New("注册表类对象为nil")
+	}
+	return 父.I创建子项(段[len(段)-1])
+}
+
+// I删除表项 删除合并视图中路径对应的表项。User和Machine中都存在时只删除User中的表项,
+// 此后Machine中的表项重新可见。
+func (c *I合并类根) I删除表项(路径 string) error {
+	if c == nil {
+		return errors.New("注册表类对象为nil")
+	}
+	段 := 拆分路径(路径)
+	if len(段) == 0 {
+		return errors.New("表项名称不能为空")
+	}
+	父路径 := strings.Join(段[:len(段)-1], `\`)
+	for _, 根 := range []*I离线表项{c.User, c.Machine} {
+		if 根.I查找(路径) == nil {
+			continue
+		}
+		return 根.I查找(父路径).I删除子项(段[len(段)-1])
+	}
+	return ErrOfflineNotExist
+}
@@ -0,0 +1,84 @@
+package 注册表类_test
+
+import (
+	"reflect"
+	"testing"
+
+	注册表类 "e.coding.net/gogit/go/gosdk/core/win_registry_cn"
+)
+
+var sz = 注册表类.I新建文本值
+
+func text(v *注册表类.I离线值) string {
+	s, _ := v.I取文本()
+	return s
+}
+
+func TestMergedClassesRoot(t *testing.T) {
+	machine := &注册表类.I离线表项{Name: "Classes", SubKeys: []*注册表类.I离线表项{
+		{Name: ".txt", Values: []*注册表类.I离线值{sz("", "txtfile"), sz("Content Type", "text/plain")}},
+		{Name: "CLSID", SubKeys: []*注册表类.I离线表项{
+			{Name: "{A}", SubKeys: []*注册表类.I离线表项{{Name: "InprocServer32", Values: []*注册表类.I离线值{sz("", `C:\a.dll`)}}}},
+		}},
+		{Name: "txtfile"},
+	}}
+	user := &注册表类.I离线表项{Name: "ROOT", SubKeys: []*注册表类.I离线表项{
+		{Name: ".TXT", Values: []*注册表类.I离线值{sz("", "Editor.txt")}},
+		{Name: "clsid", SubKeys: []*注册表类.I离线表项{{Name: "{B}"}}},
+	}}
+	c := &注册表类.I合并类根{Machine: machine, User: user}
+
+	if v, err := c.I取值(".txt", ""); err != nil || text(v) != "Editor.txt" {
+		t.Errorf("user association should win: got %v, %v", v, err)
+	}
+	if _, err := c.I取值(".txt", "Content Type"); err != 注册表类.ErrOfflineNotExist {
+		t.Errorf("values are not merged per value: got %v", err)
+	}
+	if v, err := c.I取值(`CLSID\{A}\InprocServer32`, ""); err != nil || text(v) != `C:\a.dll` {
+		t.Errorf("machine COM registration: got %v, %v", v, err)
+	}
+	names, err := c.I取子项名称("")
+	if want := []string{".TXT", "clsid", "txtfile"}; err != nil || !reflect.DeepEqual(names, want) {
+		t.Errorf("root subkeys: got %q, %v, want %q", names, err, want)
+	}
+	names, _ = c.I取子项名称("CLSID")
+	if want := []string{"{A}", "{B}"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("CLSID subkeys: got %q, want %q", names, want)
+	}
+
+	// Writes go to the side the key is read from, new keys to their parent's side.
+	if err := c.I设置值(".txt", sz("PerceivedType", "text")); err != nil {
+		t.Fatal(err)
+	}
+	if user.I查找(".txt").I取值("PerceivedType") == nil || machine.I查找(".txt").I取值("PerceivedType") != nil {
+		t.Error("value write should go to the user key")
+	}
+	if _, existed, err := c.I创建表项(`txtfile\shell\open`); err != nil || existed {
+		t.Fatalf("create: existed=%v err=%v", existed, err)
+	}
+	if machine.I查找(`txtfile\shell\open`) == nil {
+		t.Error("key under a machine-only parent should be created in the machine hive")
+	}
+	if _, _, err := c.I创建表项(`CLSID\{B}\LocalServer32`); err != nil {
+		t.Fatal(err)
+	}
+	if user.I查找(`CLSID\{B}\LocalServer32`) == nil {
+		t.Error("key under a user parent should be created in the user hive")
+	}
+	if _, _, err := c.I创建表项(".new"); err != nil || machine.I查找(".new") == nil {
+		t.Errorf("top-level key should be created in the machine hive: %v", err)
+	}
+
+	if err := c.I删除表项(".txt"); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := c.I取值(".txt", ""); v == nil || text(v) != "txtfile" {
+		t.Errorf("deleting the user key should reveal the machine key, got %v", v)
+	}
+	if err := c.I删除表项("CLSID"); err == nil {
+		t.Error("deleting a key with subkeys should fail")
+	}
+	if err := c.I删除表项("missing"); err != 注册表类.ErrOfflineNotExist {
+		t.Errorf("got %v, want ErrOfflineNotExist", err)
+	}
+}
@@ -0,0 +1,53 @@
+package 注册表类
+
+import "fmt"
+
+// I压缩结果 描述 I压缩配置单元 前后配置单元的大小变化。
+type I压缩结果 struct {
+	OriginalSize                 int64 // 原文件大小
+	CompactedSize                int64 // 压缩后的文件大小
+	OriginalFreeBytes            int64 // 原文件中空闲单元的总字节数
+	CompactedFreeBytes           int64 // 压缩后空闲单元的总字节数, 只剩各hbin末尾的填充
+	OriginalSecurityDescriptors  int   // 原文件中被引用的sk单元数量
+	CompactedSecurityDescriptors int   // 去重后的sk单元数量
+}
+
+// I节省字节数 返回压缩减少的文件大小。
+func (r *I压缩结果) I节省字节数() int64 {
+	if r == nil {
+		return 0
+	}
+	return r.OriginalSize - r.CompactedSize
+}
+
+// I压缩配置单元 重写配置单元: 单元紧密排列在hbin中, 相同的安全描述符合并为一个sk单元,
+// 子项索引按名称重建。写出后重新解析并与原表项树比较,
+// 逻辑内容有任何不同都返回错误而不是结果。
+// 原文件必须能通过 I解析配置单元, 损坏的文件请先用 I修复配置单元 处理。
+func I压缩配置单元(数据 []byte) ([]byte, *I压缩结果, error) {
+	原遍历 := hive新遍历器(数据)
+	原树 := 原遍历.遍历()
+	if 错误 := 原遍历.报告.I取错误(); len(错误) > 0 {
+		return nil, nil, fmt.Errorf("%w: %s", ErrCorruptHive, 错误[0].Message)
+	}
+	新数据, err := I生成配置单元(原树)
+	if err != nil {
+		return nil, nil, err
+	}
+	新遍历 := hive新遍历器(新数据)
+	新树 := 新遍历.遍历()
+	if 错误 := 新遍历.报告.I取错误(); len(错误) > 0 {
+		return nil, nil, fmt.Errorf("压缩后的配置单元未通过校验: %s", 错误[0].Message)
+	}
+	if 差异 := I比较离线表项(原树, 新树); len(差异) > 0 {
+		return nil, nil, fmt.Errorf("压缩后的配置单元内容与原文件不同: %v", 差异[0])
+	}
+	return 新数据, &I压缩结果{
+		OriginalSize:                 int64(len(数据)),
+		CompactedSize:                int64(len(新数据)),
+		OriginalFreeBytes:            原遍历.报告.FreeBytes,
+		CompactedFreeBytes:           新遍历.报告.FreeBytes,
+		OriginalSecurityDescriptors:  原遍历.报告.SecurityDescriptors,
+		CompactedSecurityDescriptors: 新遍历.报告.SecurityDescriptors,
+	}, nil
+}
@@ -0,0 +1,111 @@
+package 注册表类
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// I当前控制集 是SYSTEM配置单元中指向当前控制集的符号链接名称, 离线配置单元中不存在。
+const I当前控制集 = "CurrentControlSet"
+
+// I控制集选择 是SYSTEM配置单元中 Select 表项的内容, 0表示没有该值。
+type I控制集选择 struct {
+	Current       uint32
+	Default       uint32
+	Failed        uint32
+	LastKnownGood uint32
+}
+
+// I控制集名称 返回编号n对应的控制集名称, 例如 ControlSet001。
+func I控制集名称(n uint32) string {
+	return fmt.Sprintf("ControlSet%03d", n)
+}
+
+// I取控制集选择 读取SYSTEM配置单元根项系统中的 Select 表项。
+func I取控制集选择(系统 *I离线表项) (*I控制集选择, error) {
+	选择表项 := 系统.I取子项("Select")
+	if 选择表项 == nil {
+		return nil, ErrOfflineNotExist
+	}
+	选择 := &I控制集选择{}
+	for _, f := range []struct {
+		名称 string
+		值  *uint32
+	}{
+		{"Current", &选择.Current},
+		{"Default", &选择.Default},
+		{"Failed", &选择.Failed},
+		{"LastKnownGood", &选择.LastKnownGood},
+	} {
+		if v := 选择表项.I取值(f.名称); v != nil && v.Type == DWORD && len(v.Data) == 4 {
+			*f.值 = le.Uint32(v.Data)
+		}
+	}
+	return 选择, nil
+}
+
+// I取当前控制集 按 Select 中 Current、Default、LastKnownGood 的顺序,
+// 返回第一个存在的控制集的名称。都不存在时返回 ErrOfflineNotExist。
+func I取当前控制集(系统 *I离线表项) (string, error) {
+	选择, err := I取控制集选择(系统)
+	if err != nil {
+		return "", err
+	}
+	for _, n := range []uint32{选择.Current, 选择.Default, 选择.LastKnownGood} {
+		if n != 0 && 系统.I取子项(I控制集名称(n)) != nil {
+			return I控制集名称(n), nil
+		}
+	}
+	return "", ErrOfflineNotExist
+}
+
+// I列出控制集 返回SYSTEM配置单元根项系统中所有 ControlSetNNN 子项的名称, 按编号排序。
+func I列出控制集(系统 *I离线表项) []string {
+	if 系统 == nil {
+		return nil
+	}
+	var 名称 []string
+	for _, 子 := range 系统.SubKeys {
+		var n uint32
+		if _, err := fmt.Sscanf(子.Name, "ControlSet%03d", &n); err == nil && strings.EqualFold(子.Name, I控制集名称(n)) {
+			名称 = append(名称, 子.Name)
+		}
+	}
+	sort.Slice(名称, func(i, j int) bool { return hive比较名称(名称[i], 名称[j]) < 0 })
+	return 名称
+}
+
+// I解析控制集路径 把相对于SYSTEM配置单元根项的路径中开头的 CurrentControlSet
+// 替换为 I取当前控制集 返回的控制集, 其他路径原样返回。
+func I解析控制集路径(系统 *I离线表项, 路径 string) (string, error) {
+	段 := 拆分路径(路径)
+	if len(段) == 0 || hive比较名称(段[0], I当前控制集) != 0 {
+		return 路径, nil
+	}
+	当前, err := I取当前控制集(系统)
+	if err != nil {
+		return "", err
+	}
+	段[0] = 当前
+	return strings.Join(段, `\`), nil
+}
+
+// I比较控制集 比较SYSTEM配置单元中的两个控制集, 名称可以是 ControlSetNNN 或 CurrentControlSet。
+// 差异中的路径相对于控制集。
+func I比较控制集(系统 *I离线表项, 旧, 新 string, 选项 ...I比较选项) ([]I离线差异, error) {
+	var 表项 [2]*I离线表项
+	for i, 名称 := range []string{旧, 新} {
+		实际, err := I解析控制集路径(系统, 名称)
+		if err != nil {
+			return nil, err
+		}
+		if 表项[i] = 系统.I查找(实际); 表项[i] == nil {
+			return nil, fmt.Errorf("控制集 %s: %w", 名称, ErrOfflineNotExist)
+		}
+	}
+	// 控制集的名称本来就不同, 不作为差异报告。
+	旧根, 新根 := *表项[0], *表项[1]
+	新根.Name = 旧根.Name
+	return I比较离线表项(&旧根, &新根, 选项...), nil
+}
@@ -0,0 +1,98 @@
+package 注册表类_test
+
+import (
+	"reflect"
+	"testing"
+
+	注册表类 "e.coding.net/gogit/go/gosdk/core/win_registry_cn"
+)
+
+func dword(name string, n uint32) *注册表类.I离线值 {
+	return &注册表类.I离线值{Name: name, Type: 注册表类.DWORD, Data: []byte{byte(n), byte(n >> 8), byte(n >> 16), byte(n >> 24)}}
+}
+
+func testSystemHive() *注册表类.I离线表项 {
+	service := func(start uint32) *注册表类.I离线表项 {
+		return &注册表类.I离线表项{Name: "Services", SubKeys: []*注册表类.I离线表项{
+			{Name: "Tcpip", Values: []*注册表类.I离线值{dword("Start", start)}},
+		}}
+	}
+	return &注册表类.I离线表项{Name: "ROOT", SubKeys: []*注册表类.I离线表项{
+		{Name: "ControlSet002", SubKeys: []*注册表类.I离线表项{service(3)}},
+		{Name: "ControlSet001", SubKeys: []*注册表类.I离线表项{service(1), {Name: "Hardware Profiles", SubKeys: []*注册表类.I离线表项{
+			{Name: "Current", Values: []*注册表类.I离线值{sz("Profile", "docked")}},
+		}}}},
+		{Name: "ControlSetX"},
+		{Name: "Select", Values: []*注册表类.I离线值{
+			dword("Current", 1), dword("Default", 1), dword("Failed", 0), dword("LastKnownGood", 2),
+		}},
+	}}
+}
+
+func TestControlSets(t *testing.T) {
+	system := testSystemHive()
+	if got := 注册表类.I列出控制集(system); !reflect.DeepEqual(got, []string{"ControlSet001", "ControlSet002"}) {
+		t.Errorf("control sets: got %q", got)
+	}
+	sel, err := 注册表类.I取控制集选择(system)
+	if err != nil || *sel != (注册表类.I控制集选择{Current: 1, Default: 1, LastKnownGood: 2}) {
+		t.Errorf("select: got %+v, %v", sel, err)
+	}
+	if p, err := 注册表类.I解析控制集路径(system, `currentcontrolset\Services\Tcpip`); err != nil || p != `ControlSet001\Services\Tcpip` {
+		t.Errorf("got %q, %v", p, err)
+	}
+	if p, _ := 注册表类.I解析控制集路径(system, `Select`); p != `Select` {
+		t.Errorf("other paths should be unchanged, got %q", p)
+	}
+
+	// A missing Current control set falls back to Default, then LastKnownGood.
+	system.I取子项("Select").I设置值(dword("Current", 5))
+	system.I取子项("Select").I设置值(dword("Default", 6))
+	if cur, err := 注册表类.I取当前控制集(system); err != nil || cur != "ControlSet002" {
+		t.Errorf("fallback: got %q, %v", cur, err)
+	}
+	system.I取子项("Select").I设置值(dword("LastKnownGood", 7))
+	if _, err := 注册表类.I取当前控制集(system); err != 注册表类.ErrOfflineNotExist {
+		t.Errorf("got %v, want ErrOfflineNotExist", err)
+	}
+}
+
+func TestCompareControlSets(t *testing.T) {
+	system := testSystemHive()
+	diffs, err := 注册表类.I比较控制集(system, "CurrentControlSet", "ControlSet002")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, d := range diffs {
+		got = append(got, d.String())
+	}
+	want := []string{`key deleted Hardware Profiles`, `value changed Services\Tcpip ["Start"]`}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if _, err := 注册表类.I比较控制集(system, "ControlSet001", "ControlSet009"); err == nil {
+		t.Error("comparing with a missing control set should fail")
+	}
+}
+
+func TestNamespaceCurrentControlSet(t *testing.T) {
+	ns := 注册表类.I新建离线命名空间()
+	if err := ns.I加载(`HKLM\SYSTEM`, testSystemHive()); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := ns.I取值(`HKLM\SYSTEM\CurrentControlSet\Services\Tcpip`, "Start"); err != nil || v.Data[0] != 1 {
+		t.Errorf("CurrentControlSet lookup: got %v, %v", v, err)
+	}
+	if v, err := ns.I取值(`HKCC`, "Profile"); err != nil || text(v) != "docked" {
+		t.Errorf("HKCC lookup: got %v, %v", v, err)
+	}
+	names, _ := ns.I取子项名称(`HKLM\SYSTEM`)
+	found := false
+	for _, name := range names {
+		found = found || name == "CurrentControlSet"
+	}
+	if !found {
+		t.Errorf("HKLM\\SYSTEM should list CurrentControlSet, got %q", names)
+	}
+}
@@ -0,0 +1,139 @@
+package 注册表类
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+)
+
+// I差异类型 表示两棵离线表项树之间的一处差异属于哪一类。
+type I差异类型 int
+
+const (
+	I差异_新增表项 I差异类型 = iota + 1
+	I差异_删除表项
+	I差异_修改表项 // 名称大小写、类名、写入时间、标志或安全描述符不同
+	I差异_新增值
+	I差异_删除值
+	I差异_修改值 // 值的类型或数据不同
+)
+
+func (t I差异类型) String() string {
+	switch t {
+	case I差异_新增表项:
+		return "key added"
+	case I差异_删除表项:
+		return "key deleted"
+	case I差异_修改表项:
+		return "key changed"
+	case I差异_新增值:
+		return "value added"
+	case I差异_删除值:
+		return "value deleted"
+	case I差异_修改值:
+		return "value changed"
+	}
+	return fmt.Sprintf("I差异类型(%d)", int(t))
+}
+
+// I离线差异 描述两棵离线表项树之间的一处差异。
+type I离线差异 struct {
+	Kind   I差异类型
+	Path   string // 表项相对根项的路径
+	Value  string // 值名称, 只对值的差异有意义
+	Detail string // I差异_修改表项 时说明哪些属性不同
+	Old    *I离线值
+	New    *I离线值
+}
+
+func (d I离线差异) String() string {
+	s := d.Kind.String() + " " + d.Path
+	switch d.Kind {
+	case I差异_新增值, I差异_删除值, I差异_修改值:
+		s += fmt.Sprintf(" [%q]", d.Value)
+	case I差异_修改表项:
+		s += " (" + d.Detail + ")"
+	}
+	return s
+}
+
+// I比较选项 控制 I比较离线表项 忽略哪些属性。
+type I比较选项 struct {
+	IgnoreModTime  bool
+	IgnoreSecurity bool
+	IgnoreClass    bool
+}
+
+// I比较离线表项 比较两棵表项树的逻辑内容, 返回从'旧'变为'新'的全部差异。
+// 子项和值按不区分大小写的名称配对, 值的先后顺序不影响结果。
+// 新增或删除的表项只报告一次, 不再展开其中的值和子项。
+func I比较离线表项(旧, 新 *I离线表项, 选项 ...I比较选项) []I离线差异 {
+	var 选 I比较选项
+	if len(选项) > 0 {
+		选 = 选项[0]
+	}
+	var 结果 []I离线差异
+	比较离线表项(旧, 新, "", 选, &结果)
+	return 结果
+}
+
+func 比较离线表项(旧, 新 *I离线表项, 路径 string, 选 I比较选项, 结果 *[]I离线差异) {
+	var 不同 []string
+	if 旧.Name != 新.Name {
+		不同 = append(不同, "name")
+	}
+	if !选.IgnoreClass && 旧.Class != 新.Class {
+		不同 = append(不同, "class")
+	}
+	if !选.IgnoreModTime && !旧.ModTime.Equal(新.ModTime) {
+		不同 = append(不同, "modtime")
+	}
+	if 旧.Flags != 新.Flags {
+		不同 = append(不同, "flags")
+	}
+	if !选.IgnoreSecurity && !bytes.Equal(旧.SecurityDescriptor, 新.SecurityDescriptor) {
+		不同 = append(不同, "security")
+	}
+	if len(不同) > 0 {
+		*结果 = append(*结果, I离线差异{Kind: I差异_修改表项, Path: 路径, Detail: fmt.Sprint(不同)})
+	}
+
+	for _, v := range 旧.Values {
+		n := 新.I取值(v.Name)
+		switch {
+		case n == nil:
+			*结果 = append(*结果, I离线差异{Kind: I差异_删除值, Path: 路径, Value: v.Name, Old: v})
+		case v.Name != n.Name || v.Type != n.Type || !bytes.Equal(v.Data, n.Data):
+			*结果 = append(*结果, I离线差异{Kind: I差异_修改值, Path: 路径, Value: v.Name, Old: v, New: n})
+		}
+	}
+	for _, n := range 新.Values {
+		if 旧.I取值(n.Name) == nil {
+			*结果 = append(*结果, I离线差异{Kind: I差异_新增值, Path: 路径, Value: n.Name, New: n})
+		}
+	}
+
+	名称 := map[string]string{}
+	for _, 子 := range 旧.SubKeys {
+		名称[string(utf16转字节(hive大写(子.Name)))] = 子.Name
+	}
+	for _, 子 := range 新.SubKeys {
+		名称[string(utf16转字节(hive大写(子.Name)))] = 子.Name
+	}
+	排序 := make([]string, 0, len(名称))
+	for _, n := range 名称 {
+		排序 = append(排序, n)
+	}
+	sort.Slice(排序, func(i, j int) bool { return hive比较名称(排序[i], 排序[j]) < 0 })
+	for _, n := range 排序 {
+		旧子, 新子 := 旧.I取子项(n), 新.I取子项(n)
+		switch {
+		case 旧子 == nil:
+			*结果 = append(*结果, I离线差异{Kind: I差异_新增表项, Path: hive连接路径(路径, 新子.Name)})
+		case 新子 == nil:
+			*结果 = append(*结果, I离线差异{Kind: I差异_删除表项, Path: hive连接路径(路径, 旧子.Name)})
+		default:
+			比较离线表项(旧子, 新子, hive连接路径(路径, 新子.Name), 选, 结果)
+		}
+	}
+}
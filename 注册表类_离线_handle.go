@@ -0,0 +1,212 @@
+package 注册表类
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+)
+
+// ErrOfflineAccessDenied 当离线句柄没有操作需要的'访问权限', 或者表项的DACL拒绝访问时返回,
+// 对应Windows上的ERROR_ACCESS_DENIED。errors.Is(err, fs.ErrPermission) 为true。
+var ErrOfflineAccessDenied = fmt.Errorf("offline registry key access denied: %w", fs.ErrPermission)
+
+// I离线句柄 是以某个'访问权限'打开的离线表项, 与Windows的注册表句柄一样,
+// 权限不足的操作返回 ErrOfflineAccessDenied: 读取值需要QUERY_VALUE, 枚举子项需要ENUMERATE_SUB_KEYS,
+// 写入和删除值需要SET_VALUE, 创建子项需要CREATE_SUB_KEY。
+// 给出模拟的调用者令牌时, 打开、创建和删除表项还按表项的DACL检查, 规则与 I访问检查 相同;
+// 表项没有安全描述符时使用最近的有安全描述符的上级表项的。读取返回的值是副本。
+type I离线句柄 struct {
+	表项 *I离线表项
+	sd *I离线表项 // 提供有效安全描述符的表项
+	权限 uint32
+	令牌 *I访问令牌
+}
+
+// I打开离线句柄 以'访问权限'打开根项下路径指向的表项, 表项不存在时返回 ErrOfflineNotExist。
+// 令牌为nil时不检查DACL, 此时 MAXIMUM_ALLOWED 得到全部权限。
+func I打开离线句柄(根 *I离线表项, 路径 string, 访问权限 uint32, 令牌 *I访问令牌) (*I离线句柄, error) {
+	if 根 == nil {
+		return nil, errors.New("注册表类对象为nil")
+	}
+	k, sd := 根, 根
+	for _, 段 := range 拆分路径(路径) {
+		if k = k.I取子项(段); k == nil {
+			return nil, ErrOfflineNotExist
+		}
+		if len(k.SecurityDescriptor) > 0 {
+			sd = k
+		}
+	}
+	return 打开离线句柄(k, sd, 访问权限, 令牌)
+}
+
+func 打开离线句柄(k, sd *I离线表项, 访问权限 uint32, 令牌 *I访问令牌) (*I离线句柄, error) {
+	权限 := I映射通用权限(访问权限 &^ key视图标志)
+	if 令牌 != nil {
+		授予, 允许, err := sd.I访问检查(令牌, 访问权限)
+		if err != nil {
+			return nil, err
+		}
+		if !允许 {
+			return nil, ErrOfflineAccessDenied
+		}
+		权限 = 授予
+	} else if 权限&MAXIMUM_ALLOWED != 0 {
+		权限 = 权限&^MAXIMUM_ALLOWED | key全部权限
+	}
+	return &I离线句柄{表项: k, sd: sd, 权限: 权限, 令牌: 令牌}, nil
+}
+
+// I取权限 返回打开句柄时获得的'访问权限', 通用权限已经映射为具体权限。
+func (h *I离线句柄) I取权限() uint32 {
+	return h.权限
+}
+
+// 检查权限 在句柄没有'需要'的全部权限时返回 ErrOfflineAccessDenied。
+func (h *I离线句柄) 检查权限(需要 uint32) error {
+	if h.权限&需要 != 需要 {
+		return ErrOfflineAccessDenied
+	}
+	return nil
+}
+
+// 子项 返回路径各段相对h的子项和它的有效安全描述符所在的表项, 不存在时返回nil。
+func (h *I离线句柄) 子项(路径 []string) (k, sd *I离线表项) {
+	k, sd = h.表项, h.sd
+	for _, 段 := range 路径 {
+		if k = k.I取子项(段); k == nil {
+			return nil, nil
+		}
+		if len(k.SecurityDescriptor) > 0 {
+			sd = k
+		}
+	}
+	return k, sd
+}
+
+// I打开子项 以'访问权限'打开路径相对h的子项, 与RegOpenKeyEx一样不要求h有任何权限。
+func (h *I离线句柄) I打开子项(路径 string, 访问权限 uint32) (*I离线句柄, error) {
+	k, sd := h.子项(拆分路径(路径))
+	if k == nil {
+		return nil, ErrOfflineNotExist
+	}
+	return 打开离线句柄(k, sd, 访问权限, h.令牌)
+}
+
+// I创建子项 创建或打开路径相对h的子项, 返回以'访问权限'打开的句柄和子项是否已存在。
+// h需要CREATE_SUB_KEY权限; 设置了令牌时, 新建子项还要求最近的已存在上级表项的DACL允许CREATE_SUB_KEY。
+func (h *I离线句柄) I创建子项(路径 string, 访问权限 uint32) (*I离线句柄, bool, error) {
+	if err := h.检查权限(key创建子项权限); err != nil {
+		return nil, false, err
+	}
+	段 := 拆分路径(路径)
+	if k, sd := h.子项(段); k != nil {
+		子, err := 打开离线句柄(k, sd, 访问权限, h.令牌)
+		return 子, true, err
+	}
+	上级, sd := h.表项, h.sd
+	i := 0
+	for ; i < len(段); i++ {
+		子 := 上级.I取子项(段[i])
+		if 子 == nil {
+			break
+		}
+		if 上级 = 子; len(子.SecurityDescriptor) > 0 {
+			sd = 子
+		}
+	}
+	if 上级 != h.表项 {
+		if _, err := 打开离线句柄(上级, sd, key创建子项权限, h.令牌); err != nil {
+			return nil, false, err
+		}
+	}
+	for ; i < len(段); i++ {
+		var err error
+		if 上级, _, err = 上级.I创建子项(段[i]); err != nil {
+			return nil, false, err
+		}
+	}
+	子, err := 打开离线句柄(上级, sd, 访问权限, h.令牌)
+	return 子, false, err
+}
+
+// I删除子项 删除路径相对h的子项。与RegDeleteKey一样, 子项以DELETE权限打开, 不要求h有任何权限;
+// 子项还有子项时返回 ErrOfflineAccessDenied。
+func (h *I离线句柄) I删除子项(路径 string) error {
+	段 := 拆分路径(路径)
+	if len(段) == 0 {
+		return ErrOfflineAccessDenied
+	}
+	k, sd := h.子项(段)
+	if k == nil {
+		return ErrOfflineNotExist
+	}
+	if _, err := 打开离线句柄(k, sd, DELETE, h.令牌); err != nil {
+		return err
+	}
+	if len(k.SubKeys) > 0 {
+		return ErrOfflineAccessDenied
+	}
+	父, _ := h.子项(段[:len(段)-1])
+	return 父.I删除子项(k.Name)
+}
+
+func 复制离线值(v *I离线值) *I离线值 {
+	return &I离线值{Name: v.Name, Type: v.Type, Data: bytes.Clone(v.Data)}
+}
+
+// I取值 返回名称为'名称'的值的副本, 不存在时返回 ErrOfflineNotExist。需要QUERY_VALUE权限。
+func (h *I离线句柄) I取值(名称 string) (*I离线值, error) {
+	if err := h.检查权限(key查询值权限); err != nil {
+		return nil, err
+	}
+	v := h.表项.I取值(名称)
+	if v == nil {
+		return nil, ErrOfflineNotExist
+	}
+	return 复制离线值(v), nil
+}
+
+// I列出值 返回所有值的副本。需要QUERY_VALUE权限。
+func (h *I离线句柄) I列出值() ([]*I离线值, error) {
+	if err := h.检查权限(key查询值权限); err != nil {
+		return nil, err
+	}
+	值 := make([]*I离线值, len(h.表项.Values))
+	for i, v := range h.表项.Values {
+		值[i] = 复制离线值(v)
+	}
+	return 值, nil
+}
+
+// I取子项名称 返回所有子项的名称。需要ENUMERATE_SUB_KEYS权限。
+func (h *I离线句柄) I取子项名称() ([]string, error) {
+	if err := h.检查权限(key枚举子项权限); err != nil {
+		return nil, err
+	}
+	名称 := make([]string, len(h.表项.SubKeys))
+	for i, 子 := range h.表项.SubKeys {
+		名称[i] = 子.Name
+	}
+	return 名称, nil
+}
+
+// I设置值 写入值的副本, 替换名称相同的值。需要SET_VALUE权限。
+func (h *I离线句柄) I设置值(值 *I离线值) error {
+	if 值 == nil {
+		return errors.New("注册表类对象为nil")
+	}
+	if err := h.检查权限(key设置值权限); err != nil {
+		return err
+	}
+	return h.表项.I设置值(复制离线值(值))
+}
+
+// I删除值 删除名称为'名称'的值, 不存在时返回 ErrOfflineNotExist。需要SET_VALUE权限。
+func (h *I离线句柄) I删除值(名称 string) error {
+	if err := h.检查权限(key设置值权限); err != nil {
+		return err
+	}
+	return h.表项.I删除值(名称)
+}
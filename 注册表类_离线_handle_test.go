@@ -0,0 +1,104 @@
+package 注册表类_test
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+
+	注册表类 "e.coding.net/gogit/go/gosdk/core/win_registry_cn"
+)
+
+func TestOfflineHandleAccessMask(t *testing.T) {
+	root := &注册表类.I离线表项{Name: "ROOT"}
+	h, err := 注册表类.I打开离线句柄(root, "", 0xf003f, nil) // KEY_ALL_ACCESS
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := h.I创建子项(`A\B`, 0xf003f); err != nil {
+		t.Fatal(err)
+	}
+	w, err := h.I打开子项(`A\B`, 0x20006) // KEY_WRITE
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.I设置值(&注册表类.I离线值{Name: "v", Type: 注册表类.DWORD, Data: []byte{1, 0, 0, 0}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.I取值("v"); !errors.Is(err, 注册表类.ErrOfflineAccessDenied) {
+		t.Errorf("I取值 through KEY_WRITE handle: got %v, want ErrOfflineAccessDenied", err)
+	}
+
+	r, err := h.I打开子项(`A\B`, 注册表类.GENERIC_READ)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.I取权限() != 0x20019 {
+		t.Errorf("GENERIC_READ mapped to %#x, want KEY_READ", r.I取权限())
+	}
+	v, err := r.I取值("v")
+	if err != nil {
+		t.Fatal(err)
+	}
+	v.Data[0] = 9
+	if again, _ := r.I取值("v"); again.Data[0] != 1 {
+		t.Error("I取值 returned the stored value instead of a copy")
+	}
+	for name, err := range map[string]error{
+		"I设置值":  r.I设置值(&注册表类.I离线值{Name: "x", Type: 注册表类.DWORD, Data: []byte{0, 0, 0, 0}}),
+		"I删除值":  r.I删除值("v"),
+		"I创建子项": func() error { _, _, err := r.I创建子项("C", 0x20019); return err }(),
+	} {
+		if !errors.Is(err, fs.ErrPermission) {
+			t.Errorf("%s through KEY_READ handle: got %v, want access denied", name, err)
+		}
+	}
+	if _, err := r.I列出值(); err != nil {
+		t.Error(err)
+	}
+	if _, err := h.I打开子项(`A\Missing`, 0x20019); !errors.Is(err, 注册表类.ErrOfflineNotExist) {
+		t.Errorf("I打开子项 of missing key: got %v, want ErrOfflineNotExist", err)
+	}
+	if err := h.I删除子项("A"); !errors.Is(err, 注册表类.ErrOfflineAccessDenied) {
+		t.Errorf("I删除子项 of key with subkeys: got %v, want ErrOfflineAccessDenied", err)
+	}
+	if err := h.I删除子项(`A\B`); err != nil {
+		t.Fatal(err)
+	}
+	if root.I查找(`A\B`) != nil {
+		t.Error(`A\B still exists after I删除子项`)
+	}
+}
+
+func TestOfflineHandleDACL(t *testing.T) {
+	user := &注册表类.I访问令牌{User: "S-1-5-21-1-2-3-1001", Groups: []string{"WD", "BU"}}
+	root := &注册表类.I离线表项{Name: "ROOT", SubKeys: []*注册表类.I离线表项{{Name: "Locked", SubKeys: []*注册表类.I离线表项{{Name: "Child"}}}}}
+	sd, _ := 注册表类.I解析SDDL("O:BAG:SYD:P(A;;KA;;;BA)(A;;KR;;;WD)")
+	if err := root.I查找("Locked").I设置安全描述符(sd); err != nil {
+		t.Fatal(err)
+	}
+	h, err := 注册表类.I打开离线句柄(root, "", 注册表类.MAXIMUM_ALLOWED, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Child 没有安全描述符, 使用 Locked 的。
+	if _, err := h.I打开子项(`Locked\Child`, 0x20006); !errors.Is(err, 注册表类.ErrOfflineAccessDenied) {
+		t.Errorf("KEY_WRITE open of read-only key: got %v, want ErrOfflineAccessDenied", err)
+	}
+	c, err := h.I打开子项(`Locked\Child`, 注册表类.MAXIMUM_ALLOWED)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.I取权限() != 0x20019 {
+		t.Errorf("MAXIMUM_ALLOWED granted %#x, want KEY_READ", c.I取权限())
+	}
+	if _, _, err := h.I创建子项(`Locked\New`, 0x20019); !errors.Is(err, 注册表类.ErrOfflineAccessDenied) {
+		t.Errorf("I创建子项 under read-only key: got %v, want ErrOfflineAccessDenied", err)
+	}
+	if err := h.I删除子项(`Locked\Child`); !errors.Is(err, 注册表类.ErrOfflineAccessDenied) {
+		t.Errorf("I删除子项 of read-only key: got %v, want ErrOfflineAccessDenied", err)
+	}
+	if root.I查找(`Locked\New`) != nil || root.I查找(`Locked\Child`) == nil {
+		t.Error("denied operation changed the tree")
+	}
+}
@@ -0,0 +1,682 @@
+package 注册表类
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf16"
+)
+
+// 离线配置单元(regf 文件)格式中用到的常量。
+// 见 https://github.com/msuhanov/regf/blob/master/Windows%20registry%20file%20format%20specification.md
+const (
+	hive基块大小    = 0x1000
+	hbin对齐      = 0x1000
+	hbin头大小     = 0x20
+	hive无效偏移    = 0xffffffff
+	hive大数据段大小  = 16344
+	hive叶最大条目   = 512
+	hive名称最大长度  = 255
+	hive值名最大长度  = 16383
+	nk固定大小      = 0x4c
+	vk固定大小      = 0x14
+	sk固定大小      = 0x14
+	hive内联数据标志  = 0x80000000
+	hive数据大小掩码  = 0x7fffffff
+	hive主版本     = 1
+	hive次版本     = 5
+	filetime纪元差 = 11644473600
+
+	hive标志_易失   = 0x0001
+	hive标志_根项   = 0x0004
+	hive标志_不可删除 = 0x0008
+	hive标志_符号链接 = 0x0010
+	hive标志_压缩名称 = 0x0020
+
+	hive值标志_压缩名称 = 0x0001
+)
+
+// ErrCorruptHive 当离线配置单元的结构无法通过校验时返回。
+var ErrCorruptHive = errors.New("registry hive is corrupt")
+
+// ErrOfflineNotExist 当离线表项或值不存在时返回。
+var ErrOfflineNotExist = errors.New("offline registry key or value does not exist")
+
+var le = binary.LittleEndian
+
+// I离线表项 是离线配置单元(regf 文件)中一个注册表项的内存表示。
+// 由 I解析配置单元 返回, 也可以手工构造后交给 I生成配置单元 写成文件。
+type I离线表项 struct {
+	Name               string
+	Class              string
+	ModTime            time.Time
+	Flags              uint16 // nk 记录中的标志位, 名称压缩和根项标志由写入时重新计算
+	SecurityDescriptor []byte // 自相对格式的安全描述符, 为空时继承父项
+	Values             []*I离线值
+	SubKeys            []*I离线表项
+}
+
+// I离线值 是离线配置单元中的一个注册表值。
+type I离线值 struct {
+	Name string
+	Type uint32
+	Data []byte
+}
+
+// I新建文本值 返回SZ类型的离线值, 数据以UTF-16LE编码并带结尾的NUL。
+func I新建文本值(名称, 文本 string) *I离线值 {
+	return &I离线值{Name: 名称, Type: SZ, Data: hive编码UTF16(文本 + "\x00")}
+}
+
+// I取文本 返回SZ、EXPAND_SZ或LINK类型值的文本, 截断到第一个NUL, 不展开环境变量。
+// 其他类型返回 ErrUnexpectedType。
+func (v *I离线值) I取文本() (string, error) {
+	if v == nil {
+		return "", errors.New("注册表类对象为nil")
+	}
+	switch v.Type {
+	case SZ, EXPAND_SZ, LINK:
+	default:
+		return "", ErrUnexpectedType
+	}
+	s := hive解码UTF16(v.Data)
+	if i := strings.IndexByte(s, 0); i >= 0 {
+		s = s[:i]
+	}
+	return s, nil
+}
+
+// I取子项 返回名称为'名称'的直接子项, 名称不区分大小写。不存在时返回nil。
+func (k *I离线表项) I取子项(名称 string) *I离线表项 {
+	if k == nil {
+		return nil
+	}
+	for _, 子 := range k.SubKeys {
+		if hive比较名称(子.Name, 名称) == 0 {
+			return 子
+		}
+	}
+	return nil
+}
+
+// I查找 按反斜杠分隔的相对路径查找子项。空路径返回k本身。不存在时返回nil。
+func (k *I离线表项) I查找(路径 string) *I离线表项 {
+	当前 := k
+	for _, 段 := range 拆分路径(路径) {
+		当前 = 当前.I取子项(段)
+		if 当前 == nil {
+			return nil
+		}
+	}
+	return 当前
+}
+
+// 拆分路径 按反斜杠拆分路径, 忽略空段。
+func 拆分路径(路径 string) []string {
+	var 段 []string
+	for _, s := range strings.Split(路径, `\`) {
+		if s != "" {
+			段 = append(段, s)
+		}
+	}
+	return 段
+}
+
+// I取值 返回名称为'名称'的值, 名称不区分大小写。不存在时返回nil。
+func (k *I离线表项) I取值(名称 string) *I离线值 {
+	if k == nil {
+		return nil
+	}
+	for _, v := range k.Values {
+		if hive比较名称(v.Name, 名称) == 0 {
+			return v
+		}
+	}
+	return nil
+}
+
+// I设置值 添加或替换名称相同(不区分大小写)的值。
+func (k *I离线表项) I设置值(值 *I离线值) error {
+	if k == nil || 值 == nil {
+		return errors.New("注册表类对象为nil")
+	}
+	if err := hive检查名称(值.Name, true); err != nil {
+		return err
+	}
+	for i, v := range k.Values {
+		if hive比较名称(v.Name, 值.Name) == 0 {
+			k.Values[i] = 值
+			return nil
+		}
+	}
+	k.Values = append(k.Values, 值)
+	return nil
+}
+
+// I删除值 删除名称为'名称'的值。不存在时返回 ErrOfflineNotExist。
+func (k *I离线表项) I删除值(名称 string) error {
+	if k == nil {
+		return errors.New("注册表类对象为nil")
+	}
+	for i, v := range k.Values {
+		if hive比较名称(v.Name, 名称) == 0 {
+			k.Values = append(k.Values[:i:i], k.Values[i+1:]...)
+			return nil
+		}
+	}
+	return ErrOfflineNotExist
+}
+
+// I创建子项 返回名称为'名称'的子项, 不存在时创建一个修改时间为当前时间的空子项。
+// 新子项的易失性与k相同。
+func (k *I离线表项) I创建子项(名称 string) (子 *I离线表项, 是否已存在 bool, err error) {
+	var 选项 uint32
+	if k.I是易失() {
+		选项 = OPTION_VOLATILE
+	}
+	return k.I创建子项Ex(名称, 选项, "")
+}
+
+// I创建子项Ex 与 I创建子项 相同, 但可以指定 OPTION_VOLATILE 和类名, 子项已存在时忽略它们。
+// 与RegCreateKeyEx一样, 易失表项下只能创建易失子项。
+func (k *I离线表项) I创建子项Ex(名称 string, 选项 uint32, 类名 string) (子 *I离线表项, 是否已存在 bool, err error) {
+	if k == nil {
+		return nil, false, errors.New("注册表类对象为nil")
+	}
+	if 子 = k.I取子项(名称); 子 != nil {
+		return 子, true, nil
+	}
+	if err := hive检查名称(名称, false); err != nil {
+		return nil, false, err
+	}
+	if k.I是易失() && 选项&OPTION_VOLATILE == 0 {
+		return nil, false, fmt.Errorf("易失表项 %q 下只能创建易失子项", k.Name)
+	}
+	子 = &I离线表项{Name: 名称, Class: 类名, ModTime: time.Now().UTC()}
+	if 选项&OPTION_VOLATILE != 0 {
+		子.Flags |= hive标志_易失
+	}
+	k.SubKeys = append(k.SubKeys, 子)
+	return 子, false, nil
+}
+
+// I是易失 报告离线表项是否是易失表项。
+func (k *I离线表项) I是易失() bool {
+	return k != nil && k.Flags&hive标志_易失 != 0
+}
+
+// I模拟重启 删除k下所有易失子项, 模拟重启或卸载配置单元后易失表项消失的效果。
+func (k *I离线表项) I模拟重启() {
+	if k == nil {
+		return
+	}
+	保留 := k.SubKeys[:0]
+	for _, 子 := range k.SubKeys {
+		if !子.I是易失() {
+			子.I模拟重启()
+			保留 = append(保留, 子)
+		}
+	}
+	for i := len(保留); i < len(k.SubKeys); i++ {
+		k.SubKeys[i] = nil
+	}
+	k.SubKeys = 保留
+}
+
+// I删除子项 删除名称为'名称'的子项。与RegDeleteKey一样, 子项本身还有子项时返回错误。
+// 不存在时返回 ErrOfflineNotExist。
+func (k *I离线表项) I删除子项(名称 string) error {
+	if k == nil {
+		return errors.New("注册表类对象为nil")
+	}
+	for i, 子 := range k.SubKeys {
+		if hive比较名称(子.Name, 名称) == 0 {
+			if len(子.SubKeys) > 0 {
+				return fmt.Errorf("表项 %q 还有子项, 不能删除", 子.Name)
+			}
+			k.SubKeys = append(k.SubKeys[:i:i], k.SubKeys[i+1:]...)
+			return nil
+		}
+	}
+	return ErrOfflineNotExist
+}
+
+// I解析配置单元 解析regf格式的配置单元文件, 返回根表项。
+// 任何错误级别的校验问题都会导致返回 ErrCorruptHive;
+// 需要详细报告时使用 I校验配置单元, 需要抢救数据时使用 I修复配置单元。
+func I解析配置单元(数据 []byte) (*I离线表项, error) {
+	w := hive新遍历器(数据)
+	根 := w.遍历()
+	if 错误 := w.报告.I取错误(); len(错误) > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrCorruptHive, 错误[0].Message)
+	}
+	return 根, nil
+}
+
+// I生成配置单元 把以'根'为根的表项树写成regf格式的配置单元文件。
+// 子项按Windows的不区分大小写顺序重新排序并重建索引, 相同的安全描述符只保存一份。
+// 与Windows保存配置单元时一样, 易失表项不会写入文件。
+func I生成配置单元(根 *I离线表项) ([]byte, error) {
+	if 根 == nil {
+		return nil, errors.New("根表项为nil")
+	}
+	w := &hive写入器{安全: map[string]uint32{}, 安全计数: map[uint32]uint32{}}
+	根偏移, err := w.写入表项(根, hive无效偏移, hive默认安全描述符, true)
+	if err != nil {
+		return nil, err
+	}
+	w.结束hbin()
+	w.链接安全单元()
+
+	结果 := make([]byte, hive基块大小, hive基块大小+len(w.缓冲))
+	copy(结果, "regf")
+	le.PutUint32(结果[0x04:], 1)
+	le.PutUint32(结果[0x08:], 1)
+	le.PutUint64(结果[0x0c:], hive取filetime(根.ModTime))
+	le.PutUint32(结果[0x14:], hive主版本)
+	le.PutUint32(结果[0x18:], hive次版本)
+	le.PutUint32(结果[0x1c:], 0) // 主文件
+	le.PutUint32(结果[0x20:], 1) // 直接内存加载
+	le.PutUint32(结果[0x24:], 根偏移)
+	le.PutUint32(结果[0x28:], uint32(len(w.缓冲)))
+	le.PutUint32(结果[0x2c:], 1)
+	le.PutUint32(结果[0x1fc:], hive基块校验和(结果))
+	return append(结果, w.缓冲...), nil
+}
+
+// hive写入器 按顺序分配单元, 生成紧凑的hbin数据。
+type hive写入器 struct {
+	缓冲     []byte // hbin数据, 不含基块, 偏移即单元偏移
+	hbin末尾 int
+	安全     map[string]uint32 // 安全描述符内容 → sk单元偏移
+	安全顺序   []uint32
+	安全计数   map[uint32]uint32
+}
+
+// 分配 分配一个能容纳'大小'字节内容的单元, 返回单元偏移。
+func (w *hive写入器) 分配(大小 int) uint32 {
+	需要 := (大小 + 4 + 7) &^ 7
+	if len(w.缓冲)+需要 > w.hbin末尾 {
+		w.结束hbin()
+		起始 := w.hbin末尾
+		hbin大小 := (hbin头大小 + 需要 + hbin对齐 - 1) &^ (hbin对齐 - 1)
+		w.缓冲 = append(w.缓冲, make([]byte, hbin头大小)...)
+		copy(w.缓冲[起始:], "hbin")
+		le.PutUint32(w.缓冲[起始+4:], uint32(起始))
+		le.PutUint32(w.缓冲[起始+8:], uint32(hbin大小))
+		w.hbin末尾 = 起始 + hbin大小
+	}
+	偏移 := len(w.缓冲)
+	w.缓冲 = append(w.缓冲, make([]byte, 需要)...)
+	le.PutUint32(w.缓冲[偏移:], uint32(-int32(需要)))
+	return uint32(偏移)
+}
+
+// 单元 返回偏移处单元的内容部分。后续分配可能使返回的切片失效。
+func (w *hive写入器) 单元(偏移 uint32) []byte {
+	大小 := int(-int32(le.Uint32(w.缓冲[偏移:])))
+	return w.缓冲[int(偏移)+4 : int(偏移)+大小]
+}
+
+// 结束hbin 用一个空闲单元填满当前hbin的剩余空间。
+func (w *hive写入器) 结束hbin() {
+	if 剩余 := w.hbin末尾 - len(w.缓冲); 剩余 > 0 {
+		偏移 := len(w.缓冲)
+		w.缓冲 = append(w.缓冲, make([]byte, 剩余)...)
+		le.PutUint32(w.缓冲[偏移:], uint32(剩余))
+	}
+}
+
+func (w *hive写入器) 写入表项(项 *I离线表项, 父偏移 uint32, 父安全 []byte, 是根 bool) (uint32, error) {
+	if !是根 {
+		if err := hive检查名称(项.Name, false); err != nil {
+			return 0, err
+		}
+	}
+	名称, 压缩 := hive编码名称(项.Name)
+	偏移 := w.分配(nk固定大小 + len(名称))
+
+	类名偏移 := uint32(hive无效偏移)
+	类名 := hive编码UTF16(项.Class)
+	if len(类名) > 0xffff {
+		return 0, fmt.Errorf("表项 %q 的类名过长", 项.Name)
+	}
+	if len(类名) > 0 {
+		类名偏移 = w.分配(len(类名))
+		copy(w.单元(类名偏移), 类名)
+	}
+
+	安全 := 项.SecurityDescriptor
+	if len(安全) == 0 {
+		安全 = 父安全
+	}
+	安全偏移 := w.安全单元(安全)
+
+	值列表 := uint32(hive无效偏移)
+	var 最长值名, 最长值数据 int
+	if len(项.Values) > 0 {
+		已有 := make(map[string]bool, len(项.Values))
+		for _, v := range 项.Values {
+			if err := hive检查名称(v.Name, true); err != nil {
+				return 0, err
+			}
+			键 := string(utf16转字节(hive大写(v.Name)))
+			if 已有[键] {
+				return 0, fmt.Errorf("表项 %q 中的值 %q 重复", 项.Name, v.Name)
+			}
+			已有[键] = true
+		}
+		值列表 = w.分配(4 * len(项.Values))
+		for i, v := range 项.Values {
+			值偏移, err := w.写入值(v)
+			if err != nil {
+				return 0, err
+			}
+			le.PutUint32(w.单元(值列表)[4*i:], 值偏移)
+			最长值名 = max(最长值名, 2*len(utf16.Encode([]rune(v.Name))))
+			最长值数据 = max(最长值数据, len(v.Data))
+		}
+	}
+
+	var 子项 []*I离线表项
+	for _, 子 := range 项.SubKeys {
+		if !子.I是易失() {
+			子项 = append(子项, 子)
+		}
+	}
+	sort.SliceStable(子项, func(i, j int) bool { return hive比较名称(子项[i].Name, 子项[j].Name) < 0 })
+	子偏移 := make([]uint32, len(子项))
+	var 最长子项名, 最长子项类名 int
+	for i, 子 := range 子项 {
+		if i > 0 && hive比较名称(子项[i-1].Name, 子.Name) == 0 {
+			return 0, fmt.Errorf("表项 %q 中的子项 %q 重复", 项.Name, 子.Name)
+		}
+		o, err := w.写入表项(子, 偏移, 安全, false)
+		if err != nil {
+			return 0, err
+		}
+		子偏移[i] = o
+		最长子项名 = max(最长子项名, 2*len(utf16.Encode([]rune(子.Name))))
+		最长子项类名 = max(最长子项类名, 2*len(utf16.Encode([]rune(子.Class))))
+	}
+	索引 := w.写入子项索引(子项, 子偏移)
+
+	标志 := 项.Flags &^ (hive标志_压缩名称 | hive标志_根项)
+	if 压缩 {
+		标志 |= hive标志_压缩名称
+	}
+	if 是根 {
+		标志 |= hive标志_根项 | hive标志_不可删除
+	}
+	c := w.单元(偏移)
+	copy(c, "nk")
+	le.PutUint16(c[0x02:], 标志)
+	le.PutUint64(c[0x04:], hive取filetime(项.ModTime))
+	le.PutUint32(c[0x10:], 父偏移)
+	le.PutUint32(c[0x14:], uint32(len(子项)))
+	le.PutUint32(c[0x1c:], 索引)
+	le.PutUint32(c[0x20:], hive无效偏移)
+	le.PutUint32(c[0x24:], uint32(len(项.Values)))
+	le.PutUint32(c[0x28:], 值列表)
+	le.PutUint32(c[0x2c:], 安全偏移)
+	le.PutUint32(c[0x30:], 类名偏移)
+	le.PutUint32(c[0x34:], uint32(最长子项名))
+	le.PutUint32(c[0x38:], uint32(最长子项类名))
+	le.PutUint32(c[0x3c:], uint32(最长值名))
+	le.PutUint32(c[0x40:], uint32(最长值数据))
+	le.PutUint16(c[0x48:], uint16(len(名称)))
+	le.PutUint16(c[0x4a:], uint16(len(类名)))
+	copy(c[nk固定大小:], 名称)
+	return 偏移, nil
+}
+
+func (w *hive写入器) 写入子项索引(子项 []*I离线表项, 子偏移 []uint32) uint32 {
+	if len(子项) == 0 {
+		return hive无效偏移
+	}
+	var 叶 []uint32
+	for 起始 := 0; 起始 < len(子项); 起始 += hive叶最大条目 {
+		结束 := min(起始+hive叶最大条目, len(子项))
+		偏移 := w.分配(4 + 8*(结束-起始))
+		c := w.单元(偏移)
+		copy(c, "lh")
+		le.PutUint16(c[2:], uint16(结束-起始))
+		for i := 起始; i < 结束; i++ {
+			le.PutUint32(c[4+8*(i-起始):], 子偏移[i])
+			le.PutUint32(c[8+8*(i-起始):], hive名称哈希(子项[i].Name))
+		}
+		叶 = append(叶, 偏移)
+	}
+	if len(叶) == 1 {
+		return 叶[0]
+	}
+	偏移 := w.分配(4 + 4*len(叶))
+	c := w.单元(偏移)
+	copy(c, "ri")
+	le.PutUint16(c[2:], uint16(len(叶)))
+	for i, o := range 叶 {
+		le.PutUint32(c[4+4*i:], o)
+	}
+	return 偏移
+}
+
+func (w *hive写入器) 写入值(v *I离线值) (uint32, error) {
+	if len(v.Data) > hive数据大小掩码 {
+		return 0, fmt.Errorf("值 %q 的数据过大", v.Name)
+	}
+	名称, 压缩 := hive编码名称(v.Name)
+	偏移 := w.分配(vk固定大小 + len(名称))
+	var 大小, 数据偏移 uint32
+	var 内联 [4]byte
+	switch n := len(v.Data); {
+	case n <= 4:
+		大小 = hive内联数据标志 | uint32(n)
+		copy(内联[:], v.Data)
+		数据偏移 = le.Uint32(内联[:])
+	case n > hive大数据段大小:
+		段数 := (n + hive大数据段大小 - 1) / hive大数据段大小
+		段列表 := w.分配(4 * 段数)
+		for i := 0; i < 段数; i++ {
+			段 := v.Data[i*hive大数据段大小 : min((i+1)*hive大数据段大小, n)]
+			段偏移 := w.分配(len(段))
+			copy(w.单元(段偏移), 段)
+			le.PutUint32(w.单元(段列表)[4*i:], 段偏移)
+		}
+		数据偏移 = w.分配(8)
+		c := w.单元(数据偏移)
+		copy(c, "db")
+		le.PutUint16(c[2:], uint16(段数))
+		le.PutUint32(c[4:], 段列表)
+		大小 = uint32(n)
+	default:
+		数据偏移 = w.分配(n)
+		copy(w.单元(数据偏移), v.Data)
+		大小 = uint32(n)
+	}
+	c := w.单元(偏移)
+	copy(c, "vk")
+	le.PutUint16(c[0x02:], uint16(len(名称)))
+	le.PutUint32(c[0x04:], 大小)
+	le.PutUint32(c[0x08:], 数据偏移)
+	le.PutUint32(c[0x0c:], v.Type)
+	if 压缩 {
+		le.PutUint16(c[0x10:], hive值标志_压缩名称)
+	}
+	copy(c[vk固定大小:], 名称)
+	return 偏移, nil
+}
+
+// 安全单元 返回保存'安全'的sk单元偏移, 相同内容的安全描述符共用一个单元。
+func (w *hive写入器) 安全单元(安全 []byte) uint32 {
+	if 偏移, ok := w.安全[string(安全)]; ok {
+		w.安全计数[偏移]++
+		return 偏移
+	}
+	偏移 := w.分配(sk固定大小 + len(安全))
+	c := w.单元(偏移)
+	copy(c, "sk")
+	le.PutUint32(c[0x10:], uint32(len(安全)))
+	copy(c[sk固定大小:], 安全)
+	w.安全[string(安全)] = 偏移
+	w.安全顺序 = append(w.安全顺序, 偏移)
+	w.安全计数[偏移] = 1
+	return 偏移
+}
+
+// 链接安全单元 把所有sk单元串成双向循环链表并写入引用计数。
+func (w *hive写入器) 链接安全单元() {
+	n := len(w.安全顺序)
+	for i, 偏移 := range w.安全顺序 {
+		c := w.单元(偏移)
+		le.PutUint32(c[0x04:], w.安全顺序[(i+1)%n])
+		le.PutUint32(c[0x08:], w.安全顺序[(i+n-1)%n])
+		le.PutUint32(c[0x0c:], w.安全计数[偏移])
+	}
+}
+
+// hive基块校验和 计算基块前508字节的异或校验和。
+func hive基块校验和(b []byte) uint32 {
+	var x uint32
+	for i := 0; i < 0x1fc; i += 4 {
+		x ^= le.Uint32(b[i:])
+	}
+	switch x {
+	case 0xffffffff:
+		return 0xfffffffe
+	case 0:
+		return 1
+	}
+	return x
+}
+
+// hive检查名称 检查表项名称或值名称是否可以写入配置单元。值名称可以为空(默认值)。
+func hive检查名称(名称 string, 是值 bool) error {
+	最大长度 := hive名称最大长度
+	if 是值 {
+		最大长度 = hive值名最大长度
+	} else {
+		if 名称 == "" {
+			return errors.New("表项名称不能为空")
+		}
+		if strings.Contains(名称, `\`) {
+			return fmt.Errorf("表项名称 %q 不能包含反斜杠", 名称)
+		}
+	}
+	if len(utf16.Encode([]rune(名称))) > 最大长度 {
+		return fmt.Errorf("名称 %q 过长", 名称)
+	}
+	return nil
+}
+
+// hive编码名称 返回名称在nk/vk记录中的存储形式。
+// 所有字符都小于0x100时使用每字符一字节的压缩形式。
+func hive编码名称(名称 string) ([]byte, bool) {
+	u := utf16.Encode([]rune(名称))
+	for _, c := range u {
+		if c > 0xff {
+			return utf16转字节(u), false
+		}
+	}
+	b := make([]byte, len(u))
+	for i, c := range u {
+		b[i] = byte(c)
+	}
+	return b, true
+}
+
+func hive解码名称(b []byte, 压缩 bool) string {
+	if !压缩 {
+		return hive解码UTF16(b)
+	}
+	r := make([]rune, len(b))
+	for i, c := range b {
+		r[i] = rune(c)
+	}
+	return string(r)
+}
+
+func hive编码UTF16(s string) []byte {
+	return utf16转字节(utf16.Encode([]rune(s)))
+}
+
+func hive解码UTF16(b []byte) string {
+	u := make([]uint16, len(b)/2)
+	for i := range u {
+		u[i] = le.Uint16(b[2*i:])
+	}
+	return string(utf16.Decode(u))
+}
+
+func utf16转字节(u []uint16) []byte {
+	b := make([]byte, 2*len(u))
+	for i, c := range u {
+		le.PutUint16(b[2*i:], c)
+	}
+	return b
+}
+
+// hive大写 按注册表比较名称的方式把名称转换为大写的UTF-16序列。
+func hive大写(名称 string) []uint16 {
+	u := utf16.Encode([]rune(名称))
+	for i, c := range u {
+		if 大写 := unicode.ToUpper(rune(c)); 大写 <= 0xffff {
+			u[i] = uint16(大写)
+		}
+	}
+	return u
+}
+
+// hive比较名称 以不区分大小写的方式比较两个名称, 与子项索引的排序规则一致。
+func hive比较名称(a, b string) int {
+	ua, ub := hive大写(a), hive大写(b)
+	for i := 0; i < len(ua) && i < len(ub); i++ {
+		if ua[i] != ub[i] {
+			if ua[i] < ub[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return len(ua) - len(ub)
+}
+
+// hive名称哈希 计算lh索引中使用的名称哈希。
+func hive名称哈希(名称 string) uint32 {
+	var h uint32
+	for _, c := range hive大写(名称) {
+		h = h*37 + uint32(c)
+	}
+	return h
+}
+
+func hive取filetime(t time.Time) uint64 {
+	if t.IsZero() {
+		return 0
+	}
+	return uint64(t.Unix()+filetime纪元差)*1e7 + uint64(t.Nanosecond()/100)
+}
+
+func hive解析filetime(ft uint64) time.Time {
+	if ft == 0 {
+		return time.Time{}
+	}
+	return time.Unix(int64(ft/1e7)-filetime纪元差, int64(ft%1e7)*100).UTC()
+}
+
+// hive默认安全描述符 用于没有安全描述符的根表项:
+// 所有者为Administrators, SYSTEM和Administrators完全控制, Everyone读取。
+var hive默认安全描述符 = func() []byte {
+	sd, err := I解析SDDL("O:BAG:SYD:(A;CI;KA;;;SY)(A;CI;KA;;;BA)(A;CI;KR;;;WD)")
+	if err != nil {
+		panic(err)
+	}
+	数据, err := sd.I生成()
+	if err != nil {
+		panic(err)
+	}
+	return 数据
+}()
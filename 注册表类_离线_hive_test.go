@@ -0,0 +1,403 @@
+package 注册表类_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"runtime"
+	"testing"
+	"time"
+
+	注册表类 "e.coding.net/gogit/go/gosdk/core/win_registry_cn"
+)
+
+func testHiveTree() *注册表类.I离线表项 {
+	mod := time.Date(2024, 5, 6, 7, 8, 9, 123456700, time.UTC)
+	big := bytes.Repeat([]byte{1, 2, 3, 4, 5, 6, 7}, 5000)
+	return &注册表类.I离线表项{
+		Name:    "ROOT",
+		ModTime: mod,
+		SubKeys: []*注册表类.I离线表项{
+			{
+				Name:    "Software",
+				Class:   "SoftwareClass",
+				ModTime: mod,
+				Values: []*注册表类.I离线值{
+					{Name: "", Type: 注册表类.SZ, Data: []byte("d\x00\x00\x00")},
+					{Name: "Dword", Type: 注册表类.DWORD, Data: []byte{1, 0, 0, 0}},
+					{Name: "Empty", Type: 注册表类.BINARY, Data: []byte{}},
+					{Name: "Big", Type: 注册表类.BINARY, Data: big},
+					{Name: "Mid", Type: 注册表类.BINARY, Data: bytes.Repeat([]byte{9}, 100)},
+					{Name: "名称", Type: 注册表类.QWORD, Data: []byte{1, 2, 3, 4, 5, 6, 7, 8}},
+				},
+				SubKeys: []*注册表类.I离线表项{
+					{Name: "b", ModTime: mod},
+					{Name: "A", ModTime: mod},
+					{Name: "中文", ModTime: mod},
+				},
+			},
+			{Name: "System", ModTime: mod},
+		},
+	}
+}
+
+func equalHiveKey(t *testing.T, path string, want, got *注册表类.I离线表项) {
+	t.Helper()
+	if want.Name != got.Name || want.Class != got.Class || !want.ModTime.Equal(got.ModTime) {
+		t.Errorf("%s: got key %q class %q time %v, want %q class %q time %v", path, got.Name, got.Class, got.ModTime, want.Name, want.Class, want.ModTime)
+	}
+	if len(want.Values) != len(got.Values) {
+		t.Fatalf("%s: got %d values, want %d", path, len(got.Values), len(want.Values))
+	}
+	for i, v := range want.Values {
+		g := got.Values[i]
+		if v.Name != g.Name || v.Type != g.Type || !bytes.Equal(v.Data, g.Data) {
+			t.Errorf("%s: value %d: got %q type %d (%d bytes), want %q type %d (%d bytes)", path, i, g.Name, g.Type, len(g.Data), v.Name, v.Type, len(v.Data))
+		}
+	}
+	for _, w := range want.SubKeys {
+		g := got.I取子项(w.Name)
+		if g == nil {
+			t.Errorf("%s: missing subkey %q", path, w.Name)
+			continue
+		}
+		equalHiveKey(t, path+`\`+w.Name, w, g)
+	}
+	if len(want.SubKeys) != len(got.SubKeys) {
+		t.Errorf("%s: got %d subkeys, want %d", path, len(got.SubKeys), len(want.SubKeys))
+	}
+}
+
+func TestHiveRoundTrip(t *testing.T) {
+	tree := testHiveTree()
+	data, err := 注册表类.I生成配置单元(tree)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := 注册表类.I校验配置单元(data)
+	if len(report.Issues) != 0 {
+		t.Fatalf("unexpected issues in generated hive: %v", report.Issues)
+	}
+	if report.Keys != 6 || report.Values != 6 || report.SecurityDescriptors != 1 {
+		t.Errorf("got %d keys, %d values, %d security descriptors", report.Keys, report.Values, report.SecurityDescriptors)
+	}
+	got, err := 注册表类.I解析配置单元(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	equalHiveKey(t, "", tree, got)
+
+	names := []string{}
+	for _, k := range got.I查找("Software").SubKeys {
+		names = append(names, k.Name)
+	}
+	if fmt.Sprint(names) != "[A b 中文]" {
+		t.Errorf("subkeys are not sorted: %v", names)
+	}
+	if got.I查找(`SOFTWARE\a`) == nil {
+		t.Error(`lookup of SOFTWARE\a failed`)
+	}
+}
+
+func TestHiveManySubKeys(t *testing.T) {
+	root := &注册表类.I离线表项{Name: "ROOT"}
+	for i := 0; i < 1500; i++ {
+		root.SubKeys = append(root.SubKeys, &注册表类.I离线表项{Name: fmt.Sprintf("Key%04d", i)})
+	}
+	data, err := 注册表类.I生成配置单元(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report := 注册表类.I校验配置单元(data); len(report.Issues) != 0 {
+		t.Fatalf("unexpected issues: %v", report.Issues[0])
+	}
+	got, err := 注册表类.I解析配置单元(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.SubKeys) != 1500 || got.SubKeys[1499].Name != "Key1499" {
+		t.Errorf("got %d subkeys", len(got.SubKeys))
+	}
+}
+
+func TestHiveRejectsDuplicates(t *testing.T) {
+	root := &注册表类.I离线表项{Name: "ROOT", SubKeys: []*注册表类.I离线表项{{Name: "a"}, {Name: "A"}}}
+	if _, err := 注册表类.I生成配置单元(root); err == nil {
+		t.Error("duplicate subkeys should fail")
+	}
+	root = &注册表类.I离线表项{Name: "ROOT", Values: []*注册表类.I离线值{{Name: "v"}, {Name: "V"}}}
+	if _, err := 注册表类.I生成配置单元(root); err == nil {
+		t.Error("duplicate values should fail")
+	}
+}
+
+// findCell returns the file offset of the first allocated cell whose content starts with sig
+// and contains needle.
+func findCell(t *testing.T, data []byte, sig string, needle []byte) int {
+	t.Helper()
+	for pos := 0x1000 + 0x20; pos+8 <= len(data); {
+		if string(data[pos:pos+4]) == "hbin" {
+			pos += 0x20
+			continue
+		}
+		size := int32(binary.LittleEndian.Uint32(data[pos:]))
+		if size < 0 {
+			c := data[pos+4 : pos-int(size)]
+			if string(c[:2]) == sig && bytes.Contains(c, needle) {
+				return pos
+			}
+			size = -size
+		}
+		pos += int(size)
+	}
+	t.Fatalf("no %s cell containing %q", sig, needle)
+	return 0
+}
+
+func hasIssue(report *注册表类.I配置单元报告, code string) bool {
+	for _, p := range report.Issues {
+		if p.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
+func TestHiveCheckDetectsCorruption(t *testing.T) {
+	clean, err := 注册表类.I生成配置单元(testHiveTree())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		corrupt func(d []byte)
+		code    string
+	}{
+		{"checksum", func(d []byte) { d[0x1fc] ^= 0xff }, "base.checksum"},
+		{"hbin", func(d []byte) { copy(d[0x1000:], "xbin") }, "hbin.signature"},
+		{"order", func(d []byte) {
+			// rename "b" to "0" so that it sorts before "A"
+			pos := findCell(t, d, "nk", []byte("b"))
+			d[pos+4+0x4c] = '0'
+		}, "index.order"},
+		{"hash", func(d []byte) {
+			pos := findCell(t, d, "lh", nil)
+			d[pos+4+8] ^= 1
+		}, "index.hash"},
+		{"subkey count", func(d []byte) {
+			pos := findCell(t, d, "nk", []byte("Software"))
+			d[pos+4+0x14]++
+		}, "index.count"},
+		{"value count", func(d []byte) {
+			pos := findCell(t, d, "nk", []byte("Software"))
+			d[pos+4+0x24] += 20
+		}, "values.count"},
+		{"refcount", func(d []byte) {
+			pos := findCell(t, d, "sk", nil)
+			d[pos+4+0x0c]++
+		}, "security.refcount"},
+		{"parent", func(d []byte) {
+			pos := findCell(t, d, "nk", []byte("System"))
+			d[pos+4+0x10] ^= 0x40
+		}, "key.parent"},
+	}
+	for _, test := range tests {
+		data := bytes.Clone(clean)
+		test.corrupt(data)
+		report := 注册表类.I校验配置单元(data)
+		if !hasIssue(report, test.code) {
+			t.Errorf("%s: expected issue %s, got %v", test.name, test.code, report.Issues)
+		}
+		if test.code != "base.checksum" && report.I是否有效() {
+			t.Errorf("%s: report should not be valid", test.name)
+		}
+		if _, err := 注册表类.I解析配置单元(data); !errors.Is(err, 注册表类.ErrCorruptHive) {
+			t.Errorf("%s: parse returned %v, want ErrCorruptHive", test.name, err)
+		}
+	}
+}
+
+func TestHiveCheckHugeDataSize(t *testing.T) {
+	data, err := 注册表类.I生成配置单元(testHiveTree())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Claim that the big-data value "Big" is almost 2 GiB long.
+	pos := findCell(t, data, "vk", []byte("Big"))
+	binary.LittleEndian.PutUint32(data[pos+4+4:], 0x7fffffff)
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	report := 注册表类.I校验配置单元(data)
+	runtime.ReadMemStats(&after)
+	if !hasIssue(report, "value.data") {
+		t.Errorf("expected issue value.data, got %v", report.Issues)
+	}
+	if n := after.TotalAlloc - before.TotalAlloc; n > 64<<20 {
+		t.Errorf("checking the hive allocated %d bytes", n)
+	}
+	if _, err := 注册表类.I解析配置单元(data); !errors.Is(err, 注册表类.ErrCorruptHive) {
+		t.Errorf("parse returned %v, want ErrCorruptHive", err)
+	}
+}
+
+func TestHiveRepair(t *testing.T) {
+	data, err := 注册表类.I生成配置单元(testHiveTree())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Point the "Mid" value at a free cell and break the checksum.
+	pos := findCell(t, data, "vk", []byte("Mid"))
+	binary.LittleEndian.PutUint32(data[pos+4+8:], 0x7ff0)
+	data[0x1fc] ^= 0xff
+
+	fixed, report, err := 注册表类.I修复配置单元(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.I是否有效() {
+		t.Error("report of the corrupt hive should contain errors")
+	}
+	if r := 注册表类.I校验配置单元(fixed); len(r.Issues) != 0 {
+		t.Fatalf("repaired hive has issues: %v", r.Issues)
+	}
+	got, err := 注册表类.I解析配置单元(fixed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sw := got.I查找("Software")
+	if sw.I取值("Mid") != nil || sw.I取值("Big") == nil || len(sw.SubKeys) != 3 {
+		t.Errorf("unexpected salvaged content: %d values, %d subkeys", len(sw.Values), len(sw.SubKeys))
+	}
+
+	if _, _, err := 注册表类.I修复配置单元([]byte("not a hive")); !errors.Is(err, 注册表类.ErrCorruptHive) {
+		t.Errorf("repair of garbage returned %v", err)
+	}
+}
+
+func TestHiveRepairBadName(t *testing.T) {
+	data, err := 注册表类.I生成配置单元(testHiveTree())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Rename the subkey "b" to a backslash, which cannot be written back.
+	pos := findCell(t, data, "nk", []byte("b"))
+	data[pos+4+0x4c] = '\\'
+
+	fixed, report, err := 注册表类.I修复配置单元(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !hasIssue(report, "key.name") {
+		t.Errorf("expected issue key.name, got %v", report.Issues)
+	}
+	got, err := 注册表类.I解析配置单元(fixed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sw := got.I查找("Software"); len(sw.SubKeys) != 2 || sw.I查找("A") == nil {
+		t.Errorf("unexpected salvaged subkeys: %d", len(sw.SubKeys))
+	}
+}
+
+// bloatHive appends an hbin to a generated hive holding a second copy of its only
+// security descriptor, used by the "System" key, followed by free space.
+func bloatHive(t *testing.T, data []byte) []byte {
+	le := binary.LittleEndian
+	sk := findCell(t, data, "sk", nil)
+	skSize := int(-int32(le.Uint32(data[sk:])))
+	start := len(data)
+	hbin := make([]byte, 0x2000)
+	copy(hbin, "hbin")
+	le.PutUint32(hbin[4:], uint32(start-0x1000))
+	le.PutUint32(hbin[8:], uint32(len(hbin)))
+	copy(hbin[0x20:], data[sk:sk+skSize])
+	le.PutUint32(hbin[0x20+skSize:], uint32(len(hbin)-0x20-skSize))
+	data = append(data, hbin...)
+
+	oldCell, newCell := uint32(sk-0x1000), uint32(start-0x1000+0x20)
+	for _, link := range []struct{ at, to uint32 }{{oldCell, newCell}, {newCell, oldCell}} {
+		c := data[0x1000+link.at+4:]
+		le.PutUint32(c[0x04:], link.to)
+		le.PutUint32(c[0x08:], link.to)
+	}
+	le.PutUint32(data[0x1000+newCell+4+0x0c:], 1)
+	le.PutUint32(data[sk+4+0x0c:], le.Uint32(data[sk+4+0x0c:])-1)
+	nk := findCell(t, data, "nk", []byte("System"))
+	le.PutUint32(data[nk+4+0x2c:], newCell)
+
+	le.PutUint32(data[0x28:], uint32(len(data)-0x1000))
+	le.PutUint32(data[0x1fc:], 0)
+	var sum uint32
+	for i := 0; i < 0x1fc; i += 4 {
+		sum ^= le.Uint32(data[i:])
+	}
+	le.PutUint32(data[0x1fc:], sum)
+	return data
+}
+
+func TestHiveCompact(t *testing.T) {
+	data, err := 注册表类.I生成配置单元(testHiveTree())
+	if err != nil {
+		t.Fatal(err)
+	}
+	bloated := bloatHive(t, data)
+	if report := 注册表类.I校验配置单元(bloated); len(report.Issues) != 0 || report.SecurityDescriptors != 2 {
+		t.Fatalf("bloated hive: %d security descriptors, issues %v", report.SecurityDescriptors, report.Issues)
+	}
+
+	compacted, result, err := 注册表类.I压缩配置单元(bloated)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.I节省字节数() != int64(len(bloated)-len(compacted)) || result.I节省字节数() < 0x2000 {
+		t.Errorf("saved %d bytes, bloated %d, compacted %d", result.I节省字节数(), len(bloated), len(compacted))
+	}
+	if result.OriginalSecurityDescriptors != 2 || result.CompactedSecurityDescriptors != 1 {
+		t.Errorf("security descriptors: %d -> %d", result.OriginalSecurityDescriptors, result.CompactedSecurityDescriptors)
+	}
+	if result.CompactedFreeBytes >= result.OriginalFreeBytes {
+		t.Errorf("free bytes: %d -> %d", result.OriginalFreeBytes, result.CompactedFreeBytes)
+	}
+	before, _ := 注册表类.I解析配置单元(bloated)
+	after, _ := 注册表类.I解析配置单元(compacted)
+	if diff := 注册表类.I比较离线表项(before, after); len(diff) != 0 {
+		t.Errorf("compaction changed content: %v", diff)
+	}
+
+	bloated[0x1fc] ^= 0xff
+	if _, _, err := 注册表类.I压缩配置单元(bloated); !errors.Is(err, 注册表类.ErrCorruptHive) {
+		t.Errorf("compacting a corrupt hive returned %v", err)
+	}
+}
+
+func TestHiveDiff(t *testing.T) {
+	a, b := testHiveTree(), testHiveTree()
+	sw := b.I查找("Software")
+	sw.Values[1].Data = []byte{2, 0, 0, 0}
+	sw.Values = append(sw.Values[:2], sw.Values[3:]...) // drop "Empty"
+	sw.Values = append(sw.Values, &注册表类.I离线值{Name: "New", Type: 注册表类.SZ})
+	sw.SubKeys = sw.SubKeys[1:] // drop "b"
+	b.SubKeys = append(b.SubKeys, &注册表类.I离线表项{Name: "Added"})
+	b.I查找("System").Class = "changed"
+
+	got := []string{}
+	for _, d := range 注册表类.I比较离线表项(a, b) {
+		got = append(got, d.String())
+	}
+	want := []string{
+		`key added Added`,
+		`value changed Software ["Dword"]`,
+		`value deleted Software ["Empty"]`,
+		`value added Software ["New"]`,
+		`key deleted Software\b`,
+		`key changed System ([class])`,
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("got diff\n%v\nwant\n%v", got, want)
+	}
+	if d := 注册表类.I比较离线表项(a, b, 注册表类.I比较选项{IgnoreClass: true}); len(d) != 5 {
+		t.Errorf("IgnoreClass: got %d differences, want 5", len(d))
+	}
+}
@@ -0,0 +1,87 @@
+//go:build windows
+// +build windows
+
+package 注册表类
+
+import (
+	"errors"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+const (
+	// SE_BACKUP_NAME 和 SE_RESTORE_NAME 是加载和卸载配置单元所需的特权。
+	SE_BACKUP_NAME  = "SeBackupPrivilege"
+	SE_RESTORE_NAME = "SeRestorePrivilege"
+)
+
+// I启用特权 在当前进程的令牌中启用指定的特权, 例如 SE_BACKUP_NAME 和 SE_RESTORE_NAME。
+// 令牌中没有该特权时返回ERROR_NOT_ALL_ASSIGNED。
+func I启用特权(特权 ...string) error {
+	var 令牌 windows.Token
+	if err := windows.OpenProcessToken(windows.CurrentProcess(), windows.TOKEN_ADJUST_PRIVILEGES|windows.TOKEN_QUERY, &令牌); err != nil {
+		return err
+	}
+	defer 令牌.Close()
+	for _, 名称 := range 特权 {
+		var 特权值 windows.Tokenprivileges
+		特权值.PrivilegeCount = 1
+		特权值.Privileges[0].Attributes = windows.SE_PRIVILEGE_ENABLED
+		if err := windows.LookupPrivilegeValue(nil, windows.StringToUTF16Ptr(名称), &特权值.Privileges[0].Luid); err != nil {
+			return err
+		}
+		if err := adjustTokenPrivileges(令牌, &特权值); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var procAdjustTokenPrivileges = modadvapi32.NewProc("AdjustTokenPrivileges")
+
+// adjustTokenPrivileges 调用AdjustTokenPrivileges。没有全部分配时该函数也返回成功,
+// 只通过最后的错误报告ERROR_NOT_ALL_ASSIGNED, 所以必须使用与调用在同一线程上取得的错误,
+// 之后单独调用GetLastError时goroutine可能已经换了线程。
+func adjustTokenPrivileges(令牌 windows.Token, 特权值 *windows.Tokenprivileges) error {
+	r1, _, e1 := procAdjustTokenPrivileges.Call(uintptr(令牌), 0, uintptr(unsafe.Pointer(特权值)), 0, 0, 0)
+	if r1 == 0 {
+		return e1
+	}
+	if e1 == windows.ERROR_NOT_ALL_ASSIGNED {
+		return windows.ERROR_NOT_ALL_ASSIGNED
+	}
+	return nil
+}
+
+// I加载配置单元 使用RegLoadKey把配置单元文件加载到注册表对象k下的子项,
+// k只能是LOCAL_MACHINE或USERS。加载后可以像普通表项一样打开和修改,
+// 修改直接写回文件。调用者需要先用 I启用特权 启用 SE_BACKUP_NAME 和 SE_RESTORE_NAME。
+func I加载配置单元(k *Key结构, 子项, 文件 string) error {
+	if k == nil {
+		return errors.New("注册表类对象为nil")
+	}
+	p子项, err := syscall.UTF16PtrFromString(子项)
+	if err != nil {
+		return err
+	}
+	p文件, err := syscall.UTF16PtrFromString(文件)
+	if err != nil {
+		return err
+	}
+	return regLoadKey(syscall.Handle(k.Key父类), p子项, p文件)
+}
+
+// I卸载配置单元 使用RegUnLoadKey卸载 I加载配置单元 加载的子项。
+// 仍有打开的句柄指向该配置单元时返回ERROR_ACCESS_DENIED。
+func I卸载配置单元(k *Key结构, 子项 string) error {
+	if k == nil {
+		return errors.New("注册表类对象为nil")
+	}
+	p子项, err := syscall.UTF16PtrFromString(子项)
+	if err != nil {
+		return err
+	}
+	return regUnLoadKey(syscall.Handle(k.Key父类), p子项)
+}
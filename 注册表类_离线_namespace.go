@@ -0,0 +1,298 @@
+package 注册表类
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// I离线命名空间 把离线配置单元组合成与在线注册表相同的根项结构,
+// 例如把SYSTEM、SOFTWARE挂载到 HKEY_LOCAL_MACHINE 下, 把NTUSER.DAT和UsrClass.dat
+// 挂载到 HKEY_USERS\<SID> 和 HKEY_USERS\<SID>_Classes 下。
+//
+// 路径以根项开头, 支持 HKLM、HKU、HKCU、HKCR、HKCC 及其完整名称。
+// HKLM\SYSTEM\CurrentControlSet 按SYSTEM配置单元中的 Select 解析到实际的控制集,
+// HKEY_CURRENT_CONFIG 是其中 Hardware Profiles\Current 的别名。
+// 设置当前用户后, HKEY_CURRENT_USER 是 HKEY_USERS\<SID> 的别名,
+// HKEY_CLASSES_ROOT 是 HKLM\SOFTWARE\Classes 和 HKEY_USERS\<SID>_Classes 的合并视图。
+type I离线命名空间 struct {
+	本机   *I离线表项 // 虚拟的 HKEY_LOCAL_MACHINE
+	用户   *I离线表项 // 虚拟的 HKEY_USERS
+	当前用户 string
+}
+
+// I新建离线命名空间 返回没有挂载任何配置单元的命名空间。
+func I新建离线命名空间() *I离线命名空间 {
+	return &I离线命名空间{
+		本机: &I离线表项{Name: "HKEY_LOCAL_MACHINE"},
+		用户: &I离线表项{Name: "HKEY_USERS"},
+	}
+}
+
+// 根项名称 返回根项的完整名称, 不认识时返回空字符串。
+func 根项名称(名称 string) string {
+	switch strings.ToUpper(名称) {
+	case "HKEY_LOCAL_MACHINE", "HKLM", "LOCAL_MACHINE":
+		return "HKEY_LOCAL_MACHINE"
+	case "HKEY_USERS", "HKU", "USERS":
+		return "HKEY_USERS"
+	case "HKEY_CURRENT_USER", "HKCU", "CURRENT_USER":
+		return "HKEY_CURRENT_USER"
+	case "HKEY_CLASSES_ROOT", "HKCR", "CLASSES_ROOT":
+		return "HKEY_CLASSES_ROOT"
+	case "HKEY_CURRENT_CONFIG", "HKCC", "CURRENT_CONFIG":
+		return "HKEY_CURRENT_CONFIG"
+	}
+	return ""
+}
+
+// 挂载父项 返回 HKLM\<名称> 或 HKU\<名称> 形式的路径对应的虚拟根项和名称。
+func (n *I离线命名空间) 挂载父项(路径 string) (*I离线表项, string, error) {
+	段 := 拆分路径(路径)
+	if len(段) != 2 {
+		return nil, "", fmt.Errorf("挂载路径 %q 必须是 HKLM\\<名称> 或 HKU\\<名称>", 路径)
+	}
+	switch 根项名称(段[0]) {
+	case "HKEY_LOCAL_MACHINE":
+		return n.本机, 段[1], nil
+	case "HKEY_USERS":
+		return n.用户, 段[1], nil
+	}
+	return nil, "", fmt.Errorf("挂载路径 %q 必须是 HKLM\\<名称> 或 HKU\\<名称>", 路径)
+}
+
+// I加载 与RegLoadKey类似, 把配置单元的根项挂载到 HKLM\<名称> 或 HKU\<名称>。
+// 根项的Name会被改为<名称>; 之后通过命名空间所做的修改直接作用在根项上,
+// 可以用 I生成配置单元 写回文件。路径已被占用时返回错误。
+func (n *I离线命名空间) I加载(路径 string, 根 *I离线表项) error {
+	if n == nil || 根 == nil {
+		return errors.New("注册表类对象为nil")
+	}
+	父, 名称, err := n.挂载父项(路径)
+	if err != nil {
+		return err
+	}
+	if err := hive检查名称(名称, false); err != nil {
+		return err
+	}
+	if 父.I取子项(名称) != nil {
+		return fmt.Errorf("%s 已经存在", 路径)
+	}
+	根.Name = 名称
+	父.SubKeys = append(父.SubKeys, 根)
+	return nil
+}
+
+// I加载文件 解析regf格式的配置单元数据并挂载到路径, 见 I加载。
+func (n *I离线命名空间) I加载文件(路径 string, 数据 []byte) error {
+	根, err := I解析配置单元(数据)
+	if err != nil {
+		return err
+	}
+	return n.I加载(路径, 根)
+}
+
+// I卸载 与RegUnLoadKey类似, 卸载 I加载 挂载的配置单元, 返回它的根项。
+// 卸载当前用户的配置单元时同时清除当前用户。
+func (n *I离线命名空间) I卸载(路径 string) (*I离线表项, error) {
+	if n == nil {
+		return nil, errors.New("注册表类对象为nil")
+	}
+	父, 名称, err := n.挂载父项(路径)
+	if err != nil {
+		return nil, err
+	}
+	for i, 子 := range 父.SubKeys {
+		if hive比较名称(子.Name, 名称) == 0 {
+			父.SubKeys = append(父.SubKeys[:i:i], 父.SubKeys[i+1:]...)
+			if 父 == n.用户 && hive比较名称(名称, n.当前用户) == 0 {
+				n.当前用户 = ""
+			}
+			return 子, nil
+		}
+	}
+	return nil, ErrOfflineNotExist
+}
+
+// I设置当前用户 把 HKEY_CURRENT_USER 指向 HKEY_USERS\<SID>, 该配置单元必须已经挂载。
+func (n *I离线命名空间) I设置当前用户(SID string) error {
+	if n == nil {
+		return errors.New("注册表类对象为nil")
+	}
+	子 := n.用户.I取子项(SID)
+	if 子 == nil {
+		return ErrOfflineNotExist
+	}
+	n.当前用户 = 子.Name
+	return nil
+}
+
+// I取当前用户 返回 HKEY_CURRENT_USER 对应的SID, 没有设置时返回空字符串。
+func (n *I离线命名空间) I取当前用户() string {
+	if n == nil {
+		return ""
+	}
+	return n.当前用户
+}
+
+// I模拟重启 删除所有已挂载配置单元中的易失表项。
+func (n *I离线命名空间) I模拟重启() {
+	if n == nil {
+		return
+	}
+	n.本机.I模拟重启()
+	n.用户.I模拟重启()
+}
+
+// I类根 返回 HKEY_CLASSES_ROOT 的合并视图。
+func (n *I离线命名空间) I类根() *I合并类根 {
+	if n == nil {
+		return nil
+	}
+	c := &I合并类根{Machine: n.本机.I查找(`SOFTWARE\Classes`)}
+	if n.当前用户 != "" {
+		c.User = n.用户.I取子项(n.当前用户 + "_Classes")
+	}
+	return c
+}
+
+// 解析 返回路径所在的虚拟根项(HKEY_CLASSES_ROOT 时为nil而返回合并视图)和相对路径。
+func (n *I离线命名空间) 解析(路径 string) (*I离线表项, *I合并类根, string, error) {
+	if n == nil {
+		return nil, nil, "", errors.New("注册表类对象为nil")
+	}
+	段 := 拆分路径(路径)
+	if len(段) == 0 {
+		return nil, nil, "", fmt.Errorf("路径 %q 没有根项", 路径)
+	}
+	相对 := strings.Join(段[1:], `\`)
+	switch 根项名称(段[0]) {
+	case "HKEY_LOCAL_MACHINE":
+		return n.本机, nil, n.解析控制集(相对), nil
+	case "HKEY_CURRENT_CONFIG":
+		return n.本机, nil, n.解析控制集(hive连接路径(`SYSTEM\CurrentControlSet\Hardware Profiles\Current`, 相对)), nil
+	case "HKEY_USERS":
+		return n.用户, nil, 相对, nil
+	case "HKEY_CURRENT_USER":
+		if n.当前用户 == "" {
+			return nil, nil, "", ErrOfflineNotExist
+		}
+		return n.用户, nil, hive连接路径(n.当前用户, 相对), nil
+	case "HKEY_CLASSES_ROOT":
+		return nil, n.I类根(), 相对, nil
+	}
+	return nil, nil, "", fmt.Errorf("不支持的根项 %q", 段[0])
+}
+
+// 解析控制集 把相对于 HKEY_LOCAL_MACHINE 的路径中的 SYSTEM\CurrentControlSet
+// 替换为挂载的SYSTEM配置单元中的当前控制集, 无法解析时原样返回。
+func (n *I离线命名空间) 解析控制集(相对 string) string {
+	段 := 拆分路径(相对)
+	if len(段) < 2 || hive比较名称(段[0], "SYSTEM") != 0 {
+		return 相对
+	}
+	实际, err := I解析控制集路径(n.本机.I取子项(段[0]), strings.Join(段[1:], `\`))
+	if err != nil {
+		return 相对
+	}
+	return hive连接路径(段[0], 实际)
+}
+
+// 遍历 从虚拟根项逐段查找相对路径, 遇到符号链接时转到链接目标继续查找。
+// 打开链接为true时, 路径最后一段的链接表项本身被返回而不跟随。
+func (n *I离线命名空间) 遍历(根 *I离线表项, 相对 string, 打开链接 bool) (*I离线表项, error) {
+	段 := 拆分路径(相对)
+	当前, 跳数 := 根, 0
+	for i := 0; i < len(段); i++ {
+		子 := 当前.I取子项(段[i])
+		if 子 == nil {
+			return nil, ErrOfflineNotExist
+		}
+		if !子.I是链接() || 打开链接 && i == len(段)-1 {
+			当前 = 子
+			continue
+		}
+		if 跳数++; 跳数 > I链接最大跳数 {
+			return nil, ErrLinkLoop
+		}
+		目标, err := 子.I取链接目标()
+		if err != nil {
+			return nil, err
+		}
+		目标, err = I转注册表路径(目标)
+		if err != nil {
+			return nil, err
+		}
+		新根, _, 新相对, err := n.解析(目标)
+		if err != nil {
+			return nil, err
+		}
+		段 = append(拆分路径(新相对), 段[i+1:]...)
+		当前, i = 新根, -1
+	}
+	return 当前, nil
+}
+
+// I查找Ex 返回路径对应的表项, 路径中的符号链接都会被跟随; 打开链接为true时,
+// 与REG_OPTION_OPEN_LINK一样返回路径最后一段的链接表项本身。
+// 表项不存在时返回 ErrOfflineNotExist, 链接形成循环时返回 ErrLinkLoop。
+// HKEY_CLASSES_ROOT 下的路径不跟随链接。
+func (n *I离线命名空间) I查找Ex(路径 string, 打开链接 bool) (*I离线表项, error) {
+	根, 类根, 相对, err := n.解析(路径)
+	if err != nil {
+		return nil, err
+	}
+	if 类根 != nil {
+		if k := 类根.I查找(相对); k != nil {
+			return k, nil
+		}
+		return nil, ErrOfflineNotExist
+	}
+	return n.遍历(根, 相对, 打开链接)
+}
+
+// I查找 返回路径对应的表项, 跟随路径中的符号链接。
+// HKEY_CLASSES_ROOT 下的路径返回合并视图中读取所用的表项。不存在时返回nil。
+func (n *I离线命名空间) I查找(路径 string) *I离线表项 {
+	k, _ := n.I查找Ex(路径, false)
+	return k
+}
+
+// I取子项名称 返回路径下所有子项的名称。HKEY_CLASSES_ROOT 下返回合并后的子项。
+// 路径不存在时返回 ErrOfflineNotExist。
+func (n *I离线命名空间) I取子项名称(路径 string) ([]string, error) {
+	根, 类根, 相对, err := n.解析(路径)
+	if err != nil {
+		return nil, err
+	}
+	if 类根 != nil {
+		return 类根.I取子项名称(相对)
+	}
+	k, err := n.遍历(根, 相对, false)
+	if err != nil {
+		return nil, err
+	}
+	名称 := make([]string, len(k.SubKeys))
+	for i, 子 := range k.SubKeys {
+		名称[i] = 子.Name
+	}
+	// 与在线注册表一样, SYSTEM下列出 CurrentControlSet 链接。
+	if 根 == n.本机 && k.I取子项(I当前控制集) == nil && k == n.本机.I取子项("SYSTEM") {
+		if _, err := I取当前控制集(k); err == nil {
+			名称 = append(名称, I当前控制集)
+		}
+	}
+	return 名称, nil
+}
+
+// I取值 返回路径对应表项中名称为'名称'的值。表项或值不存在时返回 ErrOfflineNotExist。
+func (n *I离线命名空间) I取值(路径, 名称 string) (*I离线值, error) {
+	k := n.I查找(路径)
+	if k == nil {
+		return nil, ErrOfflineNotExist
+	}
+	if v := k.I取值(名称); v != nil {
+		return v, nil
+	}
+	return nil, ErrOfflineNotExist
+}
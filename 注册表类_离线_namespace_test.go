@@ -0,0 +1,127 @@
+package 注册表类_test
+
+import (
+	"reflect"
+	"testing"
+
+	注册表类 "e.coding.net/gogit/go/gosdk/core/win_registry_cn"
+)
+
+func TestOfflineNamespace(t *testing.T) {
+	const sid = "S-1-5-21-1-2-3-1001"
+	software := &注册表类.I离线表项{Name: "ROOT", SubKeys: []*注册表类.I离线表项{
+		{Name: "Classes", SubKeys: []*注册表类.I离线表项{{Name: ".txt", Values: []*注册表类.I离线值{sz("", "txtfile")}}}},
+		{Name: "Vendor"},
+	}}
+	data, err := 注册表类.I生成配置单元(software)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ntuser := &注册表类.I离线表项{Name: "ROOT", SubKeys: []*注册表类.I离线表项{
+		{Name: "Environment", Values: []*注册表类.I离线值{sz("TEMP", `C:\Temp`)}},
+	}}
+	usrclass := &注册表类.I离线表项{Name: "ROOT", SubKeys: []*注册表类.I离线表项{
+		{Name: ".txt", Values: []*注册表类.I离线值{sz("", "Editor.txt")}},
+	}}
+
+	ns := 注册表类.I新建离线命名空间()
+	if err := ns.I加载文件(`HKLM\SOFTWARE`, data); err != nil {
+		t.Fatal(err)
+	}
+	if err := ns.I加载(`HKEY_USERS\`+sid, ntuser); err != nil {
+		t.Fatal(err)
+	}
+	if err := ns.I加载(`USERS\`+sid+"_Classes", usrclass); err != nil {
+		t.Fatal(err)
+	}
+	if err := ns.I加载(`HKLM\software`, ntuser); err == nil {
+		t.Error("mounting over an existing hive should fail")
+	}
+	if err := ns.I加载(`HKLM\A\B`, ntuser); err == nil {
+		t.Error("mounting below the first level should fail")
+	}
+	if err := ns.I加载(`HKCU\X`, ntuser); err == nil {
+		t.Error("mounting under HKCU should fail")
+	}
+
+	names, err := ns.I取子项名称("HKLM")
+	if err != nil || !reflect.DeepEqual(names, []string{"SOFTWARE"}) {
+		t.Errorf("HKLM subkeys: got %q, %v", names, err)
+	}
+	if ns.I查找(`HKEY_LOCAL_MACHINE\Software\Vendor`) == nil {
+		t.Error("mounted SOFTWARE hive is not visible")
+	}
+	if ns.I查找(`HKCU\Environment`) != nil {
+		t.Error("HKCU should not resolve before a current user is set")
+	}
+	if v, _ := ns.I取值(`HKCR\.txt`, ""); text(v) != "txtfile" {
+		t.Errorf("HKCR without a current user: got %q", text(v))
+	}
+
+	if err := ns.I设置当前用户(sid); err != nil {
+		t.Fatal(err)
+	}
+	if ns.I查找(`HKCU\Environment`) != ns.I查找(`HKU\`+sid+`\Environment`) {
+		t.Error("HKCU is not an alias of HKU\\<SID>")
+	}
+	ns.I查找(`HKCU\Environment`).I设置值(sz("TMP", `D:\Tmp`))
+	if v, _ := ns.I取值(`HKU\`+sid+`\Environment`, "TMP"); text(v) != `D:\Tmp` {
+		t.Errorf("write through HKCU is not visible under HKU: got %q", text(v))
+	}
+	if v, _ := ns.I取值(`HKCR\.txt`, ""); text(v) != "Editor.txt" {
+		t.Errorf("HKCR should prefer the user's classes: got %q", text(v))
+	}
+
+	root, err := ns.I卸载(`HKU\` + sid)
+	if err != nil || root != ntuser {
+		t.Fatalf("unload: %v", err)
+	}
+	if ns.I取当前用户() != "" || ns.I查找(`HKCU`) != nil {
+		t.Error("unloading the current user's hive should clear HKCU")
+	}
+	if _, err := ns.I卸载(`HKU\` + sid); err != 注册表类.ErrOfflineNotExist {
+		t.Errorf("second unload: got %v, want ErrOfflineNotExist", err)
+	}
+}
+
+func TestVolatileKeys(t *testing.T) {
+	root := &注册表类.I离线表项{Name: "ROOT"}
+	stable, _, _ := root.I创建子项Ex("Stable", 注册表类.OPTION_NON_VOLATILE, "StableClass")
+	volatile, _, err := root.I创建子项Ex("Volatile", 注册表类.OPTION_VOLATILE, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !volatile.I是易失() || stable.I是易失() || stable.Class != "StableClass" {
+		t.Fatalf("unexpected keys %+v %+v", stable, volatile)
+	}
+	if _, _, err := volatile.I创建子项Ex("Child", 注册表类.OPTION_NON_VOLATILE, ""); err == nil {
+		t.Error("creating a non-volatile key under a volatile key should fail")
+	}
+	child, _, err := volatile.I创建子项("Child")
+	if err != nil || !child.I是易失() {
+		t.Errorf("children of volatile keys should inherit volatility: %v", err)
+	}
+	stable.I创建子项Ex("Session", 注册表类.OPTION_VOLATILE, "")
+
+	data, err := 注册表类.I生成配置单元(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved, err := 注册表类.I解析配置单元(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.I查找("Volatile") != nil || saved.I查找(`Stable\Session`) != nil || saved.I查找("Stable").Class != "StableClass" {
+		t.Error("volatile keys should not be written to the hive file")
+	}
+
+	ns := 注册表类.I新建离线命名空间()
+	ns.I加载(`HKLM\TEST`, root)
+	ns.I模拟重启()
+	if ns.I查找(`HKLM\TEST\Volatile`) != nil || ns.I查找(`HKLM\TEST\Stable\Session`) != nil {
+		t.Error("volatile keys should not survive a reboot")
+	}
+	if ns.I查找(`HKLM\TEST\Stable`) == nil {
+		t.Error("stable keys should survive a reboot")
+	}
+}
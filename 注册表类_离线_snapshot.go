@@ -0,0 +1,120 @@
+//go:build windows
+// +build windows
+
+package 注册表类
+
+import (
+	"errors"
+	"fmt"
+)
+
+// I读取离线表项 把注册表对象k下的子项路径及其所有子项读取为离线表项树,
+// 包括类名和上次写入时间, 不包括安全描述符。树根的名称是路径的最后一段。
+// 符号链接表项不被跟随: 读取为带有链接标志和 SymbolicLinkValue 的离线表项, 不包括目标的内容。
+func I读取离线表项(k *Key结构, 路径 string) (*I离线表项, error) {
+	if k == nil {
+		return nil, errors.New("注册表类对象为nil")
+	}
+	子k, err := I打开链接表项(k, 路径, READ)
+	if err != nil {
+		return nil, err
+	}
+	defer 子k.I关闭()
+	项 := &I离线表项{}
+	if 段 := 拆分路径(路径); len(段) > 0 {
+		项.Name = 段[len(段)-1]
+	}
+	信息, err := 子k.I取对象信息()
+	if err != nil {
+		return nil, err
+	}
+	项.Class, 项.ModTime = 信息.Class, 信息.I取写入时间().UTC()
+	if _, err := 子k.I取链接目标(); err == nil {
+		项.Flags |= hive标志_符号链接
+	}
+	子项, 值, err := 子k.读取内容()
+	if err != nil {
+		return nil, err
+	}
+	项.Values = 值
+	for _, 名称 := range 子项 {
+		子, err := I读取离线表项(子k, 名称)
+		if errors.Is(err, ErrNotExist) {
+			continue // 读取期间被删除
+		}
+		if err != nil {
+			return nil, err
+		}
+		项.SubKeys = append(项.SubKeys, 子)
+	}
+	return 项, nil
+}
+
+// I写入离线表项 把离线表项树写入注册表对象k下的子项路径, 缺少的表项用树中的类名创建,
+// 易失的离线表项创建为易失表项。已有的值被覆盖, 树中没有的值和子项保持不变。
+// 符号链接离线表项用 I创建链接表项 重建, 已有的链接改为指向树中的目标。
+// 所有内容写完后再按树中的时间设置各表项的上次写入时间, 时间为零值的表项保持不变。
+func I写入离线表项(k *Key结构, 路径 string, 树 *I离线表项) error {
+	if k == nil || 树 == nil {
+		return errors.New("注册表类对象为nil")
+	}
+	if 树.I是链接() {
+		return 写入链接表项(k, 路径, 树)
+	}
+	选项 := I打开选项{Access: ALL_ACCESS, View: I视图_本机, Class: 树.Class}
+	if 树.I是易失() {
+		选项.Options = OPTION_VOLATILE
+	}
+	子k, _, _, err := I创建表项Ex(k, 路径, 选项)
+	if err != nil {
+		return err
+	}
+	defer 子k.I关闭()
+	for _, v := range 树.Values {
+		if err := 子k.setValue(v.Name, v.Type, v.Data); err != nil {
+			return err
+		}
+	}
+	for _, 子 := range 树.SubKeys {
+		if err := I写入离线表项(子k, 子.Name, 子); err != nil {
+			return err
+		}
+	}
+	if 树.ModTime.IsZero() {
+		return nil
+	}
+	return 子k.I设置写入时间(树.ModTime)
+}
+
+// 写入链接表项 在注册表对象k下的子项路径重建符号链接离线表项。
+// 路径已经存在并且不是符号链接时返回错误。
+func 写入链接表项(k *Key结构, 路径 string, 树 *I离线表项) error {
+	目标, err := 树.I取链接目标()
+	if err != nil {
+		return err
+	}
+	链接, err := I打开链接表项(k, 路径, ALL_ACCESS)
+	switch {
+	case err == nil:
+		if _, err = 链接.I取链接目标(); err != nil {
+			链接.I关闭()
+			return fmt.Errorf("表项 %s 已经存在并且不是符号链接", 路径)
+		}
+		var 内核路径 string
+		if 内核路径, err = I转内核路径(目标); err == nil {
+			err = 链接.setValue(I链接值名称, LINK, hive编码UTF16(内核路径))
+		}
+	case errors.Is(err, ErrNotExist):
+		链接, err = I创建链接表项(k, 路径, 目标)
+		if err != nil {
+			return err
+		}
+	default:
+		return err
+	}
+	defer 链接.I关闭()
+	if err != nil || 树.ModTime.IsZero() {
+		return err
+	}
+	return 链接.I设置写入时间(树.ModTime)
+}
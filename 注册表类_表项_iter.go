@@ -0,0 +1,111 @@
+//go:build windows
+// +build windows
+
+package 注册表类
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"syscall"
+
+	"golang.org/x/sys/windows"
+)
+
+// I子项条目 是 I遍历子项 返回的子项名称和统计信息。
+type I子项条目 struct {
+	Name string
+	Info *I对象信息
+}
+
+// I遍历子项 返回逐个枚举注册表对象k的子项的迭代器, 每个子项都带有它的 I对象信息。
+// 子项以QUERY_VALUE权限打开以读取统计信息, 打开失败时该子项的Info为nil并同时返回错误,
+// 调用者可以选择继续或中断。ctx取消后返回ctx.Err()并结束。
+// 枚举按索引进行, 迭代期间增删子项可能导致遗漏或重复。
+func (k *Key结构) I遍历子项(ctx context.Context) iter.Seq2[I子项条目, error] {
+	return func(yield func(I子项条目, error) bool) {
+		if k == nil {
+			yield(I子项条目{}, errors.New("注册表类对象为nil"))
+			return
+		}
+		if k.拦截 != nil {
+			k.拦截遍历子项(ctx, yield)
+			return
+		}
+		名称 := make([]uint16, 256) // 表项名称最长255个字符
+		for i := uint32(0); ; i++ {
+			if err := ctx.Err(); err != nil {
+				yield(I子项条目{}, err)
+				return
+			}
+			n := uint32(len(名称))
+			err := windows.RegEnumKeyEx(windows.Handle(k.Key父类), i, &名称[0], &n, nil, nil, nil, nil)
+			if err == _ERROR_NO_MORE_ITEMS {
+				return
+			}
+			if err != nil {
+				yield(I子项条目{}, err)
+				return
+			}
+			条目 := I子项条目{Name: syscall.UTF16ToString(名称[:n])}
+			子k, err := I打开表项(k, 条目.Name, QUERY_VALUE)
+			if err == nil {
+				条目.Info, err = 子k.I取对象信息()
+				子k.I关闭()
+			}
+			if !yield(条目, err) {
+				return
+			}
+		}
+	}
+}
+
+// I遍历值 返回逐个枚举注册表对象k的值的迭代器, 一次调用同时返回名称、类型和数据。
+// 注册表对象需要以QUERY_VALUE权限打开。ctx取消后返回ctx.Err()并结束。
+// 枚举按索引进行, 迭代期间增删值可能导致遗漏或重复。
+func (k *Key结构) I遍历值(ctx context.Context) iter.Seq2[*I离线值, error] {
+	return func(yield func(*I离线值, error) bool) {
+		if k == nil {
+			yield(nil, errors.New("注册表类对象为nil"))
+			return
+		}
+		if k.拦截 != nil {
+			k.拦截遍历值(ctx, yield)
+			return
+		}
+		信息, err := k.I取对象信息()
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		名称 := make([]uint16, 信息.MaxValueNameLen+1)
+		数据 := make([]byte, max(信息.MaxValueLen, 1))
+		for i := uint32(0); ; {
+			if err := ctx.Err(); err != nil {
+				yield(nil, err)
+				return
+			}
+			n, 长度 := uint32(len(名称)), uint32(len(数据))
+			var 值类型 uint32
+			err := regEnumValue(syscall.Handle(k.Key父类), i, &名称[0], &n, nil, &值类型, &数据[0], &长度)
+			if err == _ERROR_NO_MORE_ITEMS {
+				return
+			}
+			if err == syscall.ERROR_MORE_DATA {
+				// 迭代期间值变长了, 扩大缓冲区后重试同一个索引。
+				名称 = make([]uint16, 2*len(名称))
+				数据 = make([]byte, max(长度, uint32(2*len(数据))))
+				continue
+			}
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			v := &I离线值{Name: syscall.UTF16ToString(名称[:n]), Type: 值类型, Data: append([]byte(nil), 数据[:长度]...)}
+			if !yield(v, nil) {
+				return
+			}
+			i++
+		}
+	}
+}
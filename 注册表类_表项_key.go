@@ -24,8 +24,8 @@ package 注册表类
 
 import (
 	"errors"
+	"golang.org/x/sys/windows"
 	"golang.org/x/sys/windows/registry"
-	"runtime"
 	"syscall"
 	"time"
 )
@@ -68,6 +68,10 @@ const (
 // type Key结构 syscall.Handle
 type Key结构 struct {
 	Key父类 registry.Key
+
+	拦截 拦截链    // 见 I启用拦截
+	路径 string // 启用拦截时相对于启用拦截的注册表对象的路径
+	借用 bool   // I启用拦截 返回的注册表对象, 句柄属于调用者
 }
 
 var (
@@ -75,12 +79,12 @@ var (
 	// 应用程序可以使用这些键作为注册表的入口点。
 	// 通常在OpenKey中使用这些键来打开新的键，
 	//但它们也可以在需要注册表对象的任何地方使用。
-	CLASSES_ROOT     = &Key结构{registry.Key(syscall.HKEY_CLASSES_ROOT)}
-	CURRENT_USER     = &Key结构{registry.Key(syscall.HKEY_CURRENT_USER)}
-	LOCAL_MACHINE    = &Key结构{registry.Key(syscall.HKEY_LOCAL_MACHINE)}
-	USERS            = &Key结构{registry.Key(syscall.HKEY_USERS)}
-	CURRENT_CONFIG   = &Key结构{registry.Key(syscall.HKEY_CURRENT_CONFIG)}
-	PERFORMANCE_DATA = &Key结构{registry.Key(syscall.HKEY_PERFORMANCE_DATA)}
+	CLASSES_ROOT     = &Key结构{Key父类: registry.Key(syscall.HKEY_CLASSES_ROOT)}
+	CURRENT_USER     = &Key结构{Key父类: registry.Key(syscall.HKEY_CURRENT_USER)}
+	LOCAL_MACHINE    = &Key结构{Key父类: registry.Key(syscall.HKEY_LOCAL_MACHINE)}
+	USERS            = &Key结构{Key父类: registry.Key(syscall.HKEY_USERS)}
+	CURRENT_CONFIG   = &Key结构{Key父类: registry.Key(syscall.HKEY_CURRENT_CONFIG)}
+	PERFORMANCE_DATA = &Key结构{Key父类: registry.Key(syscall.HKEY_PERFORMANCE_DATA)}
 )
 
 // I关闭 关闭打开键k。
@@ -88,6 +92,9 @@ func (k *Key结构) I关闭() error {
 	if k == nil {
 		return errors.New("注册表类对象为nil")
 	}
+	if k.借用 {
+		return nil
+	}
 	return k.Key父类.Close()
 }
 
@@ -95,6 +102,7 @@ func (k *Key结构) I关闭() error {
 // 它接受任何打开的注册表对象，
 // 并返回新注册表对象和错误。
 // '访问权限'参数指定要打开的注册表对象的所需'访问权限'。
+// 没有指定'访问权限'时, 因拒绝访问失败会依次降级为WRITE和READ。
 func I打开表项(k *Key结构, 路径 string, 访问权限 ...uint32) (*Key结构, error) {
 	var 权限参数 uint32
 	if len(访问权限) > 0 {
@@ -102,19 +110,13 @@ func I打开表项(k *Key结构, 路径 string, 访问权限 ...uint32) (*Key结
 	}
 
 	//这里是单独增加的, 防止win64系统运行32位软件, 访问注册表被重定向到32位注册表, 具体参考精易"注册表操作Ex"类
+	//没有指定权限时依次尝试 ALL_ACCESS、WRITE、READ, 见 I打开表项Ex
 	if 权限参数 == 0 {
-		if runtime.GOARCH == "amd64" {
-			权限参数 = WOW64_64KEY | ALL_ACCESS //64位注册表 注意:这里的权限 采用的是  #ALL_ACCESS  全部权限
-		} else {
-			权限参数 = WOW64_32KEY | ALL_ACCESS //32位注册表 注意:这里的权限 采用的是  #ALL_ACCESS  全部权限
-		}
+		new, _, err := I打开表项Ex(k, 路径)
+		return new, err
 	}
 
-	new, err := registry.OpenKey(k.Key父类, 路径, 权限参数)
-	if err != nil {
-		return nil, err
-	}
-	return &Key结构{new}, err
+	return k.打开子项(路径, 0, 权限参数)
 }
 
 // I打开远程表项 在另一台计算机pcname上打开预定义的注册表项.要打开的注册表对象由k指定,
@@ -125,7 +127,7 @@ func I打开远程表项(计算机名 string, k Key结构) (*Key结构, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &Key结构{new}, err
+	return &Key结构{Key父类: new}, err
 }
 
 // I取所有子项名称 返回注册表对象k的子注册表对象的名称。
@@ -135,6 +137,9 @@ func (k *Key结构) I取所有子项名称(n int) ([]string, error) {
 	if k == nil {
 		return nil, errors.New("注册表类对象为nil")
 	}
+	if k.拦截 != nil {
+		return k.拦截取子项名称(n)
+	}
 	return k.Key父类.ReadSubKeyNames(n)
 }
 
@@ -149,24 +154,53 @@ func I创建表项(k *Key结构, 路径 string, 访问权限 ...uint32) (newk *K
 	}
 
 	//这里是单独增加的, 防止win64系统运行32位软件, 访问注册表被重定向到32位注册表, 具体参考精易"注册表操作Ex"类
+	//没有指定权限时依次尝试 ALL_ACCESS、WRITE、READ, 见 I创建表项Ex
 	if 权限参数 == 0 {
-		if runtime.GOARCH == "amd64" {
-			权限参数 = WOW64_64KEY | ALL_ACCESS //64位注册表 注意:这里的权限 采用的是  #ALL_ACCESS  全部权限
-		} else {
-			权限参数 = WOW64_32KEY | ALL_ACCESS //32位注册表 注意:这里的权限 采用的是  #ALL_ACCESS  全部权限
-		}
+		newk, 是否已存在, _, err = I创建表项Ex(k, 路径)
+		return newk, 是否已存在, err
 	}
 
-	new, 是否已存在, err := registry.CreateKey(k.Key父类, 路径, 权限参数)
-	if err != nil {
-		return nil, 是否已存在, err
-	}
-	return &Key结构{new}, 是否已存在, err
+	return k.创建子项(路径, _REG_OPTION_NON_VOLATILE, nil, 权限参数)
 }
 
 // I删除表项 删除注册表对象k的子注册表对象路径及其值。
 func I删除表项(k *Key结构, 路径 string) error {
-	return registry.DeleteKey(k.Key父类, 路径)
+	if k == nil {
+		return errors.New("注册表类对象为nil")
+	}
+	return k.拦截调用(&I调用记录{Op: I操作_删除表项, Path: k.子路径(路径)}, func() error {
+		return registry.DeleteKey(k.Key父类, 路径)
+	})
+}
+
+// I删除表项树 删除注册表对象k的子注册表对象路径及其所有子项和值。
+// 符号链接表项(包括路径本身)只删除链接, 不进入链接目标, 所以不会删除子树以外的数据。
+func I删除表项树(k *Key结构, 路径 string) error {
+	if k == nil {
+		return errors.New("注册表类对象为nil")
+	}
+	子k, err := I打开链接表项(k, 路径, ENUMERATE_SUB_KEYS|QUERY_VALUE)
+	if err != nil {
+		return err
+	}
+	if _, err := 子k.I取链接目标(); err == nil {
+		子k.I关闭()
+		return I删除链接表项(k, 路径)
+	}
+	名称, err := 子k.I取所有子项名称(-1)
+	for _, n := range 名称 {
+		if err = I删除表项树(子k, n); errors.Is(err, ErrNotExist) {
+			err = nil // 已被同时删除
+		}
+		if err != nil {
+			break
+		}
+	}
+	子k.I关闭()
+	if err != nil {
+		return err
+	}
+	return I删除表项(k, 路径)
 }
 
 // A I对象信息 描述注册表对象的统计信息。由Stat.返回。
@@ -176,7 +210,10 @@ type I对象信息 struct {
 	ValueCount      uint32
 	MaxValueNameLen uint32 // 键的最长值名称的大小，以Unicode字符表示，不包括终止的零字节
 	MaxValueLen     uint32 //键值中最长的数据组件，以字节为单位
+	Class           string // 创建注册表对象时指定的类名, 没有时为空
 	KeyInfo父类       registry.KeyInfo
+
+	修改时间 time.Time // 拦截器提供的写入时间, 见 I启用拦截
 }
 
 // I取写入时间 返回键的上次写入时间。
@@ -184,6 +221,9 @@ func (ki *I对象信息) I取写入时间() time.Time {
 	if ki == nil {
 		return time.Time{}
 	}
+	if !ki.修改时间.IsZero() {
+		return ki.修改时间
+	}
 	return ki.KeyInfo父类.ModTime()
 }
 
@@ -192,6 +232,9 @@ func (k *Key结构) I取对象信息() (*I对象信息, error) {
 	if k == nil {
 		return nil, errors.New("注册表类对象为nil")
 	}
+	if k.拦截 != nil {
+		return k.拦截取对象信息()
+	}
 	返回, err := k.Key父类.Stat()
 	if err != nil {
 		return nil, err
@@ -204,5 +247,42 @@ func (k *Key结构) I取对象信息() (*I对象信息, error) {
 		MaxValueLen:     返回.MaxValueLen,     //键值中最长的数据组件，以字节为单位
 		KeyInfo父类:       *返回,
 	}
+	对象信息.Class, err = k.取类名()
+	if err != nil {
+		return nil, err
+	}
 	return &对象信息, err
 }
+
+// I设置写入时间 使用NtSetInformationKey把注册表对象k的上次写入时间设置为t。
+// 注册表对象需要以SET_VALUE权限打开。之后对该注册表对象的修改会再次更新写入时间。
+func (k *Key结构) I设置写入时间(t time.Time) error {
+	if k == nil {
+		return errors.New("注册表类对象为nil")
+	}
+	ft := hive取filetime(t)
+	return ntSetInformationKey(syscall.Handle(k.Key父类), _KeyWriteTimeInformation, &ft, 8)
+}
+
+// 取类名 使用RegQueryInfoKey读取注册表对象k的类名。
+func (k *Key结构) 取类名() (string, error) {
+	var 长度 uint32
+	h := windows.Handle(k.Key父类)
+	if err := windows.RegQueryInfoKey(h, nil, &长度, nil, nil, nil, nil, nil, nil, nil, nil, nil); err != nil {
+		return "", err
+	}
+	if 长度 == 0 {
+		return "", nil
+	}
+	for {
+		缓冲区 := make([]uint16, 长度+1)
+		长度 = uint32(len(缓冲区))
+		err := windows.RegQueryInfoKey(h, &缓冲区[0], &长度, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+		if err == nil {
+			return syscall.UTF16ToString(缓冲区[:长度]), nil
+		}
+		if err != syscall.ERROR_MORE_DATA {
+			return "", err
+		}
+	}
+}
@@ -0,0 +1,83 @@
+//go:build windows
+// +build windows
+
+package 注册表类
+
+import (
+	"context"
+	"errors"
+	"runtime"
+
+	"golang.org/x/sys/windows"
+)
+
+// 最大等待对象数 是WaitForMultipleObjects一次能等待的最多对象数量。
+const 最大等待对象数 = 64
+
+// I监视变化 监视注册表对象列表'表项'的变化, 注册表对象需要以NOTIFY权限打开。
+// 开始监视后先调用一次'变化时', 之后每当有表项发生'过滤'指定的变化时再调用一次;
+// 子树为true时同时监视所有子项。一段时间内的多次变化可能只触发一次调用,
+// 所以'变化时'应该重新读取它关心的所有内容。
+// I监视变化 一直阻塞到ctx取消或'变化时'返回错误, 返回该错误或ctx.Err()。
+// 最多同时监视63个表项。
+func I监视变化(ctx context.Context, 表项 []*Key结构, 子树 bool, 过滤 uint32, 变化时 func() error) error {
+	过滤列表 := make([]uint32, len(表项))
+	for i := range 过滤列表 {
+		过滤列表[i] = 过滤
+	}
+	return 监视变化(ctx, 表项, 子树, 过滤列表, func(int) error { return 变化时() })
+}
+
+// 监视变化 与 I监视变化 相同, 但每个表项使用各自的过滤条件,
+// 并把触发调用的表项的序号传给'变化时', 开始监视后的第一次调用传入-1。
+func 监视变化(ctx context.Context, 表项 []*Key结构, 子树 bool, 过滤 []uint32, 变化时 func(int) error) error {
+	if len(表项) > 最大等待对象数-1 {
+		return errors.New("监视的表项过多")
+	}
+	// 通知与注册它的线程绑定, 线程退出时会被取消。
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	事件 := make([]windows.Handle, 0, len(表项)+1)
+	defer func() {
+		for _, h := range 事件 {
+			windows.CloseHandle(h)
+		}
+	}()
+	for range len(表项) + 1 {
+		h, err := windows.CreateEvent(nil, 0, 0, nil)
+		if err != nil {
+			return err
+		}
+		事件 = append(事件, h)
+	}
+	取消 := 事件[len(表项)]
+	停止 := context.AfterFunc(ctx, func() { windows.SetEvent(取消) })
+	defer 停止()
+
+	for i, k := range 表项 {
+		if k == nil {
+			return errors.New("注册表类对象为nil")
+		}
+		if err := windows.RegNotifyChangeKeyValue(windows.Handle(k.Key父类), 子树, 过滤[i], 事件[i], true); err != nil {
+			return err
+		}
+	}
+	for i := -1; ; {
+		if err := 变化时(i); err != nil {
+			return err
+		}
+		r, err := windows.WaitForMultipleObjects(事件, false, windows.INFINITE)
+		if err != nil {
+			return err
+		}
+		i = int(r - windows.WAIT_OBJECT_0)
+		if i == len(表项) {
+			return ctx.Err()
+		}
+		// 通知只触发一次, 在重新读取之前再次注册, 不会遗漏之后的变化。
+		if err := windows.RegNotifyChangeKeyValue(windows.Handle(表项[i].Key父类), 子树, 过滤[i], 事件[i], true); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,109 @@
+//go:build windows
+// +build windows
+
+package 注册表类
+
+import (
+	"errors"
+	"runtime"
+	"syscall"
+)
+
+// I默认降级权限 是没有指定'访问权限'时依次尝试的权限, 前一个因拒绝访问失败时尝试下一个。
+var I默认降级权限 = []uint32{ALL_ACCESS, WRITE, READ}
+
+// I打开选项 控制 I打开表项Ex 和 I创建表项Ex 请求的权限和视图。
+type I打开选项 struct {
+	// Access 是首先请求的'访问权限', 不包含WOW64标志。为0时依次尝试 I默认降级权限。
+	Access uint32
+	// Fallback 是Access因ERROR_ACCESS_DENIED失败后依次尝试的权限。
+	Fallback []uint32
+	// View 选择注册表视图。Access中已包含WOW64标志时忽略。
+	View I视图
+	// Options 是 I创建表项Ex 使用的 OPTION_* 创建选项, 例如 OPTION_VOLATILE。
+	Options uint32
+	// Class 是 I创建表项Ex 新建表项时设置的类名, 表项已存在时忽略。
+	Class string
+}
+
+// 权限链 返回按顺序尝试的权限, 已加上视图标志。
+func (o *I打开选项) 权限链() []uint32 {
+	链 := append([]uint32{o.Access}, o.Fallback...)
+	if o.Access == 0 {
+		链 = append(append([]uint32(nil), I默认降级权限...), o.Fallback...)
+	}
+	var 视图标志 uint32
+	switch o.View {
+	case I视图_默认:
+		if runtime.GOARCH == "amd64" {
+			视图标志 = WOW64_64KEY
+		} else {
+			视图标志 = WOW64_32KEY
+		}
+	case I视图_32位:
+		视图标志 = WOW64_32KEY
+	case I视图_64位:
+		视图标志 = WOW64_64KEY
+	}
+	for i, 权限 := range 链 {
+		if 权限&(WOW64_32KEY|WOW64_64KEY) == 0 {
+			链[i] = 权限 | 视图标志
+		}
+	}
+	return 链
+}
+
+// I打开表项Ex 按'选项'打开注册表对象k下的子项路径,
+// 返回新注册表对象和实际获得的'访问权限'(不包含WOW64标志)。
+// 只有ERROR_ACCESS_DENIED会触发降级, 其他错误直接返回。
+func I打开表项Ex(k *Key结构, 路径 string, 选项 ...I打开选项) (*Key结构, uint32, error) {
+	if k == nil {
+		return nil, 0, errors.New("注册表类对象为nil")
+	}
+	var 参数 I打开选项
+	if len(选项) > 0 {
+		参数 = 选项[0]
+	}
+	var err error
+	for _, 权限 := range 参数.权限链() {
+		var new *Key结构
+		new, err = k.打开子项(路径, 0, 权限)
+		if err == nil {
+			return new, 权限 &^ (WOW64_32KEY | WOW64_64KEY), nil
+		}
+		if !errors.Is(err, syscall.ERROR_ACCESS_DENIED) {
+			break
+		}
+	}
+	return nil, 0, err
+}
+
+// I创建表项Ex 按'选项'在注册表对象k下创建或打开子项路径,
+// 返回新注册表对象、该注册表对象是否已存在和实际获得的'访问权限'(不包含WOW64标志)。
+// 选项中的 Options 和 Class 只对新建的表项有效; 在易失表项下创建非易失子项会失败。
+// 只有ERROR_ACCESS_DENIED会触发降级, 其他错误直接返回。
+func I创建表项Ex(k *Key结构, 路径 string, 选项 ...I打开选项) (newk *Key结构, 是否已存在 bool, 已获得权限 uint32, err error) {
+	if k == nil {
+		return nil, false, 0, errors.New("注册表类对象为nil")
+	}
+	var 参数 I打开选项
+	if len(选项) > 0 {
+		参数 = 选项[0]
+	}
+	var p类名 *uint16
+	if 参数.Class != "" {
+		if p类名, err = syscall.UTF16PtrFromString(参数.Class); err != nil {
+			return nil, false, 0, err
+		}
+	}
+	for _, 权限 := range 参数.权限链() {
+		newk, 是否已存在, err = k.创建子项(路径, 参数.Options, p类名, 权限)
+		if err == nil {
+			return newk, 是否已存在, 权限 &^ (WOW64_32KEY | WOW64_64KEY), nil
+		}
+		if !errors.Is(err, syscall.ERROR_ACCESS_DENIED) {
+			break
+		}
+	}
+	return nil, false, 0, err
+}
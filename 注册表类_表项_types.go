@@ -0,0 +1,21 @@
+package 注册表类
+
+const (
+	// OPTION_NON_VOLATILE 等是创建注册表项时的选项, 对应RegCreateKeyEx的dwOptions参数。
+	// 见 https://learn.microsoft.com/en-us/windows/win32/api/winreg/nf-winreg-regcreatekeyexw
+
+	OPTION_NON_VOLATILE   = 0x0000 //表项保存在配置单元文件中, 默认值。
+	OPTION_VOLATILE       = 0x0001 //表项只保存在内存中, 卸载配置单元或重启后消失。易失表项下只能创建易失子项。
+	OPTION_CREATE_LINK    = 0x0002 //创建符号链接表项。
+	OPTION_BACKUP_RESTORE = 0x0004 //忽略'访问权限', 以备份或还原语义打开, 需要SeBackupPrivilege或SeRestorePrivilege特权。
+	OPTION_OPEN_LINK      = 0x0008 //打开符号链接本身而不是目标。
+)
+
+const (
+	// NOTIFY_CHANGE_NAME 等是 I监视变化 的过滤条件, 对应RegNotifyChangeKeyValue的dwNotifyFilter参数。
+
+	NOTIFY_CHANGE_NAME       = 0x00000001 //添加或删除子项。
+	NOTIFY_CHANGE_ATTRIBUTES = 0x00000002 //表项的属性改变, 例如安全描述符。
+	NOTIFY_CHANGE_LAST_SET   = 0x00000004 //添加、删除或修改值。
+	NOTIFY_CHANGE_SECURITY   = 0x00000008 //表项的安全描述符改变。
+)
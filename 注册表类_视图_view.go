@@ -0,0 +1,74 @@
+//go:build windows
+// +build windows
+
+package 注册表类
+
+import (
+	"context"
+	"errors"
+)
+
+// I读取双视图 分别以32位和64位视图打开注册表对象k下的子项路径, 读取子项名称和值并合并。
+// 结果中的每一项都标记了它来自的视图; 共享表项的内容在两个视图中相同, 会合并为标记两个视图的项。
+// 只有一个视图中存在该路径时, 只返回该视图的内容; 两个视图中都不存在时返回ErrNotExist。
+func I读取双视图(k *Key结构, 路径 string) (*I双视图内容, error) {
+	if k == nil {
+		return nil, errors.New("注册表类对象为nil")
+	}
+	var 子项 [2][]string
+	var 值 [2][]*I离线值
+	存在 := false
+	for i, 视图 := range []I视图{I视图_32位, I视图_64位} {
+		子k, _, err := I打开表项Ex(k, 路径, I打开选项{Access: READ, View: 视图})
+		if errors.Is(err, ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		存在 = true
+		子项[i], 值[i], err = 子k.读取内容()
+		子k.I关闭()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if !存在 {
+		return nil, ErrNotExist
+	}
+	return 合并双视图(子项[0], 子项[1], 值[0], 值[1]), nil
+}
+
+// 读取内容 返回注册表对象k的所有子项名称和值。
+func (k *Key结构) 读取内容() ([]string, []*I离线值, error) {
+	子项, err := k.I取所有子项名称(-1)
+	if err != nil {
+		return nil, nil, err
+	}
+	var 值 []*I离线值
+	for v, err := range k.I遍历值(context.Background()) {
+		if err != nil {
+			return nil, nil, err
+		}
+		值 = append(值, v)
+	}
+	return 子项, 值, nil
+}
+
+// 取原始值 返回值的类型和未经转换的数据。
+func (k *Key结构) 取原始值(名称 string) (*I离线值, error) {
+	if k != nil && k.拦截 != nil {
+		return k.拦截取原始值(名称)
+	}
+	缓冲区 := make([]byte, 64)
+	for {
+		n, 值类型, err := k.I取值(名称, 缓冲区)
+		if err == nil {
+			return &I离线值{Name: 名称, Type: 值类型, Data: 缓冲区[:n]}, nil
+		}
+		if err != ErrShortBuffer || n <= len(缓冲区) {
+			return nil, err
+		}
+		缓冲区 = make([]byte, n)
+	}
+}
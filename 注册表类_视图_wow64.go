@@ -0,0 +1,315 @@
+package 注册表类
+
+import (
+	"sort"
+	"strings"
+)
+
+// I视图 选择64位Windows上访问的注册表视图。
+type I视图 uint32
+
+const (
+	// I视图_默认 与 I打开表项 的默认行为一致: amd64程序访问64位视图, 其他程序访问32位视图。
+	I视图_默认 I视图 = iota
+	// I视图_本机 不指定WOW64标志, 访问当前进程所属的视图(可能被重定向)。
+	I视图_本机
+	I视图_32位
+	I视图_64位
+)
+
+func (v I视图) String() string {
+	switch v {
+	case I视图_默认:
+		return "default"
+	case I视图_本机:
+		return "native"
+	case I视图_32位:
+		return "32-bit"
+	case I视图_64位:
+		return "64-bit"
+	}
+	return "unknown"
+}
+
+// I键类别 描述注册表项在WOW64下的处理方式, 见
+// https://learn.microsoft.com/en-us/windows/win32/winprog64/shared-registry-keys
+type I键类别 int
+
+const (
+	// I键类别_不受影响 表示路径不在WOW64处理的范围内, 两个视图看到同一个表项。
+	I键类别_不受影响 I键类别 = iota
+	// I键类别_重定向 表示32位视图被重定向到WOW6432Node下的独立表项。
+	I键类别_重定向
+	// I键类别_共享 表示两个视图共享同一个表项。
+	I键类别_共享
+	// I键类别_反射 表示Windows Vista在两个视图之间反射的表项。
+	// Windows 7起不再反射, 这些表项改为共享, 路径解析与 I键类别_共享 相同。
+	I键类别_反射
+)
+
+func (c I键类别) String() string {
+	switch c {
+	case I键类别_不受影响:
+		return "unaffected"
+	case I键类别_重定向:
+		return "redirected"
+	case I键类别_共享:
+		return "shared"
+	case I键类别_反射:
+		return "reflected"
+	}
+	return "unknown"
+}
+
+const wow64节点 = "WOW6432Node"
+
+// wow64重定向类 是Classes下被重定向的子项, 其余子项共享。
+var wow64重定向类 = []string{"CLSID", "DirectShow", "Interface", "Media Type", "MediaFoundation"}
+
+// wow64反射项 是HKLM\SOFTWARE下Windows Vista反射的表项(不含Classes)。
+var wow64反射项 = []string{
+	`Microsoft\COM3`,
+	`Microsoft\EventSystem`,
+	`Microsoft\Ole`,
+	`Microsoft\Rpc`,
+}
+
+// wow64共享项 是HKLM\SOFTWARE下两个视图共享的表项, 包含其所有子项。
+var wow64共享项 = []string{
+	`Clients`,
+	`Microsoft\Cryptography\Calais\Current`,
+	`Microsoft\Cryptography\Calais\Readers`,
+	`Microsoft\Cryptography\Services`,
+	`Microsoft\CTF\SystemShared`,
+	`Microsoft\CTF\TIP`,
+	`Microsoft\DFS`,
+	`Microsoft\Driver Signing`,
+	`Microsoft\EnterpriseCertificates`,
+	`Microsoft\MSMQ`,
+	`Microsoft\Non-Driver Signing`,
+	`Microsoft\Notepad\DefaultFonts`,
+	`Microsoft\RAS`,
+	`Microsoft\Shared Tools\MSInfo`,
+	`Microsoft\SystemCertificates`,
+	`Microsoft\TermServLicensing`,
+	`Microsoft\Transaction Server`,
+	`Microsoft\Windows\CurrentVersion\App Paths`,
+	`Microsoft\Windows\CurrentVersion\Control Panel\Cursors\Schemes`,
+	`Microsoft\Windows\CurrentVersion\Explorer\AutoplayHandlers`,
+	`Microsoft\Windows\CurrentVersion\Explorer\DriveIcons`,
+	`Microsoft\Windows\CurrentVersion\Explorer\KindMap`,
+	`Microsoft\Windows\CurrentVersion\Group Policy`,
+	`Microsoft\Windows\CurrentVersion\Policies`,
+	`Microsoft\Windows\CurrentVersion\PreviewHandlers`,
+	`Microsoft\Windows\CurrentVersion\Setup`,
+	`Microsoft\Windows\CurrentVersion\Telephony`,
+	`Microsoft\Windows NT\CurrentVersion\Console`,
+	`Microsoft\Windows NT\CurrentVersion\FontDpi`,
+	`Microsoft\Windows NT\CurrentVersion\FontLink`,
+	`Microsoft\Windows NT\CurrentVersion\FontMapper`,
+	`Microsoft\Windows NT\CurrentVersion\Fonts`,
+	`Microsoft\Windows NT\CurrentVersion\FontSubstitutes`,
+	`Microsoft\Windows NT\CurrentVersion\Gre_Initialize`,
+	`Microsoft\Windows NT\CurrentVersion\Image File Execution Options`,
+	`Microsoft\Windows NT\CurrentVersion\LanguagePack`,
+	`Microsoft\Windows NT\CurrentVersion\NetworkCards`,
+	`Microsoft\Windows NT\CurrentVersion\Perflib`,
+	`Microsoft\Windows NT\CurrentVersion\Ports`,
+	`Microsoft\Windows NT\CurrentVersion\Print`,
+	`Microsoft\Windows NT\CurrentVersion\ProfileList`,
+	`Microsoft\Windows NT\CurrentVersion\Time Zones`,
+	`Policies`,
+	`RegisteredApplications`,
+}
+
+// wow64前缀匹配 报告路径段是否以列表中的某一项开头, 不区分大小写。
+func wow64前缀匹配(段 []string, 列表 []string) bool {
+next:
+	for _, 项 := range 列表 {
+		前缀 := strings.Split(项, `\`)
+		if len(段) < len(前缀) {
+			continue
+		}
+		for i, p := range 前缀 {
+			if hive比较名称(段[i], p) != 0 {
+				continue next
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// I解析视图路径 按Windows 7及以后版本的WOW64规则, 返回完整路径在'视图'中对应的实际路径和表项类别。
+// 路径以根项开头, 例如 `HKLM\SOFTWARE\Vendor` 或 `HKEY_USERS\S-1-5-21-1-2-3-1001_Classes\CLSID`,
+// 支持 HKLM、HKCU、HKU、HKCR 及其完整名称。除 I视图_32位 外的视图都按64位视图解析。
+// 已经位于WOW6432Node下的路径原样返回。
+func I解析视图路径(路径 string, 视图 I视图) (string, I键类别) {
+	段 := 拆分路径(路径)
+	if len(段) == 0 {
+		return 路径, I键类别_不受影响
+	}
+	是名称 := func(i int, 名称 string) bool {
+		return i < len(段) && hive比较名称(段[i], 名称) == 0
+	}
+	软件, 类 := -1, -1 // SOFTWARE 和 Classes 之后第一段的位置
+	用户 := func(i int) {
+		if 是名称(i, "Software") && 是名称(i+1, "Classes") {
+			类 = i + 2
+		}
+	}
+	switch strings.ToUpper(段[0]) {
+	case "HKEY_LOCAL_MACHINE", "HKLM":
+		if 是名称(1, "SOFTWARE") {
+			软件 = 2
+			if 是名称(2, "Classes") {
+				类 = 3
+			}
+		}
+	case "HKEY_CURRENT_USER", "HKCU":
+		用户(1)
+	case "HKEY_USERS", "HKU":
+		if len(段) > 1 && strings.HasSuffix(strings.ToUpper(段[1]), "_CLASSES") {
+			类 = 2
+		} else {
+			用户(2)
+		}
+	case "HKEY_CLASSES_ROOT", "HKCR":
+		类 = 1
+	}
+
+	重定向 := func(i int) (string, I键类别) {
+		if 视图 != I视图_32位 || 是名称(i, wow64节点) {
+			return strings.Join(段, `\`), I键类别_重定向
+		}
+		结果 := append(append(append([]string(nil), 段[:i]...), wow64节点), 段[i:]...)
+		return strings.Join(结果, `\`), I键类别_重定向
+	}
+	switch {
+	case 类 >= 0:
+		if 是名称(类, wow64节点) || wow64前缀匹配(段[类:], wow64重定向类) {
+			return 重定向(类)
+		}
+		return strings.Join(段, `\`), I键类别_共享
+	case 软件 >= 0:
+		if wow64前缀匹配(段[软件:], wow64反射项) {
+			return strings.Join(段, `\`), I键类别_反射
+		}
+		if wow64前缀匹配(段[软件:], wow64共享项) {
+			return strings.Join(段, `\`), I键类别_共享
+		}
+		return 重定向(软件)
+	}
+	return strings.Join(段, `\`), I键类别_不受影响
+}
+
+// I视图项 是 I双视图内容 中的子项, Views 按32位、64位的顺序列出包含该子项的视图。
+type I视图项 struct {
+	Name  string
+	Views []I视图
+}
+
+// I视图值 是 I双视图内容 中的值。两个视图中同名的值类型和数据都相同时合并为一项,
+// 否则每个视图各一项。
+type I视图值 struct {
+	Name  string
+	Type  uint32
+	Data  []byte
+	Views []I视图
+}
+
+// I双视图内容 是同一路径在32位和64位视图中的子项和值的合并结果, 按名称排序。
+type I双视图内容 struct {
+	SubKeys []I视图项
+	Values  []I视图值
+}
+
+// 合并双视图 合并两个视图中读取的子项名称和值。
+func 合并双视图(子项32, 子项64 []string, 值32, 值64 []*I离线值) *I双视图内容 {
+	内容 := &I双视图内容{}
+	位置 := map[string]int{}
+	for _, 视图子项 := range []struct {
+		视图 I视图
+		名称 []string
+	}{{I视图_32位, 子项32}, {I视图_64位, 子项64}} {
+		for _, 名称 := range 视图子项.名称 {
+			键 := string(utf16转字节(hive大写(名称)))
+			i, ok := 位置[键]
+			if !ok {
+				i = len(内容.SubKeys)
+				位置[键] = i
+				内容.SubKeys = append(内容.SubKeys, I视图项{Name: 名称})
+			}
+			内容.SubKeys[i].Views = append(内容.SubKeys[i].Views, 视图子项.视图)
+		}
+	}
+	sort.SliceStable(内容.SubKeys, func(i, j int) bool {
+		return hive比较名称(内容.SubKeys[i].Name, 内容.SubKeys[j].Name) < 0
+	})
+
+	已合并 := map[*I离线值]bool{}
+	for _, v := range 值32 {
+		项 := I视图值{Name: v.Name, Type: v.Type, Data: v.Data, Views: []I视图{I视图_32位}}
+		for _, w := range 值64 {
+			if !已合并[w] && hive比较名称(v.Name, w.Name) == 0 && v.Type == w.Type && string(v.Data) == string(w.Data) {
+				已合并[w] = true
+				项.Views = append(项.Views, I视图_64位)
+				break
+			}
+		}
+		内容.Values = append(内容.Values, 项)
+	}
+	for _, w := range 值64 {
+		if !已合并[w] {
+			内容.Values = append(内容.Values, I视图值{Name: w.Name, Type: w.Type, Data: w.Data, Views: []I视图{I视图_64位}})
+		}
+	}
+	sort.SliceStable(内容.Values, func(i, j int) bool {
+		return hive比较名称(内容.Values[i].Name, 内容.Values[j].Name) < 0
+	})
+	return 内容
+}
+
+// I查找视图 在挂载于'挂载点'(例如 `HKLM\SOFTWARE`)的离线表项k中,
+// 按 I解析视图路径 的规则查找相对路径在'视图'中对应的子项。不存在时返回nil。
+func (k *I离线表项) I查找视图(挂载点, 路径 string, 视图 I视图) *I离线表项 {
+	挂载段 := 拆分路径(挂载点)
+	实际, _ := I解析视图路径(挂载点+`\`+路径, 视图)
+	实际段 := 拆分路径(实际)
+	if len(实际段) < len(挂载段) {
+		return nil
+	}
+	for i, 名称 := range 挂载段 {
+		if hive比较名称(实际段[i], 名称) != 0 {
+			return nil
+		}
+	}
+	return k.I查找(strings.Join(实际段[len(挂载段):], `\`))
+}
+
+// I读取双视图 读取离线表项k中相对路径在32位和64位视图中的子项和值并合并,
+// 挂载点的含义与 I查找视图 相同。两个视图中都不存在时返回nil。
+func (k *I离线表项) I读取双视图(挂载点, 路径 string) *I双视图内容 {
+	项32 := k.I查找视图(挂载点, 路径, I视图_32位)
+	项64 := k.I查找视图(挂载点, 路径, I视图_64位)
+	if 项32 == nil && 项64 == nil {
+		return nil
+	}
+	名称 := func(项 *I离线表项) []string {
+		if 项 == nil {
+			return nil
+		}
+		结果 := make([]string, len(项.SubKeys))
+		for i, 子 := range 项.SubKeys {
+			结果[i] = 子.Name
+		}
+		return 结果
+	}
+	值 := func(项 *I离线表项) []*I离线值 {
+		if 项 == nil {
+			return nil
+		}
+		return 项.Values
+	}
+	return 合并双视图(名称(项32), 名称(项64), 值(项32), 值(项64))
+}
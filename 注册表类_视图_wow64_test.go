@@ -0,0 +1,110 @@
+package 注册表类_test
+
+import (
+	"reflect"
+	"testing"
+
+	注册表类 "e.coding.net/gogit/go/gosdk/core/win_registry_cn"
+)
+
+var viewPathTests = []struct {
+	path string
+	kind 注册表类.I键类别
+	p32  string // empty when the 32-bit view resolves to path
+}{
+	{`HKLM\SOFTWARE`, 注册表类.I键类别_重定向, `HKLM\SOFTWARE\WOW6432Node`},
+	{`HKLM\SOFTWARE\Vendor\App`, 注册表类.I键类别_重定向, `HKLM\SOFTWARE\WOW6432Node\Vendor\App`},
+	{`HKEY_LOCAL_MACHINE\software\Microsoft\Windows\CurrentVersion\Uninstall`, 注册表类.I键类别_重定向, `HKEY_LOCAL_MACHINE\software\WOW6432Node\Microsoft\Windows\CurrentVersion\Uninstall`},
+	{`HKLM\SOFTWARE\WOW6432Node\Vendor`, 注册表类.I键类别_重定向, ""},
+	{`HKLM\SOFTWARE\Policies\Vendor`, 注册表类.I键类别_共享, ""},
+	{`HKLM\SOFTWARE\Microsoft\Windows NT\CurrentVersion\ProfileList\S-1-5-18`, 注册表类.I键类别_共享, ""},
+	{`HKLM\SOFTWARE\Microsoft\Windows NT\CurrentVersion\ProfileListX`, 注册表类.I键类别_重定向, `HKLM\SOFTWARE\WOW6432Node\Microsoft\Windows NT\CurrentVersion\ProfileListX`},
+	{`HKLM\SOFTWARE\Microsoft\Ole`, 注册表类.I键类别_反射, ""},
+	{`HKLM\SOFTWARE\Classes\.txt`, 注册表类.I键类别_共享, ""},
+	{`HKLM\SOFTWARE\Classes\CLSID\{0}`, 注册表类.I键类别_重定向, `HKLM\SOFTWARE\Classes\WOW6432Node\CLSID\{0}`},
+	{`HKCR\Interface`, 注册表类.I键类别_重定向, `HKCR\WOW6432Node\Interface`},
+	{`HKCU\Software\Classes\Media Type\x`, 注册表类.I键类别_重定向, `HKCU\Software\Classes\WOW6432Node\Media Type\x`},
+	{`HKCU\Software\Vendor`, 注册表类.I键类别_不受影响, ""},
+	{`HKU\S-1-5-21-1-2-3-1001\Software\Classes\CLSID`, 注册表类.I键类别_重定向, `HKU\S-1-5-21-1-2-3-1001\Software\Classes\WOW6432Node\CLSID`},
+	{`HKU\S-1-5-21-1-2-3-1001_Classes\CLSID`, 注册表类.I键类别_重定向, `HKU\S-1-5-21-1-2-3-1001_Classes\WOW6432Node\CLSID`},
+	{`HKLM\SYSTEM\CurrentControlSet`, 注册表类.I键类别_不受影响, ""},
+}
+
+func TestViewPath(t *testing.T) {
+	for _, test := range viewPathTests {
+		want32 := test.p32
+		if want32 == "" {
+			want32 = test.path
+		}
+		if got, kind := 注册表类.I解析视图路径(test.path, 注册表类.I视图_32位); got != want32 || kind != test.kind {
+			t.Errorf("32-bit %s: got %s (%v), want %s (%v)", test.path, got, kind, want32, test.kind)
+		}
+		if got, _ := 注册表类.I解析视图路径(test.path, 注册表类.I视图_64位); got != test.path {
+			t.Errorf("64-bit %s: got %s", test.path, got)
+		}
+	}
+}
+
+func TestOfflineDualView(t *testing.T) {
+	software := &注册表类.I离线表项{Name: "SOFTWARE", SubKeys: []*注册表类.I离线表项{
+		{Name: "Vendor", SubKeys: []*注册表类.I离线表项{{Name: "Both"}, {Name: "Native"}},
+			Values: []*注册表类.I离线值{
+				{Name: "Path", Type: 注册表类.SZ, Data: []byte("6\x00")},
+				{Name: "Same", Type: 注册表类.DWORD, Data: []byte{1, 0, 0, 0}},
+			}},
+		{Name: "WOW6432Node", SubKeys: []*注册表类.I离线表项{
+			{Name: "Vendor", SubKeys: []*注册表类.I离线表项{{Name: "both"}, {Name: "Legacy"}},
+				Values: []*注册表类.I离线值{
+					{Name: "Path", Type: 注册表类.SZ, Data: []byte("3\x00")},
+					{Name: "same", Type: 注册表类.DWORD, Data: []byte{1, 0, 0, 0}},
+				}},
+		}},
+		{Name: "Policies", Values: []*注册表类.I离线值{{Name: "P", Type: 注册表类.DWORD, Data: []byte{2, 0, 0, 0}}}},
+	}}
+
+	if k := software.I查找视图(`HKLM\SOFTWARE`, `Vendor\Legacy`, 注册表类.I视图_32位); k == nil || k.Name != "Legacy" {
+		t.Errorf("32-bit lookup of Vendor\\Legacy: got %v", k)
+	}
+	if k := software.I查找视图(`HKLM\SOFTWARE`, `Vendor\Legacy`, 注册表类.I视图_64位); k != nil {
+		t.Errorf("64-bit lookup of Vendor\\Legacy should fail")
+	}
+	// 挂载点按路径段比较, 多余的分隔符和大小写不同的非ASCII名称都不影响结果。
+	if k := software.I查找视图(`HKLM\\SOFTWARE\`, `Vendor\Legacy`, 注册表类.I视图_32位); k == nil || k.Name != "Legacy" {
+		t.Errorf("lookup below a mount with doubled separators: got %v", k)
+	}
+	if k := software.SubKeys[0].I查找视图(`HKLM\SOFTWARE\Vendor`, `Both`, 注册表类.I视图_32位); k != nil {
+		t.Errorf("32-bit lookup redirected outside the mount: got %v", k)
+	}
+	ä := &注册表类.I离线表项{Name: "Ä", SubKeys: []*注册表类.I离线表项{{Name: "Vendor"}}}
+	if k := ä.I查找视图(`HKCU\Software\ä`, `Vendor`, 注册表类.I视图_32位); k == nil || k.Name != "Vendor" {
+		t.Errorf("lookup below a non-ASCII mount: got %v", k)
+	}
+
+	both := []注册表类.I视图{注册表类.I视图_32位, 注册表类.I视图_64位}
+	only32 := []注册表类.I视图{注册表类.I视图_32位}
+	only64 := []注册表类.I视图{注册表类.I视图_64位}
+	got := software.I读取双视图(`HKLM\SOFTWARE`, "Vendor")
+	want := &注册表类.I双视图内容{
+		SubKeys: []注册表类.I视图项{
+			{Name: "both", Views: both},
+			{Name: "Legacy", Views: only32},
+			{Name: "Native", Views: only64},
+		},
+		Values: []注册表类.I视图值{
+			{Name: "Path", Type: 注册表类.SZ, Data: []byte("3\x00"), Views: only32},
+			{Name: "Path", Type: 注册表类.SZ, Data: []byte("6\x00"), Views: only64},
+			{Name: "same", Type: 注册表类.DWORD, Data: []byte{1, 0, 0, 0}, Views: both},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v\nwant %+v", got, want)
+	}
+
+	got = software.I读取双视图(`HKLM\SOFTWARE`, "Policies")
+	if got == nil || len(got.Values) != 1 || !reflect.DeepEqual(got.Values[0].Views, both) {
+		t.Errorf("shared key: got %+v", got)
+	}
+	if software.I读取双视图(`HKLM\SOFTWARE`, "Missing") != nil {
+		t.Error("missing key should return nil")
+	}
+}
@@ -0,0 +1,222 @@
+package 注册表类
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// I默认防抖 是 I绑定选项 没有指定Debounce时, 最后一次变化后等待多久再重新加载。
+const I默认防抖 = 200 * time.Millisecond
+
+// I解码结构 把离线表项的值解码到目标指向的结构体中, 每个导出字段按 I解码值 的规则解码。
+// 字段对应的值名称默认是字段名, 可以用 `reg:"名称"` 标签指定; `reg:"-"` 忽略该字段,
+// `reg:"名称,required"` 表示值不存在时返回错误。结构体类型的字段(time.Time 和实现了
+// encoding.TextUnmarshaler 的类型除外)对应同名的子项。
+// 不存在的值和子项对应的字段保持不变, 所以可以预先在目标中填好默认值。
+func I解码结构(k *I离线表项, 目标 any, 转换 ...I转换) error {
+	rv := reflect.ValueOf(目标)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("目标必须是非nil的结构体指针, 而不是 %T", 目标)
+	}
+	var 模式 I转换
+	for _, c := range 转换 {
+		模式 |= c
+	}
+	return 解码结构(k, rv.Elem(), 模式, "")
+}
+
+func 解码结构(k *I离线表项, rv reflect.Value, 模式 I转换, 路径 string) error {
+	t := rv.Type()
+	for i := 0; i < t.NumField(); i++ {
+		字段 := t.Field(i)
+		if !字段.IsExported() {
+			continue
+		}
+		名称, 选项, _ := strings.Cut(字段.Tag.Get("reg"), ",")
+		if 名称 == "-" {
+			continue
+		}
+		if 名称 == "" {
+			名称 = 字段.Name
+		}
+		必需 := 选项 == "required"
+		f := rv.Field(i)
+		if 是子项字段(f) {
+			子 := k.I取子项(名称)
+			if 子 == nil {
+				if 必需 {
+					return fmt.Errorf("缺少子项 %s", hive连接路径(路径, 名称))
+				}
+				continue
+			}
+			if err := 解码结构(子, f, 模式, hive连接路径(路径, 名称)); err != nil {
+				return err
+			}
+			continue
+		}
+		v := k.I取值(名称)
+		if v == nil {
+			if 必需 {
+				return fmt.Errorf("缺少值 %s", hive连接路径(路径, 名称))
+			}
+			continue
+		}
+		if err := 解码到(v, 模式, f.Addr().Interface()); err != nil {
+			return fmt.Errorf("%s: %w", hive连接路径(路径, 名称), err)
+		}
+	}
+	return nil
+}
+
+func 是子项字段(f reflect.Value) bool {
+	if f.Kind() != reflect.Struct {
+		return false
+	}
+	switch f.Addr().Interface().(type) {
+	case *time.Time, encoding.TextUnmarshaler:
+		return false
+	}
+	return true
+}
+
+// I绑定选项 是 I新建绑定 的选项。
+type I绑定选项[T any] struct {
+	// Debounce 是最后一次变化后等待多久再重新加载, 用于合并连续的多次写入。为0时使用 I默认防抖。
+	Debounce time.Duration
+	// Coerce 是解码值时允许的转换, 见 I解码值。
+	Coerce I转换
+	// Defaults 返回解码前的初始值, 为nil时从T的零值开始。
+	Defaults func() *T
+	// Validate 检查解码后的值, 返回错误时保留原来的值。
+	Validate func(*T) error
+	// OnChange 在新的值替换旧的值之后调用, 内容没有变化时不调用。
+	OnChange func(旧, 新 *T)
+	// OnError 在重新加载、解码或检查失败时调用, 绑定继续使用原来的值并继续监视。
+	OnError func(error)
+}
+
+// I绑定 保存从注册表解码出的结构体, 并在表项变化时重新加载。
+// I取 可以在任意协程中调用, 返回的值不应被修改。
+type I绑定[T any] struct {
+	选项  I绑定选项[T]
+	加载  func() (*I离线表项, error)
+	当前  atomic.Pointer[T]
+	锁   sync.Mutex // 串行化重新加载和计时器
+	计时器 *time.Timer
+	已关闭 bool
+	停止  func() error
+}
+
+// I新建绑定 用'加载'返回的离线表项树解码出T的初始值, 返回绑定。
+// 初始值加载、解码或检查失败时返回错误。之后每次调用 I通知变化 都会在防抖时间后重新加载。
+// 监视Windows注册表时使用 I绑定表项。
+func I新建绑定[T any](加载 func() (*I离线表项, error), 选项 ...I绑定选项[T]) (*I绑定[T], error) {
+	b := &I绑定[T]{加载: 加载}
+	if len(选项) > 0 {
+		b.选项 = 选项[0]
+	}
+	if b.选项.Debounce <= 0 {
+		b.选项.Debounce = I默认防抖
+	}
+	新, err := b.解码()
+	if err != nil {
+		return nil, err
+	}
+	b.当前.Store(新)
+	return b, nil
+}
+
+// I取 返回当前的值。
+func (b *I绑定[T]) I取() *T {
+	return b.当前.Load()
+}
+
+// I通知变化 报告表项发生了变化, 在防抖时间内没有新的变化后重新加载。
+func (b *I绑定[T]) I通知变化() {
+	b.锁.Lock()
+	defer b.锁.Unlock()
+	if b.已关闭 {
+		return
+	}
+	if b.计时器 == nil {
+		b.计时器 = time.AfterFunc(b.选项.Debounce, func() { b.I重新加载() })
+		return
+	}
+	b.计时器.Reset(b.选项.Debounce)
+}
+
+// I重新加载 立即重新加载。成功且内容变化时替换当前值并调用OnChange;
+// 失败时调用OnError并返回错误, 当前值保持不变。绑定关闭后不再加载, 直接返回nil。
+func (b *I绑定[T]) I重新加载() error {
+	b.锁.Lock()
+	// 计时器可能在 I关闭 停止它之前已经触发, 它的重新加载会在关闭之后才取得锁。
+	if b.已关闭 {
+		b.锁.Unlock()
+		return nil
+	}
+	新, err := b.解码()
+	if err != nil {
+		b.锁.Unlock()
+		if b.选项.OnError != nil {
+			b.选项.OnError(err)
+		}
+		return err
+	}
+	旧 := b.当前.Load()
+	变化 := !reflect.DeepEqual(旧, 新)
+	if 变化 {
+		b.当前.Store(新)
+	}
+	b.锁.Unlock()
+	if 变化 && b.选项.OnChange != nil {
+		b.选项.OnChange(旧, 新)
+	}
+	return nil
+}
+
+func (b *I绑定[T]) 解码() (*T, error) {
+	k, err := b.加载()
+	if err != nil {
+		return nil, err
+	}
+	新 := new(T)
+	if b.选项.Defaults != nil {
+		if d := b.选项.Defaults(); d != nil {
+			*新 = *d
+		}
+	}
+	if err := I解码结构(k, 新, b.选项.Coerce); err != nil {
+		return nil, err
+	}
+	if b.选项.Validate != nil {
+		if err := b.选项.Validate(新); err != nil {
+			return nil, fmt.Errorf("检查配置: %w", err)
+		}
+	}
+	return 新, nil
+}
+
+// I关闭 停止等待中的重新加载和对表项的监视, 可以多次调用。
+func (b *I绑定[T]) I关闭() error {
+	if b == nil {
+		return errors.New("注册表类对象为nil")
+	}
+	b.锁.Lock()
+	b.已关闭 = true
+	if b.计时器 != nil {
+		b.计时器.Stop()
+	}
+	停止 := b.停止
+	b.停止 = nil
+	b.锁.Unlock()
+	if 停止 != nil {
+		return 停止()
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+package 注册表类_test
+
+import (
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	注册表类 "e.coding.net/gogit/go/gosdk/core/win_registry_cn"
+)
+
+type serverConfig struct {
+	Host    string        `reg:"HostName,required"`
+	Port    uint16        `reg:"Port"`
+	Timeout time.Duration `reg:"Timeout"`
+	Tags    []string
+	TLS     struct {
+		Enabled bool
+		Cert    string
+	}
+	Ignored string `reg:"-"`
+	secret  string
+}
+
+func testServerKey() *注册表类.I离线表项 {
+	return &注册表类.I离线表项{Name: "Server", Values: []*注册表类.I离线值{
+		sz("HostName", "example.com"),
+		dword("Port", 8080),
+		sz("Timeout", "5s"),
+		sz("Ignored", "x"),
+		sz("secret", "x"),
+	}, SubKeys: []*注册表类.I离线表项{
+		{Name: "TLS", Values: []*注册表类.I离线值{dword("Enabled", 1)}},
+	}}
+}
+
+func TestDecodeStruct(t *testing.T) {
+	cfg := serverConfig{Port: 80, Tags: []string{"default"}}
+	if err := 注册表类.I解码结构(testServerKey(), &cfg, 注册表类.COERCE_TEXT); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Host != "example.com" || cfg.Port != 8080 || cfg.Timeout != 5*time.Second || !cfg.TLS.Enabled {
+		t.Errorf("got %+v", cfg)
+	}
+	if len(cfg.Tags) != 1 || cfg.Tags[0] != "default" || cfg.Ignored != "" || cfg.secret != "" {
+		t.Errorf("missing or ignored fields changed: %+v", cfg)
+	}
+
+	k := testServerKey()
+	k.I删除值("HostName")
+	if err := 注册表类.I解码结构(k, &cfg, 注册表类.COERCE_TEXT); err == nil || !strings.Contains(err.Error(), "HostName") {
+		t.Errorf("missing required value: got %v", err)
+	}
+	k = testServerKey()
+	k.I设置值(dword(`Port`, 70000))
+	if err := 注册表类.I解码结构(k, &cfg, 注册表类.COERCE_TEXT); !errors.Is(err, 注册表类.ErrOverflow) {
+		t.Errorf("overflowing port: got %v, want ErrOverflow", err)
+	}
+	if err := 注册表类.I解码结构(k, cfg); err == nil {
+		t.Error("non-pointer target: want error")
+	}
+}
+
+func TestBindingReload(t *testing.T) {
+	key := testServerKey()
+	changes := make(chan [2]*serverConfig, 10)
+	errs := make(chan error, 10)
+	var loads atomic.Int32
+	b, err := 注册表类.I新建绑定(func() (*注册表类.I离线表项, error) {
+		loads.Add(1)
+		return key, nil
+	}, 注册表类.I绑定选项[serverConfig]{
+		Debounce: 20 * time.Millisecond,
+		Coerce:   注册表类.COERCE_TEXT,
+		Defaults: func() *serverConfig { return &serverConfig{Port: 80} },
+		Validate: func(c *serverConfig) error {
+			if c.Port < 1024 {
+				return errors.New("privileged port")
+			}
+			return nil
+		},
+		OnChange: func(old, new *serverConfig) { changes <- [2]*serverConfig{old, new} },
+		OnError:  func(err error) { errs <- err },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.I关闭()
+	if b.I取().Port != 8080 {
+		t.Fatalf("initial port: got %d", b.I取().Port)
+	}
+
+	// A burst of writes is reloaded once, after the last one.
+	loads.Store(0)
+	for _, port := range []uint32{9000, 9001, 9002} {
+		key.I设置值(dword("Port", port))
+		b.I通知变化()
+	}
+	select {
+	case c := <-changes:
+		if c[0].Port != 8080 || c[1].Port != 9002 || b.I取().Port != 9002 {
+			t.Errorf("change: got %d -> %d, current %d", c[0].Port, c[1].Port, b.I取().Port)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for reload")
+	}
+	if n := loads.Load(); n != 1 {
+		t.Errorf("burst caused %d reloads, want 1", n)
+	}
+
+	// A value that fails validation is reported and the old value kept.
+	key.I设置值(dword("Port", 443))
+	b.I通知变化()
+	select {
+	case err := <-errs:
+		if !strings.Contains(err.Error(), "privileged port") {
+			t.Errorf("got error %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for validation error")
+	}
+	if b.I取().Port != 9002 {
+		t.Errorf("invalid config replaced current value: port %d", b.I取().Port)
+	}
+
+	// A notification without a real change does not call OnChange.
+	key.I设置值(dword("Port", 9002))
+	if err := b.I重新加载(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case c := <-changes:
+		t.Errorf("unexpected change %d -> %d", c[0].Port, c[1].Port)
+	default:
+	}
+}
+
+func TestBindingReloadAfterClose(t *testing.T) {
+	key := testServerKey()
+	var loads atomic.Int32
+	b, err := 注册表类.I新建绑定(func() (*注册表类.I离线表项, error) {
+		loads.Add(1)
+		return key, nil
+	}, 注册表类.I绑定选项[serverConfig]{
+		Coerce:   注册表类.COERCE_TEXT,
+		OnChange: func(old, new *serverConfig) { t.Errorf("OnChange after close: %d -> %d", old.Port, new.Port) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.I关闭()
+	key.I设置值(dword("Port", 9000))
+	loads.Store(0)
+
+	// A timer that fired before I关闭 takes the lock after it, just like this call.
+	if err := b.I重新加载(); err != nil {
+		t.Fatal(err)
+	}
+	if n := loads.Load(); n != 0 || b.I取().Port != 8080 {
+		t.Errorf("reload after close: %d loads, port %d", n, b.I取().Port)
+	}
+}
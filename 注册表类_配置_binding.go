@@ -0,0 +1,51 @@
+//go:build windows
+// +build windows
+
+package 注册表类
+
+import (
+	"context"
+	"errors"
+)
+
+// I绑定表项 把注册表对象k下的子项路径及其所有子项解码为T, 并监视它的变化:
+// 子树中的值或子项变化后, 在防抖时间内没有新的变化时重新读取并替换当前值。
+// 重新加载失败(例如检查不通过)时只调用OnError, 监视继续进行。
+// 解码规则见 I解码结构。不再使用时调用 I关闭。
+func I绑定表项[T any](k *Key结构, 路径 string, 选项 ...I绑定选项[T]) (*I绑定[T], error) {
+	if k == nil {
+		return nil, errors.New("注册表类对象为nil")
+	}
+	子k, err := I打开表项(k, 路径, READ)
+	if err != nil {
+		return nil, err
+	}
+	b, err := I新建绑定(func() (*I离线表项, error) { return I读取离线表项(子k, "") }, 选项...)
+	if err != nil {
+		子k.I关闭()
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	结束 := make(chan error, 1)
+	b.停止 = func() error {
+		cancel()
+		err := <-结束
+		子k.I关闭()
+		if err == context.Canceled {
+			return nil
+		}
+		return err
+	}
+	go func() {
+		// 第一次回调发生在开始监视时, 也重新加载一次, 以免遗漏初始加载之后、开始监视之前的变化。
+		err := I监视变化(ctx, []*Key结构{子k}, true, NOTIFY_CHANGE_NAME|NOTIFY_CHANGE_LAST_SET, func() error {
+			b.I通知变化()
+			return nil
+		})
+		if err != context.Canceled && b.选项.OnError != nil {
+			b.选项.OnError(err)
+		}
+		结束 <- err
+	}()
+	return b, nil
+}
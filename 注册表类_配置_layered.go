@@ -0,0 +1,224 @@
+package 注册表类
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+var (
+	// ErrNotConfigured 当分层配置的任何一层都没有该值时返回。
+	ErrNotConfigured = errors.New("setting not found in any layer")
+
+	// ErrLayerReadOnly 当写入只读的配置层时返回。
+	ErrLayerReadOnly = errors.New("configuration layer is read-only")
+
+	// ErrValueLocked 当写入的值被优先级更高的只读层(例如策略)覆盖时返回。
+	ErrValueLocked = errors.New("setting is locked by a higher read-only layer")
+)
+
+// I配置来源 是分层配置中一层读写值的方式。
+// *I离线表项 和Windows上的 *Key结构 都实现了该接口。
+type I配置来源 interface {
+	// I读取原始值 返回名称为'名称'的值, 不存在时返回nil和nil。
+	I读取原始值(名称 string) (*I离线值, error)
+	// I列出原始值 返回所有值。
+	I列出原始值() ([]*I离线值, error)
+	// I写入原始值 创建或替换值。
+	I写入原始值(值 *I离线值) error
+}
+
+// I配置层 是分层配置中的一层。Source为nil表示该层不存在, 例如没有设置过策略,
+// 读取时跳过该层。ReadOnly的层不能写入, 并且它提供的值会锁定优先级更低的层。
+type I配置层 struct {
+	Name     string
+	Source   I配置来源
+	ReadOnly bool
+
+	缺失 缺失配置层 // Source为nil时层的位置, 见 I打开配置层
+}
+
+// 缺失配置层 记录打开时还不存在的层的位置, 用于在它出现后重新打开。
+type 缺失配置层 interface {
+	打开() (I配置来源, error)
+}
+
+// I配置项 是分层配置中的一个值和提供它的层的名称。
+type I配置项 struct {
+	Value *I离线值
+	Layer string
+}
+
+// I分层配置 按顺序在多个配置层中查找值, 第一个有该值的层生效,
+// 例如 策略 > 当前用户 > 本机 > 默认值。
+type I分层配置 struct {
+	Layers []I配置层
+}
+
+// I新建分层配置 返回按'层'的顺序查找的分层配置, 第一层优先级最高。
+func I新建分层配置(层 ...I配置层) *I分层配置 {
+	return &I分层配置{Layers: 层}
+}
+
+// I新建默认层 返回只读的默认值层, 默认值按 I编码值 的规则编码。
+func I新建默认层(名称 string, 默认 map[string]any) (I配置层, error) {
+	k := &I离线表项{Name: 名称}
+	for 值名称, 值 := range 默认 {
+		v, err := I编码值(值名称, 值)
+		if err != nil {
+			return I配置层{}, err
+		}
+		if err := k.I设置值(v); err != nil {
+			return I配置层{}, err
+		}
+	}
+	return I配置层{Name: 名称, Source: k, ReadOnly: true}, nil
+}
+
+// I取层 返回名称为'名称'的层, 不存在时返回nil。
+func (c *I分层配置) I取层(名称 string) *I配置层 {
+	if c == nil {
+		return nil
+	}
+	for i := range c.Layers {
+		if c.Layers[i].Name == 名称 {
+			return &c.Layers[i]
+		}
+	}
+	return nil
+}
+
+// I查找 返回第一个有名称为'名称'的值的层提供的值。所有层都没有时返回 ErrNotConfigured。
+func (c *I分层配置) I查找(名称 string) (*I配置项, error) {
+	if c == nil {
+		return nil, errors.New("注册表类对象为nil")
+	}
+	for _, 层 := range c.Layers {
+		if 层.Source == nil {
+			continue
+		}
+		v, err := 层.Source.I读取原始值(名称)
+		if err != nil {
+			return nil, fmt.Errorf("配置层 %s: %w", 层.Name, err)
+		}
+		if v != nil {
+			return &I配置项{Value: v, Layer: 层.Name}, nil
+		}
+	}
+	return nil, ErrNotConfigured
+}
+
+// I取配置 查找名称为'名称'的值并按 I解码值 的规则解码为类型T, 同时返回提供它的层的名称。
+func I取配置[T any](c *I分层配置, 名称 string, 转换 ...I转换) (T, string, error) {
+	var 零 T
+	项, err := c.I查找(名称)
+	if err != nil {
+		return 零, "", err
+	}
+	v, err := I解码值[T](项.Value, 转换...)
+	return v, 项.Layer, err
+}
+
+// I写入 把值写入名称为'层名'的层。该层是只读层时返回 ErrLayerReadOnly;
+// 优先级更高的只读层已经有该值时写入不会生效, 返回 ErrValueLocked。
+func (c *I分层配置) I写入(层名 string, 值 *I离线值) error {
+	if c == nil || 值 == nil {
+		return errors.New("注册表类对象为nil")
+	}
+	for _, 层 := range c.Layers {
+		if 层.Name == 层名 {
+			if 层.ReadOnly {
+				return fmt.Errorf("配置层 %s: %w", 层.Name, ErrLayerReadOnly)
+			}
+			if 层.Source == nil {
+				return fmt.Errorf("配置层 %s 不存在", 层.Name)
+			}
+			return 层.Source.I写入原始值(值)
+		}
+		if 层.ReadOnly && 层.Source != nil {
+			v, err := 层.Source.I读取原始值(值.Name)
+			if err != nil {
+				return fmt.Errorf("配置层 %s: %w", 层.Name, err)
+			}
+			if v != nil {
+				return fmt.Errorf("值 %q 由配置层 %s 提供: %w", 值.Name, 层.Name, ErrValueLocked)
+			}
+		}
+	}
+	return fmt.Errorf("没有名称为 %s 的配置层", 层名)
+}
+
+// I设置配置 把值按 I编码值 的规则编码后写入名称为'层名'的层, 见 I分层配置.I写入。
+func I设置配置[T any](c *I分层配置, 层名, 名称 string, 值 T) error {
+	v, err := I编码值(名称, 值)
+	if err != nil {
+		return err
+	}
+	return c.I写入(层名, v)
+}
+
+// I合并 返回所有层合并后的值, 每个名称取优先级最高的层提供的值, 按名称排序。
+func (c *I分层配置) I合并() ([]*I配置项, error) {
+	if c == nil {
+		return nil, errors.New("注册表类对象为nil")
+	}
+	var 结果 []*I配置项
+	for _, 层 := range c.Layers {
+		if 层.Source == nil {
+			continue
+		}
+		值, err := 层.Source.I列出原始值()
+		if err != nil {
+			return nil, fmt.Errorf("配置层 %s: %w", 层.Name, err)
+		}
+		for _, v := range 值 {
+			if !包含值(结果, v.Name) {
+				结果 = append(结果, &I配置项{Value: v, Layer: 层.Name})
+			}
+		}
+	}
+	sort.Slice(结果, func(i, j int) bool { return hive比较名称(结果[i].Value.Name, 结果[j].Value.Name) < 0 })
+	return 结果, nil
+}
+
+func 包含值(项 []*I配置项, 名称 string) bool {
+	for _, p := range 项 {
+		if hive比较名称(p.Value.Name, 名称) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// I配置相同 报告两个 I合并 的结果是否相同, 包括提供每个值的层。
+func I配置相同(a, b []*I配置项) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		x, y := a[i], b[i]
+		if x.Layer != y.Layer || x.Value.Name != y.Value.Name || x.Value.Type != y.Value.Type || !bytes.Equal(x.Value.Data, y.Value.Data) {
+			return false
+		}
+	}
+	return true
+}
+
+// I读取原始值 实现 I配置来源。
+func (k *I离线表项) I读取原始值(名称 string) (*I离线值, error) {
+	return k.I取值(名称), nil
+}
+
+// I列出原始值 实现 I配置来源。
+func (k *I离线表项) I列出原始值() ([]*I离线值, error) {
+	if k == nil {
+		return nil, nil
+	}
+	return k.Values, nil
+}
+
+// I写入原始值 实现 I配置来源。
+func (k *I离线表项) I写入原始值(值 *I离线值) error {
+	return k.I设置值(值)
+}
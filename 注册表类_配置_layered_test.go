@@ -0,0 +1,78 @@
+package 注册表类_test
+
+import (
+	"errors"
+	"testing"
+
+	注册表类 "e.coding.net/gogit/go/gosdk/core/win_registry_cn"
+)
+
+func TestLayeredConfig(t *testing.T) {
+	policy := &注册表类.I离线表项{Name: "Policy", Values: []*注册表类.I离线值{dword("Telemetry", 0)}}
+	user := &注册表类.I离线表项{Name: "User", Values: []*注册表类.I离线值{sz("Theme", "dark")}}
+	machine := &注册表类.I离线表项{Name: "Machine", Values: []*注册表类.I离线值{sz("Theme", "light"), dword("Telemetry", 1)}}
+	defaults, err := 注册表类.I新建默认层("defaults", map[string]any{"Theme": "system", "Port": uint16(80)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := 注册表类.I新建分层配置(
+		注册表类.I配置层{Name: "policy", Source: policy, ReadOnly: true},
+		注册表类.I配置层{Name: "absent"},
+		注册表类.I配置层{Name: "user", Source: user},
+		注册表类.I配置层{Name: "machine", Source: machine},
+		defaults,
+	)
+
+	for _, tt := range []struct {
+		name, want, layer string
+	}{
+		{"Theme", "dark", "user"},
+		{"Telemetry", "0", "policy"},
+		{"Port", "80", "defaults"},
+	} {
+		got, layer, err := 注册表类.I取配置[string](c, tt.name, 注册表类.COERCE_NUMBER)
+		if err != nil || got != tt.want || layer != tt.layer {
+			t.Errorf("%s: got %q from %q, %v; want %q from %q", tt.name, got, layer, err, tt.want, tt.layer)
+		}
+	}
+	if _, _, err := 注册表类.I取配置[string](c, "Missing"); err != 注册表类.ErrNotConfigured {
+		t.Errorf("Missing: got %v, want ErrNotConfigured", err)
+	}
+
+	if err := 注册表类.I设置配置(c, "policy", "Theme", "x"); !errors.Is(err, 注册表类.ErrLayerReadOnly) {
+		t.Errorf("write to policy: got %v, want ErrLayerReadOnly", err)
+	}
+	if err := 注册表类.I设置配置(c, "user", "Telemetry", true); !errors.Is(err, 注册表类.ErrValueLocked) {
+		t.Errorf("write locked value: got %v, want ErrValueLocked", err)
+	}
+	if err := 注册表类.I设置配置(c, "absent", "Theme", "x"); err == nil {
+		t.Error("write to absent layer: want error")
+	}
+	if err := 注册表类.I设置配置(c, "machine", "Port", uint16(8080)); err != nil {
+		t.Fatal(err)
+	}
+
+	before, err := c.I合并()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"Port": "machine", "Telemetry": "policy", "Theme": "user"}
+	if len(before) != len(want) {
+		t.Fatalf("merged: got %d values, want %d", len(before), len(want))
+	}
+	for _, e := range before {
+		if want[e.Value.Name] != e.Layer {
+			t.Errorf("merged %s: got layer %q, want %q", e.Value.Name, e.Layer, want[e.Value.Name])
+		}
+	}
+	if err := user.I删除值("Theme"); err != nil {
+		t.Fatal(err)
+	}
+	after, _ := c.I合并()
+	if 注册表类.I配置相同(before, after) {
+		t.Error("merged config did not change after deleting a user value")
+	}
+	if theme, layer, _ := 注册表类.I取配置[string](c, "Theme"); theme != "light" || layer != "machine" {
+		t.Errorf("Theme after delete: got %q from %q", theme, layer)
+	}
+}
@@ -0,0 +1,217 @@
+//go:build windows
+// +build windows
+
+package 注册表类
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+// I策略路径 是应用程序组策略设置通常所在的 Software\Policies 子项。
+const I策略路径 = `Software\Policies`
+
+// I打开配置层 以名称'名称'打开注册表对象k下的子项路径作为配置层。
+// 只读层以READ权限打开, 其他层以READ|SET_VALUE权限打开, 都包含 I监视 需要的NOTIFY权限。
+// 子项不存在时返回Source为nil的层, 读取时跳过; 此时调用者不需要关闭它。
+// 这样的层记录了k和路径, I监视 会在子项出现后打开它, 所以k在监视期间必须保持打开。
+// 层的Source不再使用时应该用 I关闭配置层 关闭。
+func I打开配置层(k *Key结构, 路径, 名称 string, 只读 bool) (I配置层, error) {
+	var 权限 uint32 = READ
+	if !只读 {
+		权限 |= SET_VALUE
+	}
+	子k, err := I打开表项(k, 路径, 权限)
+	if errors.Is(err, ErrNotExist) {
+		return I配置层{Name: 名称, ReadOnly: 只读, 缺失: &缺失表项{根: k, 路径: 路径, 权限: 权限}}, nil
+	}
+	if err != nil {
+		return I配置层{}, err
+	}
+	return I配置层{Name: 名称, Source: 子k, ReadOnly: 只读}, nil
+}
+
+// 缺失表项 是 I打开配置层 时不存在的子项。
+type 缺失表项 struct {
+	根  *Key结构
+	路径 string
+	权限 uint32
+}
+
+func (m *缺失表项) 打开() (I配置来源, error) {
+	k, err := I打开表项(m.根, m.路径, m.权限)
+	if err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// 存在深度 返回路径中已经存在的前几段的数量, 子项存在时等于路径的段数。
+func (m *缺失表项) 存在深度() (int, error) {
+	段 := 拆分路径(m.路径)
+	for n := len(段); n > 0; n-- {
+		k, err := I打开表项(m.根, strings.Join(段[:n], `\`), NOTIFY)
+		if err == nil {
+			return n, k.I关闭()
+		}
+		if !errors.Is(err, ErrNotExist) {
+			return 0, err
+		}
+	}
+	return 0, nil
+}
+
+// 最近父项 以NOTIFY权限打开路径上已经存在的最深的表项, 并返回它的深度。
+func (m *缺失表项) 最近父项() (*Key结构, int, error) {
+	深度, err := m.存在深度()
+	if err != nil {
+		return nil, 0, err
+	}
+	k, err := I打开表项(m.根, strings.Join(拆分路径(m.路径)[:深度], `\`), NOTIFY)
+	return k, 深度, err
+}
+
+// 打开缺失层 打开所有在 I打开配置层 之后出现的层。
+func (c *I分层配置) 打开缺失层() error {
+	for i := range c.Layers {
+		层 := &c.Layers[i]
+		if 层.Source != nil || 层.缺失 == nil {
+			continue
+		}
+		来源, err := 层.缺失.打开()
+		if errors.Is(err, ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		层.Source = 来源
+	}
+	return nil
+}
+
+// I关闭配置层 关闭分层配置中所有由注册表对象提供的层。
+func (c *I分层配置) I关闭配置层() error {
+	if c == nil {
+		return errors.New("注册表类对象为nil")
+	}
+	var 错误 error
+	for _, 层 := range c.Layers {
+		if k, ok := 层.Source.(*Key结构); ok && k != nil {
+			if err := k.I关闭(); err != nil && 错误 == nil {
+				错误 = err
+			}
+		}
+	}
+	return 错误
+}
+
+// errLayerAppeared 由 I监视 内部使用, 表示缺失层的路径上出现了新的子项。
+var errLayerAppeared = errors.New("configuration layer key appeared")
+
+// I监视 监视分层配置中所有由注册表对象提供的层, 先用当前的合并结果调用一次回调,
+// 之后每当合并结果(包括提供每个值的层)变化时用新的结果调用回调。
+// I打开配置层 时不存在的层通过NOTIFY_CHANGE_NAME监视路径上最近的已存在表项,
+// 子项出现后打开它作为该层的Source(之后同样需要 I关闭配置层 关闭), 并用新的合并结果调用回调。
+// 一直阻塞到ctx取消, 返回ctx.Err()或读取配置时的错误。见 I监视变化。
+func (c *I分层配置) I监视(ctx context.Context, 回调 func([]*I配置项)) error {
+	if c == nil {
+		return errors.New("注册表类对象为nil")
+	}
+	var 上次 []*I配置项
+	首次 := true
+	for {
+		if err := c.打开缺失层(); err != nil {
+			return err
+		}
+		var 表项 []*Key结构
+		var 过滤 []uint32
+		for _, 层 := range c.Layers {
+			if k, ok := 层.Source.(*Key结构); ok && k != nil {
+				表项 = append(表项, k)
+				过滤 = append(过滤, NOTIFY_CHANGE_LAST_SET)
+			}
+		}
+		层数 := len(表项)
+		var 缺失 []*缺失表项
+		var 深度 []int
+		var err error
+		for _, 层 := range c.Layers {
+			m, ok := 层.缺失.(*缺失表项)
+			if !ok || 层.Source != nil {
+				continue
+			}
+			var 父项 *Key结构
+			var d int
+			if 父项, d, err = m.最近父项(); err != nil {
+				break
+			}
+			表项 = append(表项, 父项)
+			过滤 = append(过滤, NOTIFY_CHANGE_NAME)
+			缺失, 深度 = append(缺失, m), append(深度, d)
+		}
+		if err == nil {
+			err = 监视变化(ctx, 表项, false, 过滤, func(i int) error {
+				if i >= 层数 {
+					return errLayerAppeared
+				}
+				// 开始监视之前出现的子项不会触发通知, 在这里检查。
+				if i < 0 {
+					for j, m := range 缺失 {
+						if d, err := m.存在深度(); err != nil || d != 深度[j] {
+							return errLayerAppeared
+						}
+					}
+				}
+				合并, err := c.I合并()
+				if err != nil {
+					return err
+				}
+				if 首次 || !I配置相同(上次, 合并) {
+					首次, 上次 = false, 合并
+					回调(合并)
+				}
+				return nil
+			})
+		}
+		for _, k := range 表项[层数:] {
+			k.I关闭()
+		}
+		if err != errLayerAppeared {
+			return err
+		}
+	}
+}
+
+// I读取原始值 实现 I配置来源, 值不存在时返回nil和nil。
+func (k *Key结构) I读取原始值(名称 string) (*I离线值, error) {
+	v, err := k.取原始值(名称)
+	if errors.Is(err, ErrNotExist) {
+		return nil, nil
+	}
+	return v, err
+}
+
+// I列出原始值 实现 I配置来源。
+func (k *Key结构) I列出原始值() ([]*I离线值, error) {
+	if k != nil && k.拦截 != nil {
+		return k.拦截列出值()
+	}
+	var 值 []*I离线值
+	for v, err := range k.I遍历值(context.Background()) {
+		if err != nil {
+			return nil, err
+		}
+		值 = append(值, v)
+	}
+	return 值, nil
+}
+
+// I写入原始值 实现 I配置来源。
+func (k *Key结构) I写入原始值(值 *I离线值) error {
+	if 值 == nil {
+		return errors.New("注册表类对象为nil")
+	}
+	return k.setValue(值.Name, 值.Type, 值.Data)
+}
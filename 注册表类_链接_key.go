@@ -0,0 +1,77 @@
+//go:build windows
+// +build windows
+
+package 注册表类
+
+import (
+	"errors"
+	"syscall"
+)
+
+// I创建链接表项 在注册表对象k下创建名为路径的符号链接表项, 指向目标。
+// 目标可以是 HKLM\...、HKU\... 或 \REGISTRY\... 形式的内核路径。
+// 路径已经存在时返回错误。返回的注册表对象指向链接表项本身。
+func I创建链接表项(k *Key结构, 路径, 目标 string) (*Key结构, error) {
+	if k == nil {
+		return nil, errors.New("注册表类对象为nil")
+	}
+	内核路径, err := I转内核路径(目标)
+	if err != nil {
+		return nil, err
+	}
+	链接, 是否已存在, err := k.创建子项(路径, OPTION_CREATE_LINK, nil, ALL_ACCESS|CREATE_LINK)
+	if err != nil {
+		return nil, err
+	}
+	if 是否已存在 {
+		链接.I关闭()
+		return nil, errors.New("表项已经存在: " + 路径)
+	}
+	if err := 链接.setValue(I链接值名称, LINK, hive编码UTF16(内核路径)); err != nil {
+		ntDeleteKey(syscall.Handle(链接.Key父类))
+		链接.I关闭()
+		return nil, err
+	}
+	return 链接, nil
+}
+
+// I打开链接表项 以REG_OPTION_OPEN_LINK打开注册表对象k下的路径,
+// 路径最后一段是符号链接时打开链接本身而不是目标。'访问权限'默认为ALL_ACCESS。
+func I打开链接表项(k *Key结构, 路径 string, 访问权限 ...uint32) (*Key结构, error) {
+	if k == nil {
+		return nil, errors.New("注册表类对象为nil")
+	}
+	var 权限参数 uint32 = ALL_ACCESS
+	if len(访问权限) > 0 {
+		权限参数 = 访问权限[0]
+	}
+	return k.打开子项(路径, OPTION_OPEN_LINK, 权限参数)
+}
+
+// I取链接目标 返回用 I打开链接表项 打开的符号链接表项的目标内核路径,
+// 可以用 I转注册表路径 转换为 HKEY_LOCAL_MACHINE\... 形式。
+func (k *Key结构) I取链接目标() (string, error) {
+	if k == nil {
+		return "", errors.New("注册表类对象为nil")
+	}
+	v, err := k.取原始值(I链接值名称)
+	if err != nil {
+		return "", err
+	}
+	if v.Type != LINK {
+		return "", ErrUnexpectedType
+	}
+	return hive解码UTF16(v.Data), nil
+}
+
+// I删除链接表项 删除注册表对象k下的符号链接表项本身, 不影响链接目标。
+func I删除链接表项(k *Key结构, 路径 string) error {
+	链接, err := I打开链接表项(k, 路径, DELETE)
+	if err != nil {
+		return err
+	}
+	defer 链接.I关闭()
+	return 链接.拦截调用(&I调用记录{Op: I操作_删除表项, Path: 链接.路径}, func() error {
+		return ntDeleteKey(syscall.Handle(链接.Key父类))
+	})
+}
@@ -0,0 +1,91 @@
+package 注册表类
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// I链接值名称 是符号链接表项中保存目标路径的值的名称, 值的类型为 LINK。
+const I链接值名称 = "SymbolicLinkValue"
+
+// I链接最大跳数 是解析路径时最多跟随的符号链接数量, 超过时认为存在循环。
+const I链接最大跳数 = 32
+
+// ErrLinkLoop 当解析路径时符号链接形成循环或跟随次数超过 I链接最大跳数 时返回。
+var ErrLinkLoop = errors.New("registry symbolic link loop")
+
+// I转内核路径 把 HKLM\... 或 HKU\... 形式的路径转换为符号链接目标使用的
+// \REGISTRY\MACHINE\... 或 \REGISTRY\USER\... 形式。已经是内核路径时原样返回。
+// HKCU 和 HKCR 依赖当前用户, 不能作为链接目标。
+func I转内核路径(路径 string) (string, error) {
+	段 := 拆分路径(路径)
+	if len(段) == 0 {
+		return "", fmt.Errorf("路径 %q 没有根项", 路径)
+	}
+	if strings.EqualFold(段[0], "REGISTRY") && strings.HasPrefix(路径, `\`) {
+		return 路径, nil
+	}
+	var 前缀 string
+	switch 根项名称(段[0]) {
+	case "HKEY_LOCAL_MACHINE":
+		前缀 = `\REGISTRY\MACHINE`
+	case "HKEY_USERS":
+		前缀 = `\REGISTRY\USER`
+	default:
+		return "", fmt.Errorf("路径 %q 不能转换为内核路径", 路径)
+	}
+	return strings.Join(append([]string{前缀}, 段[1:]...), `\`), nil
+}
+
+// I转注册表路径 把 \REGISTRY\MACHINE\... 或 \REGISTRY\USER\... 形式的内核路径
+// 转换为 HKEY_LOCAL_MACHINE\... 或 HKEY_USERS\... 形式。
+func I转注册表路径(内核路径 string) (string, error) {
+	段 := 拆分路径(内核路径)
+	if len(段) < 2 || !strings.EqualFold(段[0], "REGISTRY") {
+		return "", fmt.Errorf("%q 不是注册表内核路径", 内核路径)
+	}
+	switch strings.ToUpper(段[1]) {
+	case "MACHINE":
+		段[1] = "HKEY_LOCAL_MACHINE"
+	case "USER":
+		段[1] = "HKEY_USERS"
+	default:
+		return "", fmt.Errorf("%q 不是注册表内核路径", 内核路径)
+	}
+	return strings.Join(段[1:], `\`), nil
+}
+
+// I是链接 报告离线表项是否是符号链接。
+func (k *I离线表项) I是链接() bool {
+	return k != nil && k.Flags&hive标志_符号链接 != 0
+}
+
+// I取链接目标 返回符号链接表项的目标内核路径。
+func (k *I离线表项) I取链接目标() (string, error) {
+	if !k.I是链接() {
+		return "", fmt.Errorf("表项不是符号链接")
+	}
+	v := k.I取值(I链接值名称)
+	if v == nil {
+		return "", ErrOfflineNotExist
+	}
+	if v.Type != LINK {
+		return "", ErrUnexpectedType
+	}
+	return hive解码UTF16(v.Data), nil
+}
+
+// I设置链接目标 把离线表项设置为指向目标的符号链接。
+// 目标可以是 HKLM\... 或内核路径, 保存时转换为不带结尾NUL的内核路径。
+func (k *I离线表项) I设置链接目标(目标 string) error {
+	if k == nil {
+		return errors.New("注册表类对象为nil")
+	}
+	内核路径, err := I转内核路径(目标)
+	if err != nil {
+		return err
+	}
+	k.Flags |= hive标志_符号链接
+	return k.I设置值(&I离线值{Name: I链接值名称, Type: LINK, Data: hive编码UTF16(内核路径)})
+}
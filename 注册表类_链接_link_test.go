@@ -0,0 +1,80 @@
+package 注册表类_test
+
+import (
+	"testing"
+
+	注册表类 "e.coding.net/gogit/go/gosdk/core/win_registry_cn"
+)
+
+func TestKernelPath(t *testing.T) {
+	for _, test := range []struct{ path, kernel string }{
+		{`HKLM\SYSTEM\ControlSet001`, `\REGISTRY\MACHINE\SYSTEM\ControlSet001`},
+		{`HKEY_USERS\S-1-5-18\Software`, `\REGISTRY\USER\S-1-5-18\Software`},
+		{`\REGISTRY\MACHINE\SOFTWARE`, `\REGISTRY\MACHINE\SOFTWARE`},
+	} {
+		got, err := 注册表类.I转内核路径(test.path)
+		if err != nil || got != test.kernel {
+			t.Errorf("I转内核路径(%q) = %q, %v, want %q", test.path, got, err, test.kernel)
+		}
+	}
+	if _, err := 注册表类.I转内核路径(`HKCU\Software`); err == nil {
+		t.Error("HKCU cannot be a link target")
+	}
+	if got, err := 注册表类.I转注册表路径(`\Registry\Machine\SYSTEM`); err != nil || got != `HKEY_LOCAL_MACHINE\SYSTEM` {
+		t.Errorf("got %q, %v", got, err)
+	}
+	if _, err := 注册表类.I转注册表路径(`\Device\HarddiskVolume1`); err == nil {
+		t.Error("non-registry kernel path should fail")
+	}
+}
+
+func TestOfflineLinks(t *testing.T) {
+	root := &注册表类.I离线表项{Name: "ROOT", SubKeys: []*注册表类.I离线表项{
+		{Name: "Target", SubKeys: []*注册表类.I离线表项{{Name: "Child", Values: []*注册表类.I离线值{sz("V", "target")}}}},
+		{Name: "Link"}, {Name: "LoopA"}, {Name: "LoopB"}, {Name: "Dangling"},
+	}}
+	for link, target := range map[string]string{
+		"Link":     `HKLM\SOFTWARE\Target`,
+		"LoopA":    `HKLM\SOFTWARE\LoopB`,
+		"LoopB":    `\REGISTRY\MACHINE\SOFTWARE\LoopA`,
+		"Dangling": `HKLM\SOFTWARE\Missing`,
+	} {
+		if err := root.I取子项(link).I设置链接目标(target); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Links survive a round trip through the hive format.
+	data, err := 注册表类.I生成配置单元(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ns := 注册表类.I新建离线命名空间()
+	if err := ns.I加载文件(`HKLM\SOFTWARE`, data); err != nil {
+		t.Fatal(err)
+	}
+	link, err := ns.I查找Ex(`HKLM\SOFTWARE\Link`, true)
+	if err != nil || !link.I是链接() {
+		t.Fatalf("opening the link itself: %v", err)
+	}
+	if target, _ := link.I取链接目标(); target != `\REGISTRY\MACHINE\SOFTWARE\Target` {
+		t.Errorf("link target: got %q", target)
+	}
+	if v, err := ns.I取值(`HKLM\SOFTWARE\Link\Child`, "V"); err != nil || text(v) != "target" {
+		t.Errorf("lookup through link: got %v, %v", v, err)
+	}
+	if k, err := ns.I查找Ex(`HKLM\SOFTWARE\Link`, false); err != nil || k.Name != "Target" {
+		t.Errorf("following the final link: got %v, %v", k, err)
+	}
+	if _, err := ns.I查找Ex(`HKLM\SOFTWARE\LoopA\X`, false); err != 注册表类.ErrLinkLoop {
+		t.Errorf("loop: got %v, want ErrLinkLoop", err)
+	}
+	if _, err := ns.I查找Ex(`HKLM\SOFTWARE\Dangling`, false); err != 注册表类.ErrOfflineNotExist {
+		t.Errorf("dangling link: got %v, want ErrOfflineNotExist", err)
+	}
+	if _, err := ns.I查找Ex(`HKLM\SOFTWARE\Dangling`, true); err != nil {
+		t.Errorf("opening a dangling link itself: %v", err)
+	}
+	if r := 注册表类.I校验配置单元(data); !r.I是否有效() {
+		t.Errorf("hive with links is reported invalid: %v", r.I取错误())
+	}
+}